@@ -62,11 +62,20 @@ Additional GoAWK features:
   -c                use Unicode chars for index, length, match, substr, and %c
   -E progfile       load program, treat as last option, disable var=value args
   -H                parse header row and enable @"field" in CSV input mode
+  -H=<list>         use given field names for @"field" (no header row)
   -h, --help        show this help message
   -i mode           parse input into fields using CSV format (ignore FS and RS)
-                    'csv|tsv [separator=<char>] [comment=<char>] [header]'
+                    'csv|tsv [separator=<char>] [comment=<char>] [quotechar=<char>]
+                     [header[=<list>]] [strict] [fields=<n>]
+                     [onerror=abort|skip|report]'
+                    or fixed-width fields (ignore FS): 'fixed widths=<list>'
+                    or JSON Lines objects: 'jsonl [flatten=<sep>|none]'
   -o mode           use CSV output for print with args (ignore OFS and ORS)
-                    'csv|tsv [separator=<char>]'
+                    'csv|tsv [separator=<char>] [header]
+                     [quote=always|minimal|nonnumeric|never]
+                     [quotechar=<char>] [escape=<char>]'
+                    or JSON output: 'json|jsonl [fields=<list>]'
+  -M, --bignum      use arbitrary-precision arithmetic (see PREC, ROUNDMODE)
   -N mode           newline output translation: smart (default), raw, crlf
   -version          show GoAWK version and exit
 
@@ -97,11 +106,13 @@ func main() {
 	inputMode := ""
 	outputMode := ""
 	header := false
+	headerNames := ""
 	noArgVars := false
 	coverMode := cover.ModeUnspecified
 	coverProfile := ""
 	coverAppend := false
 	useChars := false
+	bignum := false
 	newlineOutput := interp.SmartNewlineMode
 
 	var i int
@@ -177,6 +188,8 @@ argsLoop:
 			debugTypes = true
 		case "-H":
 			header = true
+		case "-M", "--bignum":
+			bignum = true
 		case "-h", "--help":
 			fmt.Printf("%s\n\n%s\n\n%s", copyright, shortUsage, longUsage)
 			os.Exit(0)
@@ -214,6 +227,9 @@ argsLoop:
 				noArgVars = true
 				i++
 				break argsLoop
+			case strings.HasPrefix(arg, "-H="):
+				header = true
+				headerNames = arg[3:]
 			case strings.HasPrefix(arg, "-F"):
 				fieldSep = arg[2:]
 			case strings.HasPrefix(arg, "-f"):
@@ -247,6 +263,9 @@ argsLoop:
 	args := os.Args[i:]
 
 	fileReader := &parseutil.FileReader{}
+	if awkPath := os.Getenv("AWKPATH"); awkPath != "" {
+		fileReader.IncludePath = filepath.SplitList(awkPath)
+	}
 	if len(progFiles) > 0 {
 		// Read source: the concatenation of all source files specified
 		progFiles = expandWildcardsOnWindows(progFiles)
@@ -284,6 +303,7 @@ argsLoop:
 	parserConfig := &parser.ParserConfig{
 		DebugTypes:  debugTypes,
 		DebugWriter: os.Stdout,
+		Include:     fileReader.Include,
 	}
 	prog, err := parser.ParseProgram(fileReader.Source(), parserConfig)
 	if err != nil {
@@ -334,7 +354,11 @@ argsLoop:
 		if inputMode == "" {
 			errorExitf("-H only allowed together with -i")
 		}
-		inputMode += " header"
+		if headerNames != "" {
+			inputMode += " header=" + headerNames
+		} else {
+			inputMode += " header"
+		}
 	}
 
 	// Don't buffer output if stdout is a terminal (default output writer when
@@ -349,9 +373,11 @@ argsLoop:
 		Argv0:         filepath.Base(os.Args[0]),
 		Args:          expandWildcardsOnWindows(args),
 		Chars:         useChars,
+		Bignum:        bignum,
 		NoArgVars:     noArgVars,
 		Output:        stdout,
 		NewlineOutput: newlineOutput,
+		AllowNetwork:  true,
 		Vars: []string{
 			"FS", fieldSep,
 			"INPUTMODE", inputMode,
@@ -615,15 +615,33 @@ func TestGoAWKSpecificOptions(t *testing.T) {
 		// CSV-related options
 		{[]string{"-icsv", "-H", `{ print @"age", @"name" }`}, "name,age\nBob,42\nJane,37", "42 Bob\n37 Jane\n", ""},
 		{[]string{"-i", "csv", "-H", `{ print @"age", @"name" }`}, "name,age\nBob,42\nJane,37", "42 Bob\n37 Jane\n", ""},
+		{[]string{"-i", "csv", "-H=name,age", `{ print @"age", @"name" }`}, "Bob,42\nJane,37", "42 Bob\n37 Jane\n", ""},
+		{[]string{"-i", "tsv header=name,age", `{ print @"age", @"name" }`}, "Bob\t42", "42 Bob\n", ""},
 		{[]string{"-icsv", `{ print $2, $1 }`}, "Bob,42\nJane,37", "42 Bob\n37 Jane\n", ""},
 		{[]string{"-i", "csv", `{ print $2, $1 }`}, "Bob,42\nJane,37", "42 Bob\n37 Jane\n", ""},
 		{[]string{"-icsv", "-H", "-ocsv", `{ print @"age", @"name" }`}, "name,age\n\"Bo,ba\",42\nJane,37", "42,\"Bo,ba\"\n37,Jane\n", ""},
 		{[]string{"-csv", `{ print $2, $1 }`}, "Bob,42\nJane,37", "42 Bob\n37 Jane\n", ""},
 		{[]string{"--csv", `{ print $2, $1 }`}, "Bob,42\nJane,37", "42 Bob\n37 Jane\n", ""},
 		{[]string{"-o", "csv", `BEGIN { print "foo,bar", 3.14, "baz" }`}, "", "\"foo,bar\",3.14,baz\n", ""},
+		{[]string{"-i", "csv strict onerror=report", `{ print $1, CSVERROR }`}, "a,b\nc", "a \nc -:2: wrong number of fields (expected 2, got 1)\n", ""},
+		{[]string{"-i", "csv fields=2", `{ print $1 }`}, "a,b\nc", "a\n", "error reading from input: -:2: wrong number of fields (expected 2, got 1)\n"},
+		{[]string{"-o", "csv quote=nonnumeric", `{ print $1, $2 }`}, "Bob 42", "\"Bob\",42\n", ""},
+		{[]string{"-i", "csv quotechar='", "-o", "csv quotechar=' escape=\\", `{ print $1, $2 }`}, "'it''s',a", "'it\\'s',a\n", ""},
+		{[]string{"-i", "fixed widths=3,1:*", `{ print $2, $1 }`}, "Bob 42\nJo  37", "42 Bob\n37 Jo \n", ""},
+		{[]string{"-i", "jsonl", `{ print @"name", @"user.id" }`}, `{"name":"Bob","user":{"id":42}}`, "Bob 42\n", ""},
+		{[]string{"-i", "jsonl flatten=none", `{ print @"user" }`}, `{"user":{"id":42}}`, "{\"id\":42}\n", ""},
+		{[]string{"-o", "jsonl", `{ print $2, $1 }`}, "Bob 42", "[42,\"Bob\"]\n", ""},
+		{[]string{"-o", "csv header", `BEGIN { OFIELDS[1]="name"; OFIELDS[2]="age" } { a["age"]=$2; a["name"]=$1; printrow(a) }`}, "Bob 42\nJo 7", "name,age\nBob,42\nJo,7\n", ""},
+		{[]string{"-i", "jsonl", "-o", "jsonl fields=id,name", `{ print @"id", @"name" }`}, `{"name":"Bob","id":42}`, "{\"id\":42,\"name\":\"Bob\"}\n", ""},
 		{[]string{"-iabc", `{}`}, "", "", "invalid input mode \"abc\"\n"},
 		{[]string{"-oxyz", `{}`}, "", "", "invalid output mode \"xyz\"\n"},
 		{[]string{"-H", `{}`}, "", "", "-H only allowed together with -i\n"},
+		{[]string{"-H=a,b", `{}`}, "", "", "-H only allowed together with -i\n"},
+
+		// Arbitrary-precision arithmetic
+		{[]string{"-M", `{ s += $1 } END { print s, 2^80 }`}, "100000000000000000000\n3", "100000000000000000003 1208925819614629174706176\n", ""},
+		{[]string{"-M", "-v", "PREC=100", `{ s += $1 } END { printf "%.1f\n", s }`}, "0.1\n0.2\n100000000000000000000", "100000000000000000000.3\n", ""},
+		{[]string{"--bignum", "-v", "PREC=200", `BEGIN { printf "%.40f\n", 1/3 }`}, "", "0.3333333333333333333333333333333333333333\n", ""},
 
 		// Chars mode (vs bytes)
 		{[]string{"-c", `BEGIN { printf "%c", 4660 }`}, "", "\u1234", ""},
@@ -631,6 +649,67 @@ func TestGoAWKSpecificOptions(t *testing.T) {
 		{[]string{"-c", `{ print length }`}, "絵\n", "1\n", ""},
 		{[]string{`{ print length }`}, "絵\n", "3\n", ""},
 
+		// BEGINFILE and ENDFILE
+		{[]string{`BEGINFILE { print "begin " FILENAME } { print FNR, $0 } ENDFILE { print "end " FILENAME }`,
+			"testdata/g.1", "-", "testdata/g.4"},
+			"a\n", `
+begin testdata/g.1
+1 ONE
+end testdata/g.1
+begin -
+1 a
+end -
+begin testdata/g.4
+1 FOUR a
+2 FOUR b
+end testdata/g.4
+`[1:], ""},
+		{[]string{`BEGINFILE { if (ERRNO != "") { print "skip " FILENAME ": " ERRNO; nextfile } } { print }`,
+			"testdata/g.1", "testdata/notexist", "testdata/g.2"},
+			"", "ONE\nskip testdata/notexist: no such file or directory\nTWO\n", ""},
+		{[]string{`BEGINFILE { } { print }`, "testdata/g.1", "testdata/notexist"},
+			"", "ONE\n", "file \"testdata/notexist\" not found\n"},
+
+		// @include directive
+		{[]string{"-f", "testdata/include/main.awk"}, "", "42\n", ""},
+		{[]string{"-f", "testdata/include/bad.awk"}, "", "",
+			"testdata/include/bad.awk:2:28: expected expression instead of }\nBEGIN { print double(21) + }\n                           ^\n"},
+		{[]string{`@include "testdata/include/bad.awk"`}, "", "",
+			"testdata/include/bad.awk:2:28: expected expression instead of }\nBEGIN { print double(21) + }\n                           ^\n"},
+		{[]string{`@include "testdata/include/nope"`}, "", "",
+			"<cmdline>:1:10: @include file \"testdata/include/nope\" not found\n@include \"testdata/include/nope\"\n         ^\n"},
+
+		// @namespace directive (namespaces are reset at the start of each file)
+		{[]string{"-f", "testdata/namespace/main.awk", "-f", "testdata/namespace/other.awk"}, "a\nb\na\n", "2 1 3  3\nmain\n", ""},
+		{[]string{"-d", `@namespace "ns"
+function f(x) { return x + y + NR }  BEGIN { print f(1), awk::y, ns::y }`}, "", `
+BEGIN {
+    print ns::f(1), y, ns::y
+}
+
+function ns::f(x) {
+    return x + ns::y + NR
+}
+`[1:], ""},
+		{[]string{"-dt", "-f", "testdata/namespace/main.awk"}, "", `
+globals
+  ARGV: array 0
+  ENVIRON: array 1
+  FIELDS: array 2
+  OFIELDS: array 3
+  PROCINFO: array 4
+  app::total: scalar 0
+  counter::counts: array 5
+  counter::total: scalar 1
+function app::incr(name)  # index 2
+  name: scalar 0
+function counter::get(name)  # index 1
+  name: scalar 0
+function counter::incr(name)  # index 0
+  name: scalar 0
+`[1:], ""},
+		{[]string{`@namespace "if"`}, "", "", "<cmdline>:1:12: invalid namespace name \"if\"\n@namespace \"if\"\n           ^\n"},
+
 		// Debug options
 		{[]string{"-dt", `
 BEGIN { x=42; a[1]=x; print f(a, 1) }
@@ -641,7 +720,9 @@ globals
   ARGV: array 0
   ENVIRON: array 1
   FIELDS: array 2
-  a: array 3
+  OFIELDS: array 3
+  PROCINFO: array 4
+  a: array 5
   x: scalar 0
 function f(b, y, z)  # index 0
   b: array 0
@@ -13,7 +13,9 @@ import (
 // Program is a parsed AWK program.
 type Program struct {
 	Begin     []Stmts
+	BeginFile []Stmts
 	Actions   []*Action
+	EndFile   []Stmts
 	End       []Stmts
 	Functions []*Function
 }
@@ -25,9 +27,15 @@ func (p *Program) String() string {
 	for _, ss := range p.Begin {
 		parts = append(parts, "BEGIN {\n"+ss.String()+"}")
 	}
+	for _, ss := range p.BeginFile {
+		parts = append(parts, "BEGINFILE {\n"+ss.String()+"}")
+	}
 	for _, a := range p.Actions {
 		parts = append(parts, a.String())
 	}
+	for _, ss := range p.EndFile {
+		parts = append(parts, "ENDFILE {\n"+ss.String()+"}")
+	}
 	for _, ss := range p.End {
 		parts = append(parts, "END {\n"+ss.String()+"}")
 	}
@@ -80,44 +88,47 @@ type Node interface {
 }
 
 // All these types implement the Node interface.
-func (p *Program) node()        {}
-func (a *Action) node()         {}
-func (f *Function) node()       {}
-func (e *FieldExpr) node()      {}
-func (e *NamedFieldExpr) node() {}
-func (e *UnaryExpr) node()      {}
-func (e *BinaryExpr) node()     {}
-func (e *InExpr) node()         {}
-func (e *CondExpr) node()       {}
-func (e *NumExpr) node()        {}
-func (e *StrExpr) node()        {}
-func (e *RegExpr) node()        {}
-func (e *VarExpr) node()        {}
-func (e *IndexExpr) node()      {}
-func (e *AssignExpr) node()     {}
-func (e *AugAssignExpr) node()  {}
-func (e *IncrExpr) node()       {}
-func (e *CallExpr) node()       {}
-func (e *UserCallExpr) node()   {}
-func (e *MultiExpr) node()      {}
-func (e *GetlineExpr) node()    {}
-func (e *GroupingExpr) node()   {}
-func (s *PrintStmt) node()      {}
-func (s *PrintfStmt) node()     {}
-func (s *ExprStmt) node()       {}
-func (s *IfStmt) node()         {}
-func (s *ForStmt) node()        {}
-func (s *ForInStmt) node()      {}
-func (s *WhileStmt) node()      {}
-func (s *DoWhileStmt) node()    {}
-func (s *BreakStmt) node()      {}
-func (s *ContinueStmt) node()   {}
-func (s *NextStmt) node()       {}
-func (s *NextfileStmt) node()   {}
-func (s *ExitStmt) node()       {}
-func (s *DeleteStmt) node()     {}
-func (s *ReturnStmt) node()     {}
-func (s *BlockStmt) node()      {}
+func (p *Program) node()          {}
+func (a *Action) node()           {}
+func (f *Function) node()         {}
+func (e *FieldExpr) node()        {}
+func (e *NamedFieldExpr) node()   {}
+func (e *UnaryExpr) node()        {}
+func (e *BinaryExpr) node()       {}
+func (e *InExpr) node()           {}
+func (e *CondExpr) node()         {}
+func (e *NumExpr) node()          {}
+func (e *StrExpr) node()          {}
+func (e *RegExpr) node()          {}
+func (e *VarExpr) node()          {}
+func (e *IndexExpr) node()        {}
+func (e *AssignExpr) node()       {}
+func (e *AugAssignExpr) node()    {}
+func (e *IncrExpr) node()         {}
+func (e *CallExpr) node()         {}
+func (e *UserCallExpr) node()     {}
+func (e *IndirectCallExpr) node() {}
+func (e *MultiExpr) node()        {}
+func (e *GetlineExpr) node()      {}
+func (e *GroupingExpr) node()     {}
+func (s *PrintStmt) node()        {}
+func (s *PrintfStmt) node()       {}
+func (s *PrintrowStmt) node()     {}
+func (s *ExprStmt) node()         {}
+func (s *IfStmt) node()           {}
+func (s *ForStmt) node()          {}
+func (s *ForInStmt) node()        {}
+func (s *WhileStmt) node()        {}
+func (s *DoWhileStmt) node()      {}
+func (s *SwitchStmt) node()       {}
+func (s *BreakStmt) node()        {}
+func (s *ContinueStmt) node()     {}
+func (s *NextStmt) node()         {}
+func (s *NextfileStmt) node()     {}
+func (s *ExitStmt) node()         {}
+func (s *DeleteStmt) node()       {}
+func (s *ReturnStmt) node()       {}
+func (s *BlockStmt) node()        {}
 
 // Expr is the abstract syntax tree for any AWK expression.
 type Expr interface {
@@ -148,23 +159,24 @@ const (
 )
 
 // All these types implement the Expr interface.
-func (e *FieldExpr) precedence() int      { return precField }
-func (e *NamedFieldExpr) precedence() int { return precField }
-func (e *UnaryExpr) precedence() int      { return precUnary }
-func (e *InExpr) precedence() int         { return precIn }
-func (e *CondExpr) precedence() int       { return precCond }
-func (e *NumExpr) precedence() int        { return precPrimary }
-func (e *StrExpr) precedence() int        { return precPrimary }
-func (e *RegExpr) precedence() int        { return precPrimary }
-func (e *VarExpr) precedence() int        { return precPrimary }
-func (e *IndexExpr) precedence() int      { return precPrimary }
-func (e *AssignExpr) precedence() int     { return precAssign }
-func (e *AugAssignExpr) precedence() int  { return precAssign }
-func (e *CallExpr) precedence() int       { return precPrimary }
-func (e *UserCallExpr) precedence() int   { return precPrimary }
-func (e *MultiExpr) precedence() int      { return precPrimary }
-func (e *GetlineExpr) precedence() int    { return precPrimary }
-func (e *GroupingExpr) precedence() int   { return precGrouping }
+func (e *FieldExpr) precedence() int        { return precField }
+func (e *NamedFieldExpr) precedence() int   { return precField }
+func (e *UnaryExpr) precedence() int        { return precUnary }
+func (e *InExpr) precedence() int           { return precIn }
+func (e *CondExpr) precedence() int         { return precCond }
+func (e *NumExpr) precedence() int          { return precPrimary }
+func (e *StrExpr) precedence() int          { return precPrimary }
+func (e *RegExpr) precedence() int          { return precPrimary }
+func (e *VarExpr) precedence() int          { return precPrimary }
+func (e *IndexExpr) precedence() int        { return precPrimary }
+func (e *AssignExpr) precedence() int       { return precAssign }
+func (e *AugAssignExpr) precedence() int    { return precAssign }
+func (e *CallExpr) precedence() int         { return precPrimary }
+func (e *UserCallExpr) precedence() int     { return precPrimary }
+func (e *IndirectCallExpr) precedence() int { return precPrimary }
+func (e *MultiExpr) precedence() int        { return precPrimary }
+func (e *GetlineExpr) precedence() int      { return precPrimary }
+func (e *GroupingExpr) precedence() int     { return precGrouping }
 
 func (e *IncrExpr) precedence() int {
 	if e.Pre {
@@ -250,22 +262,25 @@ func (e *BinaryExpr) String() string {
 	return parenthesize(e.Left, e) + op + parenthesize(e.Right, e)
 }
 
-// InExpr is an expression like (index in array).
+// InExpr is an expression like (index in array). If Path is non-empty, the
+// array is a subarray, as in (index in array[x][y]).
 type InExpr struct {
 	Index    []Expr
 	Array    string
 	ArrayPos lexer.Position
+	Path     [][]Expr
 }
 
 func (e *InExpr) String() string {
+	array := e.Array + subscripts(e.Path)
 	if len(e.Index) == 1 {
-		return parenthesize(e.Index[0], e) + " in " + e.Array
+		return parenthesize(e.Index[0], e) + " in " + array
 	}
 	indices := make([]string, len(e.Index))
 	for i, index := range e.Index {
 		indices[i] = index.String()
 	}
-	return "(" + strings.Join(indices, ", ") + ") in " + e.Array
+	return "(" + strings.Join(indices, ", ") + ") in " + array
 }
 
 // CondExpr is an expression like cond ? 1 : 0.
@@ -282,10 +297,16 @@ func (e *CondExpr) String() string {
 // NumExpr is a literal number like 1234.
 type NumExpr struct {
 	Value float64
+
+	// Source text of a literal that can't be represented exactly by Value,
+	// such as a large integer or 1e400 (empty otherwise).
+	Text string
 }
 
 func (e *NumExpr) String() string {
-	if e.Value == float64(int64(e.Value)) {
+	if e.Text != "" {
+		return e.Text
+	} else if e.Value == float64(int64(e.Value)) {
 		return strconv.FormatInt(int64(e.Value), 10)
 	} else {
 		return fmt.Sprintf("%.6g", e.Value)
@@ -325,19 +346,31 @@ func (e *VarExpr) String() string {
 	return e.Name
 }
 
-// IndexExpr is an expression like a[k] (rvalue or lvalue).
+// IndexExpr is an expression like a[k] (rvalue or lvalue). For an element
+// of a subarray like a[x][y][k], Path holds the subscripts leading to the
+// subarray ([x] and [y]) and Index is the final subscript ([k]).
 type IndexExpr struct {
 	Array    string
 	ArrayPos lexer.Position
+	Path     [][]Expr
 	Index    []Expr
 }
 
 func (e *IndexExpr) String() string {
-	indices := make([]string, len(e.Index))
-	for i, index := range e.Index {
-		indices[i] = index.String()
+	return e.Array + subscripts(e.Path) + subscripts([][]Expr{e.Index})
+}
+
+// Return the subscripts in path formatted like [x][y, z].
+func subscripts(path [][]Expr) string {
+	var sb strings.Builder
+	for _, index := range path {
+		indices := make([]string, len(index))
+		for i, expr := range index {
+			indices[i] = expr.String()
+		}
+		sb.WriteString("[" + strings.Join(indices, ", ") + "]")
 	}
-	return e.Array + "[" + strings.Join(indices, ", ") + "]"
+	return sb.String()
 }
 
 // AssignExpr is an expression like x = 1234.
@@ -406,6 +439,22 @@ func (e *UserCallExpr) String() string {
 	return e.Name + "(" + strings.Join(args, ", ") + ")"
 }
 
+// IndirectCallExpr is an indirect function call like @f(1, 2, 3), where
+// the variable f holds the name of the function to call.
+type IndirectCallExpr struct {
+	Func *VarExpr
+	Args []Expr
+	Pos  lexer.Position
+}
+
+func (e *IndirectCallExpr) String() string {
+	args := make([]string, len(e.Args))
+	for i, a := range e.Args {
+		args[i] = a.String()
+	}
+	return "@" + e.Func.String() + "(" + strings.Join(args, ", ") + ")"
+}
+
 // MultiExpr isn't an interpretable expression, but it's used as a
 // pseudo-expression for print[f] parsing.
 type MultiExpr struct {
@@ -422,15 +471,19 @@ func (e *MultiExpr) String() string {
 
 // GetlineExpr is an expression read from file or pipe input.
 type GetlineExpr struct {
-	Command Expr
-	Target  Expr
-	File    Expr
+	Command   Expr
+	Coprocess bool // true for "cmd |& getline", false for "cmd | getline"
+	Target    Expr
+	File      Expr
 }
 
 func (e *GetlineExpr) String() string {
 	s := ""
 	if e.Command != nil {
 		s += parenthesize(e.Command, e) + " |"
+		if e.Coprocess {
+			s += "&"
+		}
 	}
 	s += "getline"
 	if e.Target != nil {
@@ -481,12 +534,14 @@ type Stmt interface {
 // All these types implement the Stmt interface.
 func (s *PrintStmt) stmt()    {}
 func (s *PrintfStmt) stmt()   {}
+func (s *PrintrowStmt) stmt() {}
 func (s *ExprStmt) stmt()     {}
 func (s *IfStmt) stmt()       {}
 func (s *ForStmt) stmt()      {}
 func (s *ForInStmt) stmt()    {}
 func (s *WhileStmt) stmt()    {}
 func (s *DoWhileStmt) stmt()  {}
+func (s *SwitchStmt) stmt()   {}
 func (s *BreakStmt) stmt()    {}
 func (s *ContinueStmt) stmt() {}
 func (s *NextStmt) stmt()     {}
@@ -498,12 +553,14 @@ func (s *BlockStmt) stmt()    {}
 
 func (s *PrintStmt) StartPos() lexer.Position    { return s.Start }
 func (s *PrintfStmt) StartPos() lexer.Position   { return s.Start }
+func (s *PrintrowStmt) StartPos() lexer.Position { return s.Start }
 func (s *ExprStmt) StartPos() lexer.Position     { return s.Start }
 func (s *IfStmt) StartPos() lexer.Position       { return s.Start }
 func (s *ForStmt) StartPos() lexer.Position      { return s.Start }
 func (s *ForInStmt) StartPos() lexer.Position    { return s.Start }
 func (s *WhileStmt) StartPos() lexer.Position    { return s.Start }
 func (s *DoWhileStmt) StartPos() lexer.Position  { return s.Start }
+func (s *SwitchStmt) StartPos() lexer.Position   { return s.Start }
 func (s *BreakStmt) StartPos() lexer.Position    { return s.Start }
 func (s *ContinueStmt) StartPos() lexer.Position { return s.Start }
 func (s *NextStmt) StartPos() lexer.Position     { return s.Start }
@@ -515,12 +572,14 @@ func (s *BlockStmt) StartPos() lexer.Position    { return s.Start }
 
 func (s *PrintStmt) EndPos() lexer.Position    { return s.End }
 func (s *PrintfStmt) EndPos() lexer.Position   { return s.End }
+func (s *PrintrowStmt) EndPos() lexer.Position { return s.End }
 func (s *ExprStmt) EndPos() lexer.Position     { return s.End }
 func (s *IfStmt) EndPos() lexer.Position       { return s.End }
 func (s *ForStmt) EndPos() lexer.Position      { return s.End }
 func (s *ForInStmt) EndPos() lexer.Position    { return s.End }
 func (s *WhileStmt) EndPos() lexer.Position    { return s.End }
 func (s *DoWhileStmt) EndPos() lexer.Position  { return s.End }
+func (s *SwitchStmt) EndPos() lexer.Position   { return s.End }
 func (s *BreakStmt) EndPos() lexer.Position    { return s.End }
 func (s *ContinueStmt) EndPos() lexer.Position { return s.End }
 func (s *NextStmt) EndPos() lexer.Position     { return s.End }
@@ -568,6 +627,21 @@ func (s *PrintfStmt) String() string {
 	return printString("printf", s.Args, s.Redirect, s.Dest)
 }
 
+// PrintrowStmt is a printrow() call with its output redirected, like
+// printrow(a) > "out.csv". Without a redirect, printrow() is an ordinary
+// CallExpr.
+type PrintrowStmt struct {
+	Call     *CallExpr
+	Redirect lexer.Token
+	Dest     Expr
+	Start    lexer.Position
+	End      lexer.Position
+}
+
+func (s *PrintrowStmt) String() string {
+	return s.Call.String() + " " + s.Redirect.String() + s.Dest.String()
+}
+
 // ExprStmt is statement like a bare function call: my_func(x).
 type ExprStmt struct {
 	Expr  Expr
@@ -624,12 +698,14 @@ func (s *ForStmt) String() string {
 	return "for (" + preStr + ";" + condStr + ";" + postStr + ") {\n" + s.Body.String() + "}"
 }
 
-// ForInStmt is a for loop like for (k in a) print k, a[k].
+// ForInStmt is a for loop like for (k in a) print k, a[k]. If Path is
+// non-empty, the loop is over a subarray, as in for (k in a[x]).
 type ForInStmt struct {
 	Var       string
 	VarPos    lexer.Position
 	Array     string
 	ArrayPos  lexer.Position
+	Path      [][]Expr
 	BodyStart lexer.Position
 	Body      Stmts
 	Start     lexer.Position
@@ -637,7 +713,7 @@ type ForInStmt struct {
 }
 
 func (s *ForInStmt) String() string {
-	return "for (" + s.Var + " in " + s.Array + ") {\n" + s.Body.String() + "}"
+	return "for (" + s.Var + " in " + s.Array + subscripts(s.Path) + ") {\n" + s.Body.String() + "}"
 }
 
 // WhileStmt is a while loop.
@@ -665,6 +741,40 @@ func (s *DoWhileStmt) String() string {
 	return "do {\n" + s.Body.String() + "} while (" + s.Cond.String() + ")"
 }
 
+// SwitchStmt is a gawk-style switch statement. Execution starts at the first
+// case whose value matches Expr (or the default case if none do) and falls
+// through to later cases until a break.
+type SwitchStmt struct {
+	Expr      Expr
+	BodyStart lexer.Position
+	Cases     []*SwitchCase
+	Start     lexer.Position
+	End       lexer.Position
+}
+
+func (s *SwitchStmt) String() string {
+	str := "switch (" + s.Expr.String() + ") {\n"
+	for _, c := range s.Cases {
+		str += c.String()
+	}
+	return str + "}"
+}
+
+// SwitchCase is a single "case value:" or "default:" clause of a switch
+// statement. Value is a *NumExpr, *StrExpr, or *RegExpr, or nil for the
+// default case.
+type SwitchCase struct {
+	Value Expr
+	Body  Stmts
+}
+
+func (c *SwitchCase) String() string {
+	if c.Value == nil {
+		return "default:\n" + c.Body.String()
+	}
+	return "case " + c.Value.String() + ":\n" + c.Body.String()
+}
+
 // BreakStmt is a break statement.
 type BreakStmt struct {
 	Start lexer.Position
@@ -720,10 +830,12 @@ func (s *ExitStmt) String() string {
 	return "exit" + statusStr
 }
 
-// DeleteStmt is a statement like delete a[k].
+// DeleteStmt is a statement like delete a[k]. If Path is non-empty, the
+// element is deleted from a subarray, as in delete a[x][k].
 type DeleteStmt struct {
 	Array    string
 	ArrayPos lexer.Position
+	Path     [][]Expr
 	Index    []Expr
 	Start    lexer.Position
 	End      lexer.Position
@@ -733,11 +845,7 @@ func (s *DeleteStmt) String() string {
 	if len(s.Index) == 0 {
 		return "delete " + s.Array
 	}
-	indices := make([]string, len(s.Index))
-	for i, index := range s.Index {
-		indices[i] = index.String()
-	}
-	return "delete " + s.Array + "[" + strings.Join(indices, ", ") + "]"
+	return "delete " + s.Array + subscripts(s.Path) + subscripts([][]Expr{s.Index})
 }
 
 // ReturnStmt is a return statement.
@@ -23,6 +23,13 @@ func WalkStmtList(v Visitor, stmts []Stmt) {
 	}
 }
 
+// Walk the subscripts of a subarray path (a[x][y]).
+func walkPath(v Visitor, path [][]Expr) {
+	for _, index := range path {
+		WalkExprList(v, index)
+	}
+}
+
 // Walk traverses an AST in depth-first order: It starts by calling
 // v.Visit(node); if node is nil, it does nothing. If the visitor w returned by
 // v.Visit(node) is not nil, Walk is invoked recursively with visitor
@@ -57,6 +64,7 @@ func Walk(v Visitor, node Node) {
 
 	case *InExpr:
 		WalkExprList(v, n.Index)
+		walkPath(v, n.Path)
 
 	case *CondExpr:
 		Walk(v, n.Cond)
@@ -68,6 +76,7 @@ func Walk(v Visitor, node Node) {
 	case *RegExpr: // leaf
 	case *VarExpr: // leaf
 	case *IndexExpr:
+		walkPath(v, n.Path)
 		WalkExprList(v, n.Index)
 
 	case *AssignExpr:
@@ -87,6 +96,10 @@ func Walk(v Visitor, node Node) {
 	case *UserCallExpr:
 		WalkExprList(v, n.Args)
 
+	case *IndirectCallExpr:
+		Walk(v, n.Func)
+		WalkExprList(v, n.Args)
+
 	case *MultiExpr:
 		WalkExprList(v, n.Exprs)
 
@@ -107,6 +120,10 @@ func Walk(v Visitor, node Node) {
 		WalkExprList(v, n.Args)
 		Walk(v, n.Dest)
 
+	case *PrintrowStmt:
+		Walk(v, n.Call)
+		Walk(v, n.Dest)
+
 	case *ExprStmt:
 		Walk(v, n.Expr)
 
@@ -122,6 +139,7 @@ func Walk(v Visitor, node Node) {
 		WalkStmtList(v, n.Body)
 
 	case *ForInStmt:
+		walkPath(v, n.Path)
 		WalkStmtList(v, n.Body)
 
 	case *WhileStmt:
@@ -132,6 +150,13 @@ func Walk(v Visitor, node Node) {
 		WalkStmtList(v, n.Body)
 		Walk(v, n.Cond)
 
+	case *SwitchStmt:
+		Walk(v, n.Expr)
+		for _, c := range n.Cases {
+			Walk(v, c.Value)
+			WalkStmtList(v, c.Body)
+		}
+
 	case *BreakStmt: // leaf
 	case *ContinueStmt: // leaf
 	case *NextStmt: // leaf
@@ -140,6 +165,7 @@ func Walk(v Visitor, node Node) {
 		Walk(v, n.Status)
 
 	case *DeleteStmt:
+		walkPath(v, n.Path)
 		WalkExprList(v, n.Index)
 
 	case *ReturnStmt:
@@ -152,9 +178,15 @@ func Walk(v Visitor, node Node) {
 		for _, stmts := range n.Begin {
 			WalkStmtList(v, stmts)
 		}
+		for _, stmts := range n.BeginFile {
+			WalkStmtList(v, stmts)
+		}
 		for _, action := range n.Actions {
 			Walk(v, action)
 		}
+		for _, stmts := range n.EndFile {
+			WalkStmtList(v, stmts)
+		}
 		for _, function := range n.Functions {
 			Walk(v, function)
 		}
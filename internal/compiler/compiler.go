@@ -15,12 +15,18 @@ import (
 // Program holds an entire compiled program.
 type Program struct {
 	Begin     []Opcode
+	BeginFile []Opcode
 	Actions   []Action
+	EndFile   []Opcode
 	End       []Opcode
 	Functions []Function
 	Nums      []float64
 	Strs      []string
 	Regexes   []*regexp.Regexp
+	Switches  []*SwitchTable
+
+	// True if compiled for bignum mode (see CompileBignum)
+	Bignum bool
 
 	// For disassembly
 	scalarNames     []string
@@ -44,6 +50,53 @@ type Function struct {
 	Body       []Opcode
 }
 
+// SwitchTable is the jump table for a switch statement. The Switch
+// instruction looks up the switch value in Nums and Strs (and tries any
+// regex cases) to find the first matching case, and jumps to its body, or to
+// Default if no case matches. Offsets are relative to the end of the Switch
+// instruction.
+type SwitchTable struct {
+	Cases   []SwitchCase    // in source order
+	Nums    map[float64]int // number case values to index in Cases
+	Strs    map[string]int  // string case values to index in Cases
+	Default int             // offset of default case, or end of switch if none
+}
+
+// SwitchCase is a single case in a SwitchTable.
+type SwitchCase struct {
+	Kind   SwitchKind
+	Num    float64 // value for SwitchNum
+	Str    string  // value for SwitchStr
+	Regex  int     // regex index for SwitchRegex
+	Offset int     // offset of case body
+}
+
+// SwitchKind is the type of a switch case value.
+type SwitchKind int
+
+const (
+	SwitchNum SwitchKind = iota
+	SwitchStr
+	SwitchRegex
+)
+
+// Compile BEGINFILE or ENDFILE blocks into a single block of code.
+func compileFileBlocks(blocks []ast.Stmts, resolved *resolver.ResolvedProgram, p *Program, indexes constantIndexes) []Opcode {
+	var code []Opcode
+	for _, stmts := range blocks {
+		c := compiler{resolved: resolved, program: p, indexes: indexes}
+		if len(stmts) > 0 {
+			c.stmts(stmts)
+		} else {
+			// Ensure empty 'BEGINFILE {}' isn't treated as no BEGINFILE,
+			// as its presence makes file open errors non-fatal.
+			c.add(Nop)
+		}
+		code = append(code, c.finish()...)
+	}
+	return code
+}
+
 // compileError is the internal error type raised in the rare cases when
 // compilation can't succeed, such as program too large (jump offsets greater
 // than 2GB). Most actual problems are caught as parse time.
@@ -57,6 +110,19 @@ func (e *compileError) Error() string {
 
 // Compile compiles an AST (parsed program) into virtual machine instructions.
 func Compile(resolved *resolver.ResolvedProgram) (compiledProg *Program, err error) {
+	return compile(resolved, false)
+}
+
+// CompileBignum is like Compile, but compiles the program for bignum mode:
+// number literals and arithmetic and comparison operators use the BigNum
+// and Big opcodes, and increments and augmented assignments aren't fused
+// into single opcodes. This means the interpreter doesn't need to check for
+// bignum mode in the fast paths of the normal opcodes.
+func CompileBignum(resolved *resolver.ResolvedProgram) (compiledProg *Program, err error) {
+	return compile(resolved, true)
+}
+
+func compile(resolved *resolver.ResolvedProgram, bignum bool) (compiledProg *Program, err error) {
 	defer func() {
 		// The compiler uses panic with a *compileError to signal compile
 		// errors internally, and they're caught here. This avoids the
@@ -67,7 +133,7 @@ func Compile(resolved *resolver.ResolvedProgram) (compiledProg *Program, err err
 		}
 	}()
 
-	p := &Program{}
+	p := &Program{Bignum: bignum}
 
 	// Reuse identical constants across entire program.
 	indexes := constantIndexes{
@@ -112,6 +178,10 @@ func Compile(resolved *resolver.ResolvedProgram) (compiledProg *Program, err err
 		p.Begin = append(p.Begin, c.finish()...)
 	}
 
+	// Compile BEGINFILE and ENDFILE blocks.
+	p.BeginFile = compileFileBlocks(resolved.BeginFile, resolved, p, indexes)
+	p.EndFile = compileFileBlocks(resolved.EndFile, resolved, p, indexes)
+
 	// Compile pattern-action blocks.
 	for _, action := range resolved.Actions {
 		var pattern [][]Opcode
@@ -249,6 +319,9 @@ func (c *compiler) stmt(stmt ast.Stmt) {
 			return
 
 		case *ast.IncrExpr:
+			if c.program.Bignum {
+				break
+			}
 			// Pre or post doesn't matter for an assignment expression
 			switch target := expr.Expr.(type) {
 			case *ast.VarExpr:
@@ -264,7 +337,15 @@ func (c *compiler) stmt(stmt ast.Stmt) {
 			case *ast.FieldExpr:
 				c.expr(target.Index)
 				c.add(IncrField, incrAmount(expr.Op))
+			case *ast.NamedFieldExpr:
+				c.expr(target.Field)
+				c.add(IncrFieldByName, incrAmount(expr.Op))
 			case *ast.IndexExpr:
+				if len(target.Path) > 0 {
+					c.ref(target.Array, target.Path, target.Index)
+					c.add(IncrRef, incrAmount(expr.Op))
+					return
+				}
 				c.index(target.Index)
 				scope, index := c.arrayInfo(target.Array)
 				switch scope {
@@ -277,6 +358,9 @@ func (c *compiler) stmt(stmt ast.Stmt) {
 			return
 
 		case *ast.AugAssignExpr:
+			if c.program.Bignum {
+				break
+			}
 			c.expr(expr.Right)
 
 			var augOp AugOp
@@ -309,7 +393,15 @@ func (c *compiler) stmt(stmt ast.Stmt) {
 			case *ast.FieldExpr:
 				c.expr(target.Index)
 				c.add(AugAssignField, Opcode(augOp))
+			case *ast.NamedFieldExpr:
+				c.expr(target.Field)
+				c.add(AugAssignFieldByName, Opcode(augOp))
 			case *ast.IndexExpr:
+				if len(target.Path) > 0 {
+					c.ref(target.Array, target.Path, target.Index)
+					c.add(AugAssignRef, Opcode(augOp))
+					return
+				}
 				c.index(target.Index)
 				scope, index := c.arrayInfo(target.Array)
 				switch scope {
@@ -344,6 +436,11 @@ func (c *compiler) stmt(stmt ast.Stmt) {
 		}
 		c.add(Printf, opcodeInt(len(s.Args)), Opcode(s.Redirect))
 
+	case *ast.PrintrowStmt:
+		c.expr(s.Dest) // redirect destination
+		c.printrow(s.Call.Args, s.Redirect)
+		c.add(Drop)
+
 	case *ast.IfStmt:
 		if len(s.Else) == 0 {
 			jumpOp := c.condition(s.Cond, true)
@@ -401,9 +498,16 @@ func (c *compiler) stmt(stmt ast.Stmt) {
 		// iterating, or write our own hash table that has a more flexible
 		// iterator.
 		varScope, varIndex := c.scalarInfo(s.Var)
-		arrayScope, arrayIndex := c.arrayInfo(s.Array)
-		mark := c.jumpForward(ForIn, opcodeInt(int(varScope)), opcodeInt(varIndex),
-			Opcode(arrayScope), opcodeInt(arrayIndex))
+		var mark int
+		if len(s.Path) > 0 {
+			// Loop over subarray, like for (k in a[x])
+			c.ref(s.Array, s.Path[:len(s.Path)-1], s.Path[len(s.Path)-1])
+			mark = c.jumpForward(ForInRef, opcodeInt(int(varScope)), opcodeInt(varIndex))
+		} else {
+			arrayScope, arrayIndex := c.arrayInfo(s.Array)
+			mark = c.jumpForward(ForIn, opcodeInt(int(varScope)), opcodeInt(varIndex),
+				Opcode(arrayScope), opcodeInt(arrayIndex))
+		}
 
 		c.breaks = append(c.breaks, nil) // nil tells BreakStmt it's a for-in loop
 		c.continues = append(c.continues, []int{})
@@ -454,6 +558,43 @@ func (c *compiler) stmt(stmt ast.Stmt) {
 
 		c.patchBreaks()
 
+	case *ast.SwitchStmt:
+		// Case bodies are laid out in order after the Switch instruction,
+		// so execution falls through from one case to the next till a break.
+		c.expr(s.Expr)
+		table := &SwitchTable{Nums: make(map[float64]int), Strs: make(map[string]int)}
+		c.add(Switch, opcodeInt(len(c.program.Switches)))
+		c.program.Switches = append(c.program.Switches, table)
+		c.breaks = append(c.breaks, []int{})
+
+		start := len(c.code)
+		hasDefault := false
+		for _, sc := range s.Cases {
+			offset := len(c.code) - start
+			switch value := sc.Value.(type) {
+			case nil:
+				table.Default = offset
+				hasDefault = true
+			case *ast.NumExpr:
+				if _, ok := table.Nums[value.Value]; !ok { // 0 and -0 are equal
+					table.Nums[value.Value] = len(table.Cases)
+				}
+				table.Cases = append(table.Cases, SwitchCase{Kind: SwitchNum, Num: value.Value, Offset: offset})
+			case *ast.StrExpr:
+				table.Strs[value.Value] = len(table.Cases)
+				table.Cases = append(table.Cases, SwitchCase{Kind: SwitchStr, Str: value.Value, Offset: offset})
+			case *ast.RegExpr:
+				regex := c.regexIndex(value.Regex)
+				table.Cases = append(table.Cases, SwitchCase{Kind: SwitchRegex, Regex: regex, Offset: offset})
+			}
+			c.stmts(sc.Body)
+		}
+		if !hasDefault {
+			table.Default = len(c.code) - start
+		}
+
+		c.patchBreaks()
+
 	case *ast.BreakStmt:
 		i := len(c.breaks) - 1
 		if c.breaks[i] == nil {
@@ -484,6 +625,11 @@ func (c *compiler) stmt(stmt ast.Stmt) {
 		}
 
 	case *ast.DeleteStmt:
+		if len(s.Path) > 0 {
+			c.ref(s.Array, s.Path, s.Index)
+			c.add(DeleteRef)
+			return
+		}
 		scope, index := c.arrayInfo(s.Array)
 		if len(s.Index) > 0 {
 			c.index(s.Index)
@@ -526,13 +672,20 @@ func (c *compiler) assign(target ast.Expr) {
 	case *ast.FieldExpr:
 		c.expr(t.Index)
 		c.add(AssignField)
+	case *ast.NamedFieldExpr:
+		c.expr(t.Field)
+		c.add(AssignFieldByName)
 	case *ast.IndexExpr:
-		c.index(t.Index)
+		c.indexRef(t)
 		c.assignIndexExpr(t)
 	}
 }
 
 func (c *compiler) assignIndexExpr(target *ast.IndexExpr) {
+	if len(target.Path) > 0 {
+		c.add(AssignRef)
+		return
+	}
 	scope, index := c.arrayInfo(target.Array)
 	switch scope {
 	case resolver.Global:
@@ -551,6 +704,9 @@ func (c *compiler) assignRoteIndex(target ast.Expr) {
 	case *ast.FieldExpr:
 		c.add(Rote)
 		c.add(AssignField)
+	case *ast.NamedFieldExpr:
+		c.add(Rote)
+		c.add(AssignFieldByName)
 	case *ast.IndexExpr:
 		c.add(Rote)
 		c.assignIndexExpr(t)
@@ -622,6 +778,9 @@ func (c *compiler) condition(expr ast.Expr, invert bool) Opcode {
 
 	switch cond := expr.(type) {
 	case *ast.BinaryExpr:
+		if c.program.Bignum {
+			break
+		}
 		// Optimize binary comparison expressions like "x < 10" into just
 		// JumpLess instead of two instructions (Less and JumpTrue).
 		switch cond.Op {
@@ -666,6 +825,18 @@ func (c *compiler) condition(expr ast.Expr, invert bool) Opcode {
 func (c *compiler) expr(expr ast.Expr) {
 	switch e := expr.(type) {
 	case *ast.NumExpr:
+		if e.Text != "" {
+			// Converting the string form to a number at runtime preserves
+			// the exact value of large integers (and of any literal in
+			// bignum mode).
+			c.add(Str, opcodeInt(c.strIndex(e.Text)))
+			c.numOp(UnaryPlus)
+			return
+		}
+		if c.program.Bignum {
+			c.add(BigNum, opcodeInt(c.numIndex(e.Value)))
+			return
+		}
 		c.add(Num, opcodeInt(c.numIndex(e.Value)))
 
 	case *ast.StrExpr:
@@ -743,15 +914,15 @@ func (c *compiler) expr(expr ast.Expr) {
 		if e.Pre {
 			c.dupeIndexLValue(e.Expr)
 			c.expr(&ast.NumExpr{Value: 1})
-			c.add(op)
+			c.numOp(op)
 			c.add(Dupe)
 			c.assignRoteIndex(e.Expr)
 		} else {
 			c.dupeIndexLValue(e.Expr)
-			c.add(UnaryPlus) // coerce result to number
+			c.numOp(UnaryPlus) // coerce result to number
 			c.add(Dupe)
 			c.expr(&ast.NumExpr{Value: 1})
-			c.add(op)
+			c.numOp(op)
 			c.assignRoteIndex(e.Expr)
 		}
 
@@ -764,7 +935,7 @@ func (c *compiler) expr(expr ast.Expr) {
 	case *ast.AugAssignExpr:
 		// Most AugAssignExpr (standalone) will be handled by the ExprStmt special case
 		switch e.Left.(type) {
-		case *ast.FieldExpr, *ast.IndexExpr:
+		case *ast.FieldExpr, *ast.NamedFieldExpr, *ast.IndexExpr:
 			c.expr(e.Right)
 			c.dupeIndexLValue(e.Left)
 			c.add(Rote)
@@ -790,7 +961,7 @@ func (c *compiler) expr(expr ast.Expr) {
 		c.patchForward(elseMark)
 
 	case *ast.IndexExpr:
-		c.index(e.Index)
+		c.indexRef(e)
 		c.indexExpr(e)
 
 	case *ast.CallExpr:
@@ -798,20 +969,91 @@ func (c *compiler) expr(expr ast.Expr) {
 		switch e.Func {
 		case lexer.F_SPLIT:
 			c.expr(e.Args[0])
-			varExpr := e.Args[1].(*ast.VarExpr) // split()'s 2nd arg is always an array
-			scope, index := c.arrayInfo(varExpr.Name)
+			sepIsRegex := 0
 			if len(e.Args) > 2 {
-				c.expr(e.Args[2])
 				strExpr, isStr := e.Args[2].(*ast.StrExpr)
-				sepIsRegex := 0
 				if isStr && strExpr.Regex {
 					sepIsRegex = 1
 				}
+			}
+			// split()'s 2nd arg is always an array (or a subarray)
+			if indexExpr, ok := e.Args[1].(*ast.IndexExpr); ok {
+				c.ref(indexExpr.Array, indexExpr.Path, indexExpr.Index)
+				if len(e.Args) > 2 {
+					c.expr(e.Args[2])
+					c.add(CallSplitSepRef, Opcode(sepIsRegex))
+				} else {
+					c.add(CallSplitRef)
+				}
+				return
+			}
+			varExpr := e.Args[1].(*ast.VarExpr)
+			scope, index := c.arrayInfo(varExpr.Name)
+			if len(e.Args) > 2 {
+				c.expr(e.Args[2])
 				c.add(CallSplitSep, Opcode(scope), opcodeInt(index), Opcode(sepIsRegex))
 			} else {
 				c.add(CallSplit, Opcode(scope), opcodeInt(index))
 			}
 			return
+		case lexer.F_PATSPLIT:
+			// Field and optional seps args are arrays (or subarrays), and
+			// the pattern defaults to the current value of FPAT
+			c.expr(e.Args[0])
+			var arrayArgs []Opcode
+			addArray := func(arg ast.Expr) {
+				switch arg := arg.(type) {
+				case *ast.VarExpr:
+					scope, index := c.arrayInfo(arg.Name)
+					arrayArgs = append(arrayArgs, Opcode(scope), opcodeInt(index))
+				case *ast.IndexExpr:
+					c.ref(arg.Array, arg.Path, arg.Index)
+					arrayArgs = append(arrayArgs, 0, 0)
+				}
+			}
+			addArray(e.Args[1])
+			if len(e.Args) > 2 {
+				c.expr(e.Args[2])
+			} else {
+				c.add(Special, opcodeInt(ast.V_FPAT))
+			}
+			if len(e.Args) > 3 {
+				addArray(e.Args[3])
+			}
+			c.add(CallPatsplit, opcodeInt(len(arrayArgs)/2))
+			c.add(arrayArgs...)
+			return
+		case lexer.F_ASORT, lexer.F_ASORTI:
+			// Source and optional destination args are arrays (or subarrays)
+			var arrayArgs []Opcode
+			for i, arg := range e.Args {
+				if i >= 2 {
+					break
+				}
+				switch arg := arg.(type) {
+				case *ast.VarExpr:
+					scope, index := c.arrayInfo(arg.Name)
+					arrayArgs = append(arrayArgs, Opcode(scope), opcodeInt(index))
+				case *ast.IndexExpr:
+					c.ref(arg.Array, arg.Path, arg.Index)
+					arrayArgs = append(arrayArgs, 0, 0)
+				}
+			}
+			if len(e.Args) > 2 {
+				c.expr(e.Args[2])
+			} else {
+				c.expr(&ast.StrExpr{Value: ""})
+			}
+			op := CallAsort
+			if e.Func == lexer.F_ASORTI {
+				op = CallAsorti
+			}
+			c.add(op, opcodeInt(len(arrayArgs)/2))
+			c.add(arrayArgs...)
+			return
+		case lexer.F_PRINTROW:
+			c.printrow(e.Args, lexer.ILLEGAL)
+			return
 		case lexer.F_SUB, lexer.F_GSUB:
 			op := BuiltinSub
 			if e.Func == lexer.F_GSUB {
@@ -847,12 +1089,18 @@ func (c *compiler) expr(expr ast.Expr) {
 		case lexer.F_LENGTH:
 			if len(e.Args) > 0 {
 				// Determine if the call is length(arrayVar) or length(stringExpr).
-				if varExpr, ok := e.Args[0].(*ast.VarExpr); ok {
-					scope, info, _ := c.resolved.LookupVar(c.funcName, varExpr.Name)
+				switch arg := e.Args[0].(type) {
+				case *ast.VarExpr:
+					scope, info, _ := c.resolved.LookupVar(c.funcName, arg.Name)
 					if info.Type == resolver.Array {
 						c.add(CallLengthArray, Opcode(scope), opcodeInt(info.Index))
 						return
 					}
+				case *ast.IndexExpr:
+					// Array item may be a subarray, so determine at runtime.
+					c.ref(arg.Array, arg.Path, arg.Index)
+					c.add(CallLengthRef)
+					return
 				}
 				c.expr(e.Args[0])
 				c.add(CallBuiltin, Opcode(BuiltinLengthArg))
@@ -860,16 +1108,70 @@ func (c *compiler) expr(expr ast.Expr) {
 				c.add(CallBuiltin, Opcode(BuiltinLength))
 			}
 			return
+
+		case lexer.F_TYPEOF:
+			switch arg := e.Args[0].(type) {
+			case *ast.VarExpr:
+				_, info, _ := c.resolved.LookupVar(c.funcName, arg.Name)
+				switch {
+				case info.Type == resolver.Array:
+					c.add(Str, opcodeInt(c.strIndex("array")))
+					return
+				case info.Untyped:
+					c.expr(arg)
+					c.add(CallBuiltin, Opcode(BuiltinTypeofUntyped))
+					return
+				}
+			case *ast.IndexExpr:
+				// Array item may be a subarray or not exist, so determine at
+				// runtime (without creating the item).
+				c.ref(arg.Array, arg.Path, arg.Index)
+				c.add(CallTypeofRef)
+				return
+			}
+			c.expr(e.Args[0])
+			c.add(CallBuiltin, Opcode(BuiltinTypeof))
+			return
+
+		case lexer.F_ISARRAY:
+			switch arg := e.Args[0].(type) {
+			case *ast.VarExpr:
+				_, info, _ := c.resolved.LookupVar(c.funcName, arg.Name)
+				isArray := 0.0
+				if info.Type == resolver.Array {
+					isArray = 1
+				}
+				c.add(Num, opcodeInt(c.numIndex(isArray)))
+			case *ast.IndexExpr:
+				c.ref(arg.Array, arg.Path, arg.Index)
+				c.add(CallTypeofRef, Str, opcodeInt(c.strIndex("array")), Equals)
+			default:
+				c.expr(arg)
+				c.add(Drop, Num, opcodeInt(c.numIndex(0)))
+			}
+			return
 		}
 
 		for _, arg := range e.Args {
 			c.expr(arg)
 		}
 		switch e.Func {
+		case lexer.F_AND:
+			// and(), or(), and xor() take two or more arguments; apply the
+			// operation to each pair on the stack till one value remains.
+			for i := 1; i < len(e.Args); i++ {
+				c.add(CallBuiltin, Opcode(BuiltinAnd))
+			}
 		case lexer.F_ATAN2:
 			c.add(CallBuiltin, Opcode(BuiltinAtan2))
 		case lexer.F_CLOSE:
-			c.add(CallBuiltin, Opcode(BuiltinClose))
+			if len(e.Args) > 1 {
+				c.add(CallBuiltin, Opcode(BuiltinCloseHow))
+			} else {
+				c.add(CallBuiltin, Opcode(BuiltinClose))
+			}
+		case lexer.F_COMPL:
+			c.add(CallBuiltin, Opcode(BuiltinCompl))
 		case lexer.F_COS:
 			c.add(CallBuiltin, Opcode(BuiltinCos))
 		case lexer.F_EXP:
@@ -880,16 +1182,35 @@ func (c *compiler) expr(expr ast.Expr) {
 			} else {
 				c.add(CallBuiltin, Opcode(BuiltinFflushAll))
 			}
+		case lexer.F_GENSUB:
+			if len(e.Args) < 4 {
+				// Default target is $0
+				c.expr(&ast.FieldExpr{Index: &ast.NumExpr{}})
+			}
+			c.add(CallBuiltin, Opcode(BuiltinGensub))
 		case lexer.F_INDEX:
 			c.add(CallBuiltin, Opcode(BuiltinIndex))
 		case lexer.F_INT:
 			c.add(CallBuiltin, Opcode(BuiltinInt))
 		case lexer.F_LOG:
 			c.add(CallBuiltin, Opcode(BuiltinLog))
+		case lexer.F_LSHIFT:
+			c.add(CallBuiltin, Opcode(BuiltinLshift))
 		case lexer.F_MATCH:
 			c.add(CallBuiltin, Opcode(BuiltinMatch))
+		case lexer.F_MKTIME:
+			if len(e.Args) < 2 {
+				c.expr(&ast.NumExpr{}) // utc defaults to false
+			}
+			c.add(CallBuiltin, Opcode(BuiltinMktime))
+		case lexer.F_OR:
+			for i := 1; i < len(e.Args); i++ {
+				c.add(CallBuiltin, Opcode(BuiltinOr))
+			}
 		case lexer.F_RAND:
 			c.add(CallBuiltin, Opcode(BuiltinRand))
+		case lexer.F_RSHIFT:
+			c.add(CallBuiltin, Opcode(BuiltinRshift))
 		case lexer.F_SIN:
 			c.add(CallBuiltin, Opcode(BuiltinSin))
 		case lexer.F_SPRINTF:
@@ -902,6 +1223,23 @@ func (c *compiler) expr(expr ast.Expr) {
 			} else {
 				c.add(CallBuiltin, Opcode(BuiltinSrand))
 			}
+		case lexer.F_STRFTIME:
+			switch len(e.Args) {
+			case 0:
+				c.add(CallBuiltin, Opcode(BuiltinStrftime))
+			case 1:
+				c.add(CallBuiltin, Opcode(BuiltinStrftimeFormat))
+			case 2:
+				c.expr(&ast.NumExpr{}) // utc defaults to false
+				c.add(CallBuiltin, Opcode(BuiltinStrftimeTime))
+			default:
+				c.add(CallBuiltin, Opcode(BuiltinStrftimeTime))
+			}
+		case lexer.F_STRPTIME:
+			if len(e.Args) < 2 {
+				c.expr(&ast.StrExpr{Value: ""}) // default format is RFC 3339
+			}
+			c.add(CallBuiltin, Opcode(BuiltinStrptime))
 		case lexer.F_SUBSTR:
 			if len(e.Args) > 2 {
 				c.add(CallBuiltin, Opcode(BuiltinSubstrLength))
@@ -910,10 +1248,16 @@ func (c *compiler) expr(expr ast.Expr) {
 			}
 		case lexer.F_SYSTEM:
 			c.add(CallBuiltin, Opcode(BuiltinSystem))
+		case lexer.F_SYSTIME:
+			c.add(CallBuiltin, Opcode(BuiltinSystime))
 		case lexer.F_TOLOWER:
 			c.add(CallBuiltin, Opcode(BuiltinTolower))
 		case lexer.F_TOUPPER:
 			c.add(CallBuiltin, Opcode(BuiltinToupper))
+		case lexer.F_XOR:
+			for i := 1; i < len(e.Args); i++ {
+				c.add(CallBuiltin, Opcode(BuiltinXor))
+			}
 		default:
 			panic(fmt.Sprintf("unexpected function: %s", e.Func))
 		}
@@ -922,14 +1266,19 @@ func (c *compiler) expr(expr ast.Expr) {
 		c.expr(e.Value)
 		switch e.Op {
 		case lexer.SUB:
-			c.add(UnaryMinus)
+			c.numOp(UnaryMinus)
 		case lexer.NOT:
 			c.add(Not)
 		default: // ADD
-			c.add(UnaryPlus)
+			c.numOp(UnaryPlus)
 		}
 
 	case *ast.InExpr:
+		if len(e.Path) > 0 {
+			c.ref(e.Array, e.Path, e.Index)
+			c.add(InRef)
+			return
+		}
 		c.index(e.Index)
 		scope, index := c.arrayInfo(e.Array)
 		switch scope {
@@ -948,13 +1297,28 @@ func (c *compiler) expr(expr ast.Expr) {
 			c.add(CallNative, opcodeInt(funcInfo.Index), opcodeInt(len(e.Args)))
 		} else {
 			f := c.program.Functions[funcInfo.Index]
+			// Subarray arguments (like a[x]) are pushed before the scalar
+			// arguments, and are indicated by an arrayScope of 0.
+			numRefArgs := 0
+			for i, arg := range e.Args {
+				if a, ok := arg.(*ast.IndexExpr); ok && f.Arrays[i] {
+					c.ref(a.Array, a.Path, a.Index)
+					numRefArgs++
+				}
+			}
 			var arrayOpcodes []Opcode
 			numScalarArgs := 0
+			refIndex := 0
 			for i, arg := range e.Args {
 				if f.Arrays[i] {
-					a := arg.(*ast.VarExpr)
-					scope, index := c.arrayInfo(a.Name)
-					arrayOpcodes = append(arrayOpcodes, Opcode(scope), opcodeInt(index))
+					switch a := arg.(type) {
+					case *ast.VarExpr:
+						scope, index := c.arrayInfo(a.Name)
+						arrayOpcodes = append(arrayOpcodes, Opcode(scope), opcodeInt(index))
+					case *ast.IndexExpr:
+						arrayOpcodes = append(arrayOpcodes, 0, opcodeInt(refIndex))
+						refIndex++
+					}
 				} else {
 					c.expr(arg)
 					numScalarArgs++
@@ -967,11 +1331,42 @@ func (c *compiler) expr(expr ast.Expr) {
 			c.add(arrayOpcodes...)
 		}
 
+	case *ast.IndirectCallExpr:
+		// The function isn't known until runtime, so array arguments are
+		// indicated by their (scope, index) and checked against the function's
+		// parameters by the VM. Other arguments are pushed in order, with
+		// a[x] pushed as a reference (as it may be a subarray), and are
+		// indicated by an argScope of 0. The function name is pushed last so
+		// that the VM can pop it and leave the arguments on the stack.
+		var argOpcodes []Opcode
+		for _, arg := range e.Args {
+			switch a := arg.(type) {
+			case *ast.VarExpr:
+				if _, info, _ := c.resolved.LookupVar(c.funcName, a.Name); info.Type == resolver.Array {
+					scope, index := c.arrayInfo(a.Name)
+					argOpcodes = append(argOpcodes, Opcode(scope), opcodeInt(index))
+					continue
+				}
+				c.expr(arg)
+			case *ast.IndexExpr:
+				c.ref(a.Array, a.Path, a.Index)
+			default:
+				c.expr(arg)
+			}
+			argOpcodes = append(argOpcodes, 0, 0)
+		}
+		c.expr(e.Func)
+		c.add(CallIndirect, opcodeInt(len(e.Args)))
+		c.add(argOpcodes...)
+
 	case *ast.GetlineExpr:
 		redirect := func() Opcode {
 			switch {
 			case e.Command != nil:
 				c.expr(e.Command)
+				if e.Coprocess {
+					return Opcode(lexer.PIPE_AND)
+				}
 				return Opcode(lexer.PIPE)
 			case e.File != nil:
 				c.expr(e.File)
@@ -995,6 +1390,11 @@ func (c *compiler) expr(expr ast.Expr) {
 			c.expr(target.Index)
 			c.add(GetlineField, redirect())
 		case *ast.IndexExpr:
+			if len(target.Path) > 0 {
+				c.ref(target.Array, target.Path, target.Index)
+				c.add(GetlineRef, redirect())
+				return
+			}
 			c.index(target.Index)
 			scope, index := c.arrayInfo(target.Array)
 			c.add(GetlineArray, redirect(), Opcode(scope), opcodeInt(index))
@@ -1012,11 +1412,20 @@ func (c *compiler) expr(expr ast.Expr) {
 }
 
 func (c *compiler) indexExpr(e *ast.IndexExpr) {
+	if len(e.Path) > 0 {
+		c.add(Deref)
+		return
+	}
 	scope, index := c.arrayInfo(e.Array)
-	switch scope {
-	case resolver.Global:
+	_, info, _ := c.resolved.LookupVar(c.funcName, e.Array)
+	switch {
+	case info.Subarrays && scope == resolver.Global:
+		c.add(ArrayGlobalChecked, opcodeInt(index))
+	case info.Subarrays:
+		c.add(ArrayLocalChecked, opcodeInt(index))
+	case scope == resolver.Global:
 		c.add(ArrayGlobal, opcodeInt(index))
-	case resolver.Local:
+	default: // resolver.Local
 		c.add(ArrayLocal, opcodeInt(index))
 	}
 }
@@ -1031,13 +1440,64 @@ func (c *compiler) dupeIndexLValue(expr ast.Expr) {
 		c.expr(e.Index)
 		c.add(Dupe)
 		c.add(Field)
+	case *ast.NamedFieldExpr:
+		c.expr(e.Field)
+		c.add(Dupe)
+		c.add(FieldByName)
 	case *ast.IndexExpr:
-		c.index(e.Index)
+		c.indexRef(e)
 		c.add(Dupe)
 		c.indexExpr(e)
 	}
 }
 
+// Generate the index of an index expression, or a reference to the array
+// item if it's an item of a subarray (like a[x][y]).
+func (c *compiler) indexRef(e *ast.IndexExpr) {
+	if len(e.Path) > 0 {
+		c.ref(e.Array, e.Path, e.Index)
+	} else {
+		c.index(e.Index)
+	}
+}
+
+// Generate a reference to the array item name[path...][index].
+// Compile a printrow() call with the given args, with its output redirected
+// if redirect isn't ILLEGAL (in which case the destination has already been
+// pushed).
+func (c *compiler) printrow(args []ast.Expr, redirect lexer.Token) {
+	// Array and optional fields args are arrays (or subarrays)
+	var arrayArgs []Opcode
+	for _, arg := range args {
+		switch arg := arg.(type) {
+		case *ast.VarExpr:
+			scope, index := c.arrayInfo(arg.Name)
+			arrayArgs = append(arrayArgs, Opcode(scope), opcodeInt(index))
+		case *ast.IndexExpr:
+			c.ref(arg.Array, arg.Path, arg.Index)
+			arrayArgs = append(arrayArgs, 0, 0)
+		}
+	}
+	c.add(CallPrintrow, opcodeInt(len(arrayArgs)/2))
+	c.add(arrayArgs...)
+	c.add(Opcode(redirect))
+}
+
+func (c *compiler) ref(name string, path [][]ast.Expr, index []ast.Expr) {
+	scope, arrayIndex := c.arrayInfo(name)
+	for i, subscript := range append(path[:len(path):len(path)], index) {
+		c.index(subscript)
+		switch {
+		case i > 0:
+			c.add(RefIndex)
+		case scope == resolver.Global:
+			c.add(RefGlobal, opcodeInt(arrayIndex))
+		default: // resolver.Local
+			c.add(RefLocal, opcodeInt(arrayIndex))
+		}
+	}
+}
+
 // Generate a Concat opcode or, if possible, compact multiple Concats into one
 // ConcatMulti opcode.
 func (c *compiler) concatOp(expr *ast.BinaryExpr) {
@@ -1135,9 +1595,11 @@ func (c *compiler) binaryOp(op lexer.Token) {
 	case lexer.NOT_EQUALS:
 		opcode = NotEquals
 	case lexer.MATCH:
-		opcode = Match
+		c.add(Match)
+		return
 	case lexer.NOT_MATCH:
-		opcode = NotMatch
+		c.add(NotMatch)
+		return
 	case lexer.POW:
 		opcode = Power
 	case lexer.MOD:
@@ -1145,7 +1607,17 @@ func (c *compiler) binaryOp(op lexer.Token) {
 	default:
 		panic(fmt.Sprintf("unexpected binary operation: %s", op))
 	}
-	c.add(opcode)
+	c.numOp(opcode)
+}
+
+// Add an arithmetic, comparison, or unary plus or minus opcode, using the
+// arbitrary-precision form if compiling for bignum mode.
+func (c *compiler) numOp(op Opcode) {
+	if c.program.Bignum {
+		c.add(Big, op)
+	} else {
+		c.add(op)
+	}
 }
 
 // Generate an array index, handling multi-indexes properly.
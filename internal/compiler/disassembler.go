@@ -5,6 +5,7 @@ package compiler
 import (
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 
 	"github.com/benhoyt/goawk/internal/ast"
@@ -28,6 +29,19 @@ func (p *Program) Disassemble(writer io.Writer) error {
 		}
 	}
 
+	if p.BeginFile != nil {
+		d := &disassembler{
+			program:         p,
+			writer:          writer,
+			code:            p.BeginFile,
+			nativeFuncNames: p.nativeFuncNames,
+		}
+		err := d.disassemble("BEGINFILE")
+		if err != nil {
+			return err
+		}
+	}
+
 	for _, action := range p.Actions {
 		switch len(action.Pattern) {
 		case 0:
@@ -79,6 +93,19 @@ func (p *Program) Disassemble(writer io.Writer) error {
 		}
 	}
 
+	if p.EndFile != nil {
+		d := &disassembler{
+			program:         p,
+			writer:          writer,
+			code:            p.EndFile,
+			nativeFuncNames: p.nativeFuncNames,
+		}
+		err := d.disassemble("ENDFILE")
+		if err != nil {
+			return err
+		}
+	}
+
 	if p.End != nil {
 		d := &disassembler{
 			program:         p,
@@ -172,6 +199,14 @@ func (d *disassembler) disassemble(prefix string) error {
 			arrayIndex := d.fetch()
 			d.writeOpf("ArrayLocal %s", d.localArrayName(int(arrayIndex)))
 
+		case ArrayGlobalChecked:
+			arrayIndex := d.fetch()
+			d.writeOpf("ArrayGlobalChecked %s", d.program.arrayNames[arrayIndex])
+
+		case ArrayLocalChecked:
+			arrayIndex := d.fetch()
+			d.writeOpf("ArrayLocalChecked %s", d.localArrayName(int(arrayIndex)))
+
 		case InGlobal:
 			arrayIndex := d.fetch()
 			d.writeOpf("InGlobal %s", d.program.arrayNames[arrayIndex])
@@ -180,6 +215,14 @@ func (d *disassembler) disassemble(prefix string) error {
 			arrayIndex := int(d.fetch())
 			d.writeOpf("InLocal %s", d.localArrayName(arrayIndex))
 
+		case RefGlobal:
+			arrayIndex := d.fetch()
+			d.writeOpf("RefGlobal %s", d.program.arrayNames[arrayIndex])
+
+		case RefLocal:
+			arrayIndex := int(d.fetch())
+			d.writeOpf("RefLocal %s", d.localArrayName(arrayIndex))
+
 		case AssignGlobal:
 			index := d.fetch()
 			d.writeOpf("AssignGlobal %s", d.program.scalarNames[index])
@@ -214,6 +257,10 @@ func (d *disassembler) disassemble(prefix string) error {
 			amount := d.fetch()
 			d.writeOpf("IncrField %d", amount)
 
+		case IncrFieldByName:
+			amount := d.fetch()
+			d.writeOpf("IncrFieldByName %d", amount)
+
 		case IncrGlobal:
 			amount := d.fetch()
 			index := d.fetch()
@@ -239,10 +286,18 @@ func (d *disassembler) disassemble(prefix string) error {
 			arrayIndex := int(d.fetch())
 			d.writeOpf("IncrArrayLocal %d %s", amount, d.localArrayName(arrayIndex))
 
+		case IncrRef:
+			amount := d.fetch()
+			d.writeOpf("IncrRef %d", amount)
+
 		case AugAssignField:
 			operation := AugOp(d.fetch())
 			d.writeOpf("AugAssignField %s", operation)
 
+		case AugAssignFieldByName:
+			operation := AugOp(d.fetch())
+			d.writeOpf("AugAssignFieldByName %s", operation)
+
 		case AugAssignGlobal:
 			operation := AugOp(d.fetch())
 			index := d.fetch()
@@ -268,6 +323,10 @@ func (d *disassembler) disassemble(prefix string) error {
 			arrayIndex := int(d.fetch())
 			d.writeOpf("AugAssignArrayLocal %s %s", operation, d.localArrayName(arrayIndex))
 
+		case AugAssignRef:
+			operation := AugOp(d.fetch())
+			d.writeOpf("AugAssignRef %s", operation)
+
 		case Regex:
 			regexIndex := d.fetch()
 			d.writeOpf("Regex %q (%d)", d.program.Regexes[regexIndex], regexIndex)
@@ -280,6 +339,25 @@ func (d *disassembler) disassemble(prefix string) error {
 			num := d.fetch()
 			d.writeOpf("ConcatMulti %d", num)
 
+		case Switch:
+			switchIndex := d.fetch()
+			table := d.program.Switches[switchIndex]
+			var cases []string
+			for _, c := range table.Cases {
+				var value string
+				switch c.Kind {
+				case SwitchNum:
+					value = strconv.FormatFloat(c.Num, 'g', -1, 64)
+				case SwitchStr:
+					value = strconv.Quote(c.Str)
+				default:
+					value = "/" + d.program.Regexes[c.Regex].String() + "/"
+				}
+				cases = append(cases, fmt.Sprintf("%s:0x%04x", value, d.ip+c.Offset))
+			}
+			cases = append(cases, fmt.Sprintf("default:0x%04x", d.ip+table.Default))
+			d.writeOpf("Switch %s", strings.Join(cases, " "))
+
 		case Jump:
 			offset := d.fetch()
 			d.writeOpf("Jump 0x%04x", d.ip+int(offset))
@@ -324,6 +402,12 @@ func (d *disassembler) disassemble(prefix string) error {
 			offset := d.fetch()
 			d.writeOpf("ForIn %s %s 0x%04x", d.varName(varScope, varIndex), d.arrayName(arrayScope, arrayIndex), d.ip+int(offset))
 
+		case ForInRef:
+			varScope := resolver.Scope(d.fetch())
+			varIndex := int(d.fetch())
+			offset := d.fetch()
+			d.writeOpf("ForInRef %s 0x%04x", d.varName(varScope, varIndex), d.ip+int(offset))
+
 		case CallBuiltin:
 			builtinOp := BuiltinOp(d.fetch())
 			d.writeOpf("CallBuiltin %s", builtinOp)
@@ -344,10 +428,33 @@ func (d *disassembler) disassemble(prefix string) error {
 			sepIsRegex := d.fetch()
 			d.writeOpf("CallSplitSep %s %d", d.arrayName(arrayScope, arrayIndex), sepIsRegex)
 
+		case CallSplitSepRef:
+			sepIsRegex := d.fetch()
+			d.writeOpf("CallSplitSepRef %d", sepIsRegex)
+
 		case CallSprintf:
 			numArgs := d.fetch()
 			d.writeOpf("CallSprintf %d", numArgs)
 
+		case CallPatsplit, CallAsort, CallAsorti, CallPrintrow:
+			numArrays := int(d.fetch())
+			var arrays []string
+			for i := 0; i < numArrays; i++ {
+				arrayScope := resolver.Scope(d.fetch())
+				arrayIndex := int(d.fetch())
+				if arrayScope == 0 {
+					arrays = append(arrays, "ref")
+					continue
+				}
+				arrays = append(arrays, d.arrayName(arrayScope, arrayIndex))
+			}
+			if op == CallPrintrow {
+				if redirect := lexer.Token(d.fetch()); redirect != lexer.ILLEGAL {
+					arrays = append(arrays, redirect.String())
+				}
+			}
+			d.writeOpf("%s %s", op, strings.Join(arrays, " "))
+
 		case CallUser:
 			funcIndex := d.fetch()
 			numArrayArgs := int(d.fetch())
@@ -355,6 +462,10 @@ func (d *disassembler) disassemble(prefix string) error {
 			for i := 0; i < numArrayArgs; i++ {
 				arrayScope := resolver.Scope(d.fetch())
 				arrayIndex := int(d.fetch())
+				if arrayScope == 0 {
+					arrayArgs = append(arrayArgs, fmt.Sprintf("ref%d", arrayIndex))
+					continue
+				}
 				arrayArgs = append(arrayArgs, d.arrayName(arrayScope, arrayIndex))
 			}
 			d.writeOpf("CallUser %s [%s]", d.program.Functions[funcIndex].Name, strings.Join(arrayArgs, ", "))
@@ -364,6 +475,20 @@ func (d *disassembler) disassemble(prefix string) error {
 			numArgs := d.fetch()
 			d.writeOpf("CallNative %s %d", d.nativeFuncNames[funcIndex], numArgs)
 
+		case CallIndirect:
+			numArgs := int(d.fetch())
+			var args []string
+			for i := 0; i < numArgs; i++ {
+				argScope := resolver.Scope(d.fetch())
+				argIndex := int(d.fetch())
+				if argScope == 0 {
+					args = append(args, "arg")
+					continue
+				}
+				args = append(args, d.arrayName(argScope, argIndex))
+			}
+			d.writeOpf("CallIndirect [%s]", strings.Join(args, ", "))
+
 		case Nulls:
 			numNulls := d.fetch()
 			d.writeOpf("Nulls %d", numNulls)
@@ -415,6 +540,18 @@ func (d *disassembler) disassemble(prefix string) error {
 			arrayIndex := int(d.fetch())
 			d.writeOpf("GetlineArray %s %s", redirect, d.arrayName(arrayScope, arrayIndex))
 
+		case GetlineRef:
+			redirect := lexer.Token(d.fetch())
+			d.writeOpf("GetlineRef %s", redirect)
+
+		case BigNum:
+			index := d.fetch()
+			d.writeOpf("BigNum %.6g (%d)", d.program.Nums[index], index)
+
+		case Big:
+			operation := d.fetch()
+			d.writeOpf("Big %s", operation)
+
 		default:
 			// Handles all other opcodes with no arguments
 			d.writeOpf("%s", op)
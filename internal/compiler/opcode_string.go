@@ -26,88 +26,116 @@ func _() {
 	_ = x[ArrayLocal-15]
 	_ = x[InGlobal-16]
 	_ = x[InLocal-17]
-	_ = x[AssignField-18]
-	_ = x[AssignFieldSub-19]
-	_ = x[AssignGlobal-20]
-	_ = x[AssignLocal-21]
-	_ = x[AssignSpecial-22]
-	_ = x[AssignArrayGlobal-23]
-	_ = x[AssignArrayLocal-24]
-	_ = x[Delete-25]
-	_ = x[DeleteAll-26]
-	_ = x[IncrField-27]
-	_ = x[IncrGlobal-28]
-	_ = x[IncrLocal-29]
-	_ = x[IncrSpecial-30]
-	_ = x[IncrArrayGlobal-31]
-	_ = x[IncrArrayLocal-32]
-	_ = x[AugAssignField-33]
-	_ = x[AugAssignGlobal-34]
-	_ = x[AugAssignLocal-35]
-	_ = x[AugAssignSpecial-36]
-	_ = x[AugAssignArrayGlobal-37]
-	_ = x[AugAssignArrayLocal-38]
-	_ = x[Regex-39]
-	_ = x[IndexMulti-40]
-	_ = x[ConcatMulti-41]
-	_ = x[Add-42]
-	_ = x[Subtract-43]
-	_ = x[Multiply-44]
-	_ = x[Divide-45]
-	_ = x[Power-46]
-	_ = x[Modulo-47]
-	_ = x[Equals-48]
-	_ = x[NotEquals-49]
-	_ = x[Less-50]
-	_ = x[Greater-51]
-	_ = x[LessOrEqual-52]
-	_ = x[GreaterOrEqual-53]
-	_ = x[Concat-54]
-	_ = x[Match-55]
-	_ = x[NotMatch-56]
-	_ = x[Not-57]
-	_ = x[UnaryMinus-58]
-	_ = x[UnaryPlus-59]
-	_ = x[Boolean-60]
-	_ = x[Jump-61]
-	_ = x[JumpFalse-62]
-	_ = x[JumpTrue-63]
-	_ = x[JumpEquals-64]
-	_ = x[JumpNotEquals-65]
-	_ = x[JumpLess-66]
-	_ = x[JumpGreater-67]
-	_ = x[JumpLessOrEqual-68]
-	_ = x[JumpGreaterOrEqual-69]
-	_ = x[Next-70]
-	_ = x[Nextfile-71]
-	_ = x[Exit-72]
-	_ = x[ExitStatus-73]
-	_ = x[ForIn-74]
-	_ = x[BreakForIn-75]
-	_ = x[CallBuiltin-76]
-	_ = x[CallLengthArray-77]
-	_ = x[CallSplit-78]
-	_ = x[CallSplitSep-79]
-	_ = x[CallSprintf-80]
-	_ = x[CallUser-81]
-	_ = x[CallNative-82]
-	_ = x[Return-83]
-	_ = x[ReturnNull-84]
-	_ = x[Nulls-85]
-	_ = x[Print-86]
-	_ = x[Printf-87]
-	_ = x[Getline-88]
-	_ = x[GetlineField-89]
-	_ = x[GetlineGlobal-90]
-	_ = x[GetlineLocal-91]
-	_ = x[GetlineSpecial-92]
-	_ = x[GetlineArray-93]
-	_ = x[EndOpcode-94]
+	_ = x[RefGlobal-18]
+	_ = x[RefLocal-19]
+	_ = x[RefIndex-20]
+	_ = x[Deref-21]
+	_ = x[InRef-22]
+	_ = x[ArrayGlobalChecked-23]
+	_ = x[ArrayLocalChecked-24]
+	_ = x[AssignField-25]
+	_ = x[AssignFieldSub-26]
+	_ = x[AssignFieldByName-27]
+	_ = x[AssignGlobal-28]
+	_ = x[AssignLocal-29]
+	_ = x[AssignSpecial-30]
+	_ = x[AssignArrayGlobal-31]
+	_ = x[AssignArrayLocal-32]
+	_ = x[AssignRef-33]
+	_ = x[Delete-34]
+	_ = x[DeleteAll-35]
+	_ = x[DeleteRef-36]
+	_ = x[IncrField-37]
+	_ = x[IncrFieldByName-38]
+	_ = x[IncrGlobal-39]
+	_ = x[IncrLocal-40]
+	_ = x[IncrSpecial-41]
+	_ = x[IncrArrayGlobal-42]
+	_ = x[IncrArrayLocal-43]
+	_ = x[IncrRef-44]
+	_ = x[AugAssignField-45]
+	_ = x[AugAssignFieldByName-46]
+	_ = x[AugAssignGlobal-47]
+	_ = x[AugAssignLocal-48]
+	_ = x[AugAssignSpecial-49]
+	_ = x[AugAssignArrayGlobal-50]
+	_ = x[AugAssignArrayLocal-51]
+	_ = x[AugAssignRef-52]
+	_ = x[Regex-53]
+	_ = x[IndexMulti-54]
+	_ = x[ConcatMulti-55]
+	_ = x[Add-56]
+	_ = x[Subtract-57]
+	_ = x[Multiply-58]
+	_ = x[Divide-59]
+	_ = x[Power-60]
+	_ = x[Modulo-61]
+	_ = x[Equals-62]
+	_ = x[NotEquals-63]
+	_ = x[Less-64]
+	_ = x[Greater-65]
+	_ = x[LessOrEqual-66]
+	_ = x[GreaterOrEqual-67]
+	_ = x[Concat-68]
+	_ = x[Match-69]
+	_ = x[NotMatch-70]
+	_ = x[Not-71]
+	_ = x[UnaryMinus-72]
+	_ = x[UnaryPlus-73]
+	_ = x[Boolean-74]
+	_ = x[Switch-75]
+	_ = x[Jump-76]
+	_ = x[JumpFalse-77]
+	_ = x[JumpTrue-78]
+	_ = x[JumpEquals-79]
+	_ = x[JumpNotEquals-80]
+	_ = x[JumpLess-81]
+	_ = x[JumpGreater-82]
+	_ = x[JumpLessOrEqual-83]
+	_ = x[JumpGreaterOrEqual-84]
+	_ = x[Next-85]
+	_ = x[Nextfile-86]
+	_ = x[Exit-87]
+	_ = x[ExitStatus-88]
+	_ = x[ForIn-89]
+	_ = x[ForInRef-90]
+	_ = x[BreakForIn-91]
+	_ = x[CallBuiltin-92]
+	_ = x[CallLengthArray-93]
+	_ = x[CallLengthRef-94]
+	_ = x[CallTypeofRef-95]
+	_ = x[CallSplit-96]
+	_ = x[CallSplitSep-97]
+	_ = x[CallSplitRef-98]
+	_ = x[CallSplitSepRef-99]
+	_ = x[CallSprintf-100]
+	_ = x[CallPatsplit-101]
+	_ = x[CallAsort-102]
+	_ = x[CallAsorti-103]
+	_ = x[CallPrintrow-104]
+	_ = x[CallUser-105]
+	_ = x[CallNative-106]
+	_ = x[CallIndirect-107]
+	_ = x[Return-108]
+	_ = x[ReturnNull-109]
+	_ = x[Nulls-110]
+	_ = x[Print-111]
+	_ = x[Printf-112]
+	_ = x[Getline-113]
+	_ = x[GetlineField-114]
+	_ = x[GetlineGlobal-115]
+	_ = x[GetlineLocal-116]
+	_ = x[GetlineSpecial-117]
+	_ = x[GetlineArray-118]
+	_ = x[GetlineRef-119]
+	_ = x[BigNum-120]
+	_ = x[Big-121]
+	_ = x[EndOpcode-122]
 }
 
-const _Opcode_name = "NopNumStrDupeDropSwapRoteFieldFieldIntFieldByNameFieldByNameStrGlobalLocalSpecialArrayGlobalArrayLocalInGlobalInLocalAssignFieldAssignFieldSubAssignGlobalAssignLocalAssignSpecialAssignArrayGlobalAssignArrayLocalDeleteDeleteAllIncrFieldIncrGlobalIncrLocalIncrSpecialIncrArrayGlobalIncrArrayLocalAugAssignFieldAugAssignGlobalAugAssignLocalAugAssignSpecialAugAssignArrayGlobalAugAssignArrayLocalRegexIndexMultiConcatMultiAddSubtractMultiplyDividePowerModuloEqualsNotEqualsLessGreaterLessOrEqualGreaterOrEqualConcatMatchNotMatchNotUnaryMinusUnaryPlusBooleanJumpJumpFalseJumpTrueJumpEqualsJumpNotEqualsJumpLessJumpGreaterJumpLessOrEqualJumpGreaterOrEqualNextNextfileExitExitStatusForInBreakForInCallBuiltinCallLengthArrayCallSplitCallSplitSepCallSprintfCallUserCallNativeReturnReturnNullNullsPrintPrintfGetlineGetlineFieldGetlineGlobalGetlineLocalGetlineSpecialGetlineArrayEndOpcode"
+const _Opcode_name = "NopNumStrDupeDropSwapRoteFieldFieldIntFieldByNameFieldByNameStrGlobalLocalSpecialArrayGlobalArrayLocalInGlobalInLocalRefGlobalRefLocalRefIndexDerefInRefArrayGlobalCheckedArrayLocalCheckedAssignFieldAssignFieldSubAssignFieldByNameAssignGlobalAssignLocalAssignSpecialAssignArrayGlobalAssignArrayLocalAssignRefDeleteDeleteAllDeleteRefIncrFieldIncrFieldByNameIncrGlobalIncrLocalIncrSpecialIncrArrayGlobalIncrArrayLocalIncrRefAugAssignFieldAugAssignFieldByNameAugAssignGlobalAugAssignLocalAugAssignSpecialAugAssignArrayGlobalAugAssignArrayLocalAugAssignRefRegexIndexMultiConcatMultiAddSubtractMultiplyDividePowerModuloEqualsNotEqualsLessGreaterLessOrEqualGreaterOrEqualConcatMatchNotMatchNotUnaryMinusUnaryPlusBooleanSwitchJumpJumpFalseJumpTrueJumpEqualsJumpNotEqualsJumpLessJumpGreaterJumpLessOrEqualJumpGreaterOrEqualNextNextfileExitExitStatusForInForInRefBreakForInCallBuiltinCallLengthArrayCallLengthRefCallTypeofRefCallSplitCallSplitSepCallSplitRefCallSplitSepRefCallSprintfCallPatsplitCallAsortCallAsortiCallPrintrowCallUserCallNativeCallIndirectReturnReturnNullNullsPrintPrintfGetlineGetlineFieldGetlineGlobalGetlineLocalGetlineSpecialGetlineArrayGetlineRefBigNumBigEndOpcode"
 
-var _Opcode_index = [...]uint16{0, 3, 6, 9, 13, 17, 21, 25, 30, 38, 49, 63, 69, 74, 81, 92, 102, 110, 117, 128, 142, 154, 165, 178, 195, 211, 217, 226, 235, 245, 254, 265, 280, 294, 308, 323, 337, 353, 373, 392, 397, 407, 418, 421, 429, 437, 443, 448, 454, 460, 469, 473, 480, 491, 505, 511, 516, 524, 527, 537, 546, 553, 557, 566, 574, 584, 597, 605, 616, 631, 649, 653, 661, 665, 675, 680, 690, 701, 716, 725, 737, 748, 756, 766, 772, 782, 787, 792, 798, 805, 817, 830, 842, 856, 868, 877}
+var _Opcode_index = [...]uint16{0, 3, 6, 9, 13, 17, 21, 25, 30, 38, 49, 63, 69, 74, 81, 92, 102, 110, 117, 126, 134, 142, 147, 152, 170, 187, 198, 212, 229, 241, 252, 265, 282, 298, 307, 313, 322, 331, 340, 355, 365, 374, 385, 400, 414, 421, 435, 455, 470, 484, 500, 520, 539, 551, 556, 566, 577, 580, 588, 596, 602, 607, 613, 619, 628, 632, 639, 650, 664, 670, 675, 683, 686, 696, 705, 712, 718, 722, 731, 739, 749, 762, 770, 781, 796, 814, 818, 826, 830, 840, 845, 853, 863, 874, 889, 902, 915, 924, 936, 948, 963, 974, 986, 995, 1005, 1017, 1025, 1035, 1047, 1053, 1063, 1068, 1073, 1079, 1086, 1098, 1111, 1123, 1137, 1149, 1159, 1165, 1168, 1177}
 
 func (i Opcode) String() string {
 	idx := int(i) - 0
@@ -143,35 +171,51 @@ func _() {
 	// An "invalid array index" compiler error signifies that the constant values have changed.
 	// Re-run the stringer command to generate them again.
 	var x [1]struct{}
-	_ = x[BuiltinAtan2-0]
-	_ = x[BuiltinClose-1]
-	_ = x[BuiltinCos-2]
-	_ = x[BuiltinExp-3]
-	_ = x[BuiltinFflush-4]
-	_ = x[BuiltinFflushAll-5]
-	_ = x[BuiltinGsub-6]
-	_ = x[BuiltinIndex-7]
-	_ = x[BuiltinInt-8]
-	_ = x[BuiltinLength-9]
-	_ = x[BuiltinLengthArg-10]
-	_ = x[BuiltinLog-11]
-	_ = x[BuiltinMatch-12]
-	_ = x[BuiltinRand-13]
-	_ = x[BuiltinSin-14]
-	_ = x[BuiltinSqrt-15]
-	_ = x[BuiltinSrand-16]
-	_ = x[BuiltinSrandSeed-17]
-	_ = x[BuiltinSub-18]
-	_ = x[BuiltinSubstr-19]
-	_ = x[BuiltinSubstrLength-20]
-	_ = x[BuiltinSystem-21]
-	_ = x[BuiltinTolower-22]
-	_ = x[BuiltinToupper-23]
+	_ = x[BuiltinAnd-0]
+	_ = x[BuiltinAtan2-1]
+	_ = x[BuiltinClose-2]
+	_ = x[BuiltinCloseHow-3]
+	_ = x[BuiltinCompl-4]
+	_ = x[BuiltinCos-5]
+	_ = x[BuiltinExp-6]
+	_ = x[BuiltinFflush-7]
+	_ = x[BuiltinFflushAll-8]
+	_ = x[BuiltinGensub-9]
+	_ = x[BuiltinGsub-10]
+	_ = x[BuiltinIndex-11]
+	_ = x[BuiltinInt-12]
+	_ = x[BuiltinLength-13]
+	_ = x[BuiltinLengthArg-14]
+	_ = x[BuiltinLog-15]
+	_ = x[BuiltinLshift-16]
+	_ = x[BuiltinMatch-17]
+	_ = x[BuiltinMktime-18]
+	_ = x[BuiltinOr-19]
+	_ = x[BuiltinRand-20]
+	_ = x[BuiltinRshift-21]
+	_ = x[BuiltinSin-22]
+	_ = x[BuiltinSqrt-23]
+	_ = x[BuiltinSrand-24]
+	_ = x[BuiltinSrandSeed-25]
+	_ = x[BuiltinStrftime-26]
+	_ = x[BuiltinStrftimeFormat-27]
+	_ = x[BuiltinStrftimeTime-28]
+	_ = x[BuiltinStrptime-29]
+	_ = x[BuiltinSub-30]
+	_ = x[BuiltinSubstr-31]
+	_ = x[BuiltinSubstrLength-32]
+	_ = x[BuiltinSystem-33]
+	_ = x[BuiltinSystime-34]
+	_ = x[BuiltinTolower-35]
+	_ = x[BuiltinToupper-36]
+	_ = x[BuiltinTypeof-37]
+	_ = x[BuiltinTypeofUntyped-38]
+	_ = x[BuiltinXor-39]
 }
 
-const _BuiltinOp_name = "BuiltinAtan2BuiltinCloseBuiltinCosBuiltinExpBuiltinFflushBuiltinFflushAllBuiltinGsubBuiltinIndexBuiltinIntBuiltinLengthBuiltinLengthArgBuiltinLogBuiltinMatchBuiltinRandBuiltinSinBuiltinSqrtBuiltinSrandBuiltinSrandSeedBuiltinSubBuiltinSubstrBuiltinSubstrLengthBuiltinSystemBuiltinTolowerBuiltinToupper"
+const _BuiltinOp_name = "BuiltinAndBuiltinAtan2BuiltinCloseBuiltinCloseHowBuiltinComplBuiltinCosBuiltinExpBuiltinFflushBuiltinFflushAllBuiltinGensubBuiltinGsubBuiltinIndexBuiltinIntBuiltinLengthBuiltinLengthArgBuiltinLogBuiltinLshiftBuiltinMatchBuiltinMktimeBuiltinOrBuiltinRandBuiltinRshiftBuiltinSinBuiltinSqrtBuiltinSrandBuiltinSrandSeedBuiltinStrftimeBuiltinStrftimeFormatBuiltinStrftimeTimeBuiltinStrptimeBuiltinSubBuiltinSubstrBuiltinSubstrLengthBuiltinSystemBuiltinSystimeBuiltinTolowerBuiltinToupperBuiltinTypeofBuiltinTypeofUntypedBuiltinXor"
 
-var _BuiltinOp_index = [...]uint16{0, 12, 24, 34, 44, 57, 73, 84, 96, 106, 119, 135, 145, 157, 168, 178, 189, 201, 217, 227, 240, 259, 272, 286, 300}
+var _BuiltinOp_index = [...]uint16{0, 10, 22, 34, 49, 61, 71, 81, 94, 110, 123, 134, 146, 156, 169, 185, 195, 208, 220, 233, 242, 253, 266, 276, 287, 299, 315, 330, 351, 370, 385, 395, 408, 427, 440, 454, 468, 482, 495, 515, 525}
 
 func (i BuiltinOp) String() string {
 	idx := int(i) - 0
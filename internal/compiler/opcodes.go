@@ -37,34 +37,54 @@ const (
 	InGlobal       // arrayIndex
 	InLocal        // arrayIndex
 
+	// References to array items, used for subarrays like a[x][y] (a
+	// reference holds the array and the index of the item)
+	RefGlobal // arrayIndex
+	RefLocal  // arrayIndex
+	RefIndex
+	Deref
+	InRef
+
+	// Like ArrayGlobal and ArrayLocal, but check that the item isn't a
+	// subarray (used for arrays that may contain subarrays)
+	ArrayGlobalChecked // arrayIndex
+	ArrayLocalChecked  // arrayIndex
+
 	// Assign a field, variable, or array item
 	AssignField
 	AssignFieldSub
+	AssignFieldByName
 	AssignGlobal      // index
 	AssignLocal       // index
 	AssignSpecial     // index
 	AssignArrayGlobal // arrayIndex
 	AssignArrayLocal  // arrayIndex
+	AssignRef
 
 	// Delete statement
 	Delete    // arrayScope arrayIndex
 	DeleteAll // arrayScope arrayIndex
+	DeleteRef
 
 	// Post-increment and post-decrement
 	IncrField       // amount
+	IncrFieldByName // amount
 	IncrGlobal      // amount index
 	IncrLocal       // amount index
 	IncrSpecial     // amount index
 	IncrArrayGlobal // amount arrayIndex
 	IncrArrayLocal  // amount arrayIndex
+	IncrRef         // amount
 
 	// Augmented assignment (also used for pre-increment and pre-decrement)
 	AugAssignField       // augOp
+	AugAssignFieldByName // augOp
 	AugAssignGlobal      // augOp index
 	AugAssignLocal       // augOp index
 	AugAssignSpecial     // augOp index
 	AugAssignArrayGlobal // augOp arrayIndex
 	AugAssignArrayLocal  // augOp arrayIndex
+	AugAssignRef         // augOp
 
 	// Stand-alone regex expression /foo/
 	Regex // regexIndex
@@ -99,6 +119,7 @@ const (
 	Boolean
 
 	// Control flow
+	Switch             // switchIndex
 	Jump               // offset
 	JumpFalse          // offset
 	JumpTrue           // offset
@@ -112,19 +133,39 @@ const (
 	Nextfile
 	Exit
 	ExitStatus
-	ForIn // varScope varIndex arrayScope arrayIndex offset
+	ForIn    // varScope varIndex arrayScope arrayIndex offset
+	ForInRef // varScope varIndex offset
 	BreakForIn
 
 	// Builtin functions
 	CallBuiltin     // builtinOp
 	CallLengthArray // arrayScope arrayIndex
-	CallSplit       // arrayScope arrayIndex
-	CallSplitSep    // arrayScope arrayIndex sepIsRegex
+	CallLengthRef
+	CallTypeofRef
+	CallSplit    // arrayScope arrayIndex
+	CallSplitSep // arrayScope arrayIndex sepIsRegex
+	CallSplitRef
+	CallSplitSepRef // sepIsRegex
 	CallSprintf     // numArgs
 
-	// User and native functions
-	CallUser   // funcIndex numArrayArgs [arrayScope1 arrayIndex1 ...]
-	CallNative // funcIndex numArgs
+	// Pattern-based split (an arrayScope of 0 means the array argument is a
+	// subarray reference on the stack, in argument order)
+	CallPatsplit // numArrays arrayScope1 arrayIndex1 [arrayScope2 arrayIndex2]
+
+	// Sort functions (an arrayScope of 0 means the array argument is a
+	// subarray reference on the stack, below the "how" argument)
+	CallAsort  // numArrays arrayScope1 arrayIndex1 [arrayScope2 arrayIndex2]
+	CallAsorti // numArrays arrayScope1 arrayIndex1 [arrayScope2 arrayIndex2]
+
+	// Print array as a row (an arrayScope of 0 means the array argument is a
+	// subarray reference on the stack, in argument order)
+	CallPrintrow // numArrays arrayScope1 arrayIndex1 [arrayScope2 arrayIndex2] redirect
+
+	// User, native, and indirect functions (an arrayScope of 0 means the array argument
+	// is a subarray reference on the stack, below the scalar arguments)
+	CallUser     // funcIndex numArrayArgs [arrayScope1 arrayIndex1 ...]
+	CallNative   // funcIndex numArgs
+	CallIndirect // numArgs [argScope1 argIndex1 ...]
 	Return
 	ReturnNull
 	Nulls // numNulls
@@ -138,6 +179,12 @@ const (
 	GetlineLocal   // redirect index
 	GetlineSpecial // redirect index
 	GetlineArray   // redirect arrayScope arrayIndex
+	GetlineRef     // redirect
+
+	// Arbitrary-precision operations, only used in programs compiled for
+	// bignum mode (in place of Num and the fused arithmetic opcodes)
+	BigNum // numIndex
+	Big    // opcode (arithmetic, comparison, UnaryMinus, or UnaryPlus)
 
 	EndOpcode
 )
@@ -158,28 +205,44 @@ const (
 type BuiltinOp Opcode
 
 const (
-	BuiltinAtan2 BuiltinOp = iota
+	BuiltinAnd BuiltinOp = iota
+	BuiltinAtan2
 	BuiltinClose
+	BuiltinCloseHow
+	BuiltinCompl
 	BuiltinCos
 	BuiltinExp
 	BuiltinFflush
 	BuiltinFflushAll
+	BuiltinGensub
 	BuiltinGsub
 	BuiltinIndex
 	BuiltinInt
 	BuiltinLength
 	BuiltinLengthArg
 	BuiltinLog
+	BuiltinLshift
 	BuiltinMatch
+	BuiltinMktime
+	BuiltinOr
 	BuiltinRand
+	BuiltinRshift
 	BuiltinSin
 	BuiltinSqrt
 	BuiltinSrand
 	BuiltinSrandSeed
+	BuiltinStrftime
+	BuiltinStrftimeFormat
+	BuiltinStrftimeTime
+	BuiltinStrptime
 	BuiltinSub
 	BuiltinSubstr
 	BuiltinSubstrLength
 	BuiltinSystem
+	BuiltinSystime
 	BuiltinTolower
 	BuiltinToupper
+	BuiltinTypeof
+	BuiltinTypeofUntyped
+	BuiltinXor
 )
@@ -58,7 +58,9 @@ func New(mode Mode, append bool, fileReader *parseutil.FileReader) *Cover {
 // Annotate annotates the program with coverage tracking code.
 func (cover *Cover) Annotate(prog *ast.Program) {
 	prog.Begin = cover.annotateStmtsList(prog.Begin)
+	prog.BeginFile = cover.annotateStmtsList(prog.BeginFile)
 	prog.Actions = cover.annotateActions(prog.Actions)
+	prog.EndFile = cover.annotateStmtsList(prog.EndFile)
 	prog.End = cover.annotateStmtsList(prog.End)
 	prog.Functions = cover.annotateFunctions(prog.Functions)
 }
@@ -203,6 +205,10 @@ func (cover *Cover) annotateStmts(stmts ast.Stmts) ast.Stmts {
 			s.Body = cover.annotateStmts(s.Body)
 		case *ast.DoWhileStmt:
 			s.Body = cover.annotateStmts(s.Body)
+		case *ast.SwitchStmt:
+			for _, c := range s.Cases {
+				c.Body = cover.annotateStmts(c.Body)
+			}
 		case *ast.BlockStmt:
 			s.Body = cover.annotateStmts(s.Body)
 		default:
@@ -232,6 +238,8 @@ func endPos(stmt ast.Stmt) lexer.Position {
 		return s.BodyStart
 	case *ast.WhileStmt:
 		return s.BodyStart
+	case *ast.SwitchStmt:
+		return s.BodyStart
 	default:
 		return s.EndPos()
 	}
@@ -3,15 +3,27 @@ package parseutil
 
 import (
 	"bytes"
+	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"strings"
 )
 
-// FileReader serves two purposes:
+// FileReader serves three purposes:
 // 1. read input sources and join them into a single source (slice of bytes)
-// 2. track the lines counts of each input source
+// 2. read files named in @include directives (see Include)
+// 3. track the lines counts of each input source
 type FileReader struct {
-	files  []file
-	source bytes.Buffer
+	// IncludePath is the list of directories to search for a file named in
+	// an @include directive, similar to gawk's AWKPATH. If empty, only the
+	// current directory is searched. Names that contain a path separator
+	// are not searched for.
+	IncludePath []string
+
+	files    []file
+	source   bytes.Buffer
+	included map[string]bool // absolute paths of files already added
 }
 
 type file struct {
@@ -26,14 +38,89 @@ func (fr *FileReader) AddFile(path string, source io.Reader) error {
 	if err != nil {
 		return err
 	}
+	fr.markIncluded(path)
+	fr.endFile(path, curLen)
+	return nil
+}
+
+// Include finds and reads the file named in an @include directive, and adds
+// it to the source. It returns the file's source code and the line number
+// where it starts in the overall source, or nil source if the file has
+// already been added. It's intended for use as parser.ParserConfig.Include.
+func (fr *FileReader) Include(name string) ([]byte, int, error) {
+	path, found := fr.findInclude(name)
+	if !found {
+		return nil, 0, fmt.Errorf("@include file %q not found", name)
+	}
+	if fr.markIncluded(path) {
+		return nil, 0, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	line := 1
+	for _, f := range fr.files {
+		line += f.lines
+	}
+	curLen := fr.source.Len()
+	fr.source.Write(content)
+	fr.endFile(path, curLen)
+	return content, line, nil
+}
+
+// Finish adding the file at path, whose content starts at offset start in
+// the source.
+func (fr *FileReader) endFile(path string, start int) {
 	if !bytes.HasSuffix(fr.source.Bytes(), []byte("\n")) {
 		// Append newline to file in case it doesn't end with one
 		fr.source.WriteByte('\n')
 	}
-	content := fr.source.Bytes()[curLen:]
+	if bytes.Contains(fr.source.Bytes()[start:], []byte("@namespace")) {
+		// Each file starts in the default "awk" namespace, so reset it for
+		// the next file (this extra line is counted as part of this one).
+		fr.source.WriteString("@namespace \"awk\"\n")
+	}
+	content := fr.source.Bytes()[start:]
 	lines := bytes.Count(content, []byte("\n"))
 	fr.files = append(fr.files, file{path, lines})
-	return nil
+}
+
+// Record that path has been added, and return true if it already had been.
+func (fr *FileReader) markIncluded(path string) bool {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		absPath = path
+	}
+	if fr.included == nil {
+		fr.included = make(map[string]bool)
+	}
+	if fr.included[absPath] {
+		return true
+	}
+	fr.included[absPath] = true
+	return false
+}
+
+// Find the file named in an @include directive, trying the name as is and
+// with an ".awk" suffix in each directory of the include path.
+func (fr *FileReader) findInclude(name string) (string, bool) {
+	dirs := fr.IncludePath
+	if strings.ContainsRune(name, '/') || strings.ContainsRune(name, filepath.Separator) {
+		dirs = []string{""}
+	} else if len(dirs) == 0 {
+		dirs = []string{"."}
+	}
+	for _, dir := range dirs {
+		for _, candidate := range []string{name, name + ".awk"} {
+			path := filepath.Join(dir, candidate)
+			info, err := os.Stat(path)
+			if err == nil && !info.IsDir() {
+				return path, true
+			}
+		}
+	}
+	return "", false
 }
 
 // FileLine resolves an overall line number from the concatenated source code
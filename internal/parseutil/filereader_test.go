@@ -1,6 +1,8 @@
 package parseutil_test
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -137,3 +139,101 @@ print x
 		})
 	}
 }
+
+func TestFileReaderInclude(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, source string) {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(source), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("lib.awk", "function f(x) {\n  return x\n}")
+	writeFile("util.awk", "function g() {}\n")
+
+	fr := &parseutil.FileReader{IncludePath: []string{dir}}
+	err := fr.AddFile("main", strings.NewReader("BEGIN { print f(1) }\n@include \"lib\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		source string
+		line   int
+	}{
+		{"lib", "function f(x) {\n  return x\n}", 3},
+		{"lib.awk", "", 0},
+		{filepath.Join(dir, "util.awk"), "function g() {}\n", 6},
+	}
+	for _, test := range tests {
+		source, line, err := fr.Include(test.name)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if string(source) != test.source || line != test.line {
+			t.Errorf("%s: expected %q at line %d, got %q at line %d", test.name, test.source, test.line, source, line)
+		}
+	}
+
+	expected := `BEGIN { print f(1) }
+@include "lib"
+function f(x) {
+  return x
+}
+function g() {}
+`
+	if string(fr.Source()) != expected {
+		t.Fatalf("expected source:\n%s\ngot:\n%s", expected, fr.Source())
+	}
+
+	lineTests := []struct {
+		line     int
+		path     string
+		fileLine int
+	}{
+		{2, "main", 2},
+		{3, filepath.Join(dir, "lib.awk"), 1},
+		{5, filepath.Join(dir, "lib.awk"), 3},
+		{6, filepath.Join(dir, "util.awk"), 1},
+		{7, "", 0},
+	}
+	for _, test := range lineTests {
+		path, fileLine := fr.FileLine(test.line)
+		if path != test.path || fileLine != test.fileLine {
+			t.Errorf("line %d: expected %s:%d, got %s:%d", test.line, test.path, test.fileLine, path, fileLine)
+		}
+	}
+
+	_, _, err = fr.Include("missing")
+	if err == nil || err.Error() != `@include file "missing" not found` {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+}
+
+func TestFileReaderNamespace(t *testing.T) {
+	fr := &parseutil.FileReader{}
+	err := fr.AddFile("main", strings.NewReader(`@namespace "app"
+function g() {}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = fr.AddFile("other", strings.NewReader("BEGIN { g() }\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `@namespace "app"
+function g() {}
+@namespace "awk"
+BEGIN { g() }
+`
+	if string(fr.Source()) != expected {
+		t.Fatalf("expected source:\n%s\ngot:\n%s", expected, fr.Source())
+	}
+
+	path, fileLine := fr.FileLine(4)
+	if path != "other" || fileLine != 1 {
+		t.Errorf("line 4: expected other:1, got %s:%d", path, fileLine)
+	}
+}
@@ -52,8 +52,10 @@ func (r *ResolvedProgram) IterFuncs(f func(name string, info FuncInfo)) {
 
 // VarInfo holds resolved information about a variable.
 type VarInfo struct {
-	Type  Type
-	Index int
+	Type      Type
+	Index     int
+	Untyped   bool // true if never used as a scalar or array (Type is Scalar)
+	Subarrays bool // true if array may contain subarrays (Type is Array)
 }
 
 // FuncInfo holds resolved information about a function.
@@ -173,13 +175,20 @@ func Resolve(prog *ast.Program, config *Config) *ResolvedProgram {
 	}
 
 	// Create our type resolver.
-	r := resolver{varInfo: varInfo, funcInfo: funcInfo, funcs: callGraph.funcs}
+	r := resolver{
+		varInfo:      varInfo,
+		funcInfo:     funcInfo,
+		funcs:        callGraph.funcs,
+		subarrayArgs: make(map[funcParam]bool),
+	}
 	r.varInfo[""] = make(map[string]VarInfo) // func of "" stores global vars
 
 	// Interpreter relies on ARGV and other built-in arrays being present.
 	r.recordVar("", "ARGV", Array, lexer.Position{Line: 1, Column: 1})
 	r.recordVar("", "ENVIRON", Array, lexer.Position{Line: 1, Column: 1})
 	r.recordVar("", "FIELDS", Array, lexer.Position{Line: 1, Column: 1})
+	r.recordVar("", "OFIELDS", Array, lexer.Position{Line: 1, Column: 1})
+	r.recordVar("", "PROCINFO", Array, lexer.Position{Line: 1, Column: 1})
 
 	// Main resolver pass: determine types of variables and find function
 	// information. Can't call ast.Walk on prog directly, as it will not
@@ -211,6 +220,11 @@ func Resolve(prog *ast.Program, config *Config) *ResolvedProgram {
 	for i := 0; r.updates != updates; i++ {
 		updates = r.updates
 		main.walkOrdered(prog, orderedFuncs)
+		if r.updates == updates {
+			// Types are settled, but parameters passed a possible subarray
+			// may still be unknown; if so, make them arrays and go again.
+			r.recordSubarrayParams()
+		}
 		if i >= 100 {
 			panic(ast.PosErrorf(lexer.Position{Line: 1, Column: 1},
 				"too many iterations trying to resolve variable types"))
@@ -223,7 +237,7 @@ func Resolve(prog *ast.Program, config *Config) *ResolvedProgram {
 	for _, infos := range r.varInfo {
 		for varName, info := range infos {
 			if info.Type == unknown {
-				infos[varName] = VarInfo{Type: Scalar, Index: info.Index}
+				infos[varName] = VarInfo{Type: Scalar, Index: info.Index, Untyped: true}
 			}
 		}
 	}
@@ -246,12 +260,13 @@ func Resolve(prog *ast.Program, config *Config) *ResolvedProgram {
 		for _, name := range names {
 			info := infos[name]
 			if info.Type == Array {
-				infos[name] = VarInfo{Type: info.Type, Index: array}
+				info.Index = array
 				array++
 			} else {
-				infos[name] = VarInfo{Type: info.Type, Index: scalar}
+				info.Index = scalar
 				scalar++
 			}
+			infos[name] = info
 		}
 	}
 
@@ -298,6 +313,16 @@ type resolver struct {
 	funcInfo map[string]FuncInfo
 	funcs    map[string]*ast.Function
 	updates  int
+
+	// Parameters passed an item of an array that may contain subarrays
+	// while their type was unknown (see recordSubarrayParams)
+	subarrayArgs map[funcParam]bool
+}
+
+// funcParam identifies a parameter of a user-defined function.
+type funcParam struct {
+	funcName  string
+	paramName string
 }
 
 // Look up variable from function funcName and return its scope and type
@@ -339,11 +364,51 @@ func (r *resolver) recordVar(funcName, varName string, typ Type, pos lexer.Posit
 		panic(ast.PosErrorf(pos, "can't use %s %q as %s", info.Type, varName, typ))
 	}
 	if info.Type == unknown && typ != unknown {
-		r.varInfo[varFunc][varName] = VarInfo{Type: typ, Index: info.Index}
+		info.Type = typ
+		r.varInfo[varFunc][varName] = info
 		r.updates++
 	}
 }
 
+// Record that the given array variable (in function funcName) may contain
+// subarrays, because it's used with a subarray path like a[x][y], or one of
+// its items is used as an array argument (creating a subarray).
+func (r *resolver) recordSubarrays(funcName, varName string) {
+	_, info, varFunc, _ := r.lookupVar(funcName, varName)
+	if !info.Subarrays {
+		info.Subarrays = true
+		r.varInfo[varFunc][varName] = info
+		r.updates++
+	}
+}
+
+// Record that parameters whose type is still unknown, but which were passed
+// an item of an array that may contain subarrays, are arrays. For example,
+// in f(a["x"]) where a["x"] may be a subarray and function f(p) only uses p
+// in length(p), p is an array. This is done once the other types are
+// settled, so that any other use of the parameter takes precedence.
+func (r *resolver) recordSubarrayParams() {
+	for param := range r.subarrayArgs {
+		if r.varInfo[param.funcName][param.paramName].Type == unknown {
+			pos := r.funcs[param.funcName].Pos
+			r.recordVar(param.funcName, param.paramName, Array, pos)
+		}
+	}
+}
+
+// Return true if the given array argument may contain subarrays.
+func (r *resolver) argSubarrays(funcName string, arg ast.Expr) bool {
+	switch arg := arg.(type) {
+	case *ast.VarExpr:
+		_, info, _, _ := r.lookupVar(funcName, arg.Name)
+		return info.Subarrays
+	case *ast.IndexExpr:
+		return true
+	default:
+		return false
+	}
+}
+
 // callGraphVisitor records what functions are called by the current function
 // to build our call graph.
 type callGraphVisitor struct {
@@ -385,6 +450,36 @@ type mainVisitor struct {
 	curFunc     string
 }
 
+// Walk the subscripts of a subarray path (a[x][y]).
+func (v *mainVisitor) walkPath(path [][]ast.Expr) {
+	for _, index := range path {
+		ast.WalkExprList(v, index)
+	}
+}
+
+// Record that name is an array, which may contain subarrays if it's used
+// with a subarray path.
+func (v *mainVisitor) recordArray(name string, path [][]ast.Expr, pos lexer.Position) {
+	v.r.recordVar(v.curFunc, name, Array, pos)
+	if len(path) > 0 {
+		v.r.recordSubarrays(v.curFunc, name)
+	}
+}
+
+// Walk an array argument to a builtin function that may be a subarray
+// (a[x]), which creates the subarray if needed.
+func (v *mainVisitor) walkArrayArg(arg ast.Expr) {
+	switch arg := arg.(type) {
+	case *ast.VarExpr:
+		v.r.recordVar(v.curFunc, arg.Name, Array, arg.Pos)
+	case *ast.IndexExpr:
+		ast.Walk(v, arg)
+		v.r.recordSubarrays(v.curFunc, arg.Array)
+	default:
+		ast.Walk(v, arg)
+	}
+}
+
 // Walk prog's AST, with functions walked as ordered by orderedFuncs.
 func (v *mainVisitor) walkOrdered(prog *ast.Program, orderedFuncs []string) {
 	for _, funcName := range orderedFuncs {
@@ -405,9 +500,15 @@ func (v *mainVisitor) walkOrdered(prog *ast.Program, orderedFuncs []string) {
 	for _, stmts := range prog.Begin {
 		ast.WalkStmtList(v, stmts)
 	}
+	for _, stmts := range prog.BeginFile {
+		ast.WalkStmtList(v, stmts)
+	}
 	for _, action := range prog.Actions {
 		ast.Walk(v, action)
 	}
+	for _, stmts := range prog.EndFile {
+		ast.WalkStmtList(v, stmts)
+	}
 	for _, stmts := range prog.End {
 		ast.WalkStmtList(v, stmts)
 	}
@@ -420,34 +521,61 @@ func (v *mainVisitor) Visit(node ast.Node) ast.Visitor {
 
 	case *ast.ForInStmt:
 		v.r.recordVar(v.curFunc, n.Var, Scalar, n.VarPos)
-		v.r.recordVar(v.curFunc, n.Array, Array, n.ArrayPos)
+		v.recordArray(n.Array, n.Path, n.ArrayPos)
+		v.walkPath(n.Path)
 		ast.WalkStmtList(v, n.Body)
 
 	case *ast.IndexExpr:
+		v.walkPath(n.Path)
 		ast.WalkExprList(v, n.Index)
-		v.r.recordVar(v.curFunc, n.Array, Array, n.ArrayPos)
+		v.recordArray(n.Array, n.Path, n.ArrayPos)
 
 	case *ast.InExpr:
 		ast.WalkExprList(v, n.Index)
-		v.r.recordVar(v.curFunc, n.Array, Array, n.ArrayPos)
+		v.walkPath(n.Path)
+		v.recordArray(n.Array, n.Path, n.ArrayPos)
 
 	case *ast.DeleteStmt:
-		v.r.recordVar(v.curFunc, n.Array, Array, n.ArrayPos)
+		v.recordArray(n.Array, n.Path, n.ArrayPos)
+		v.walkPath(n.Path)
 		ast.WalkExprList(v, n.Index)
 
 	case *ast.CallExpr:
 		switch n.Func {
 		case lexer.F_SPLIT:
 			ast.Walk(v, n.Args[0])
-			varExpr := n.Args[1].(*ast.VarExpr) // split()'s 2nd arg is always an array
-			v.r.recordVar(v.curFunc, varExpr.Name, Array, varExpr.Pos)
+			v.walkArrayArg(n.Args[1]) // split()'s 2nd arg is always an array
 			ast.WalkExprList(v, n.Args[2:])
 
-		case lexer.F_LENGTH:
+		case lexer.F_PATSPLIT:
+			for i, arg := range n.Args {
+				if i == 1 || i == 3 { // field and seps args are arrays
+					v.walkArrayArg(arg)
+				} else {
+					ast.Walk(v, arg)
+				}
+			}
+
+		case lexer.F_ASORT, lexer.F_ASORTI, lexer.F_PRINTROW:
+			for i, arg := range n.Args {
+				if i < 2 { // source and dest (or fields) args are arrays
+					v.walkArrayArg(arg)
+				} else {
+					ast.Walk(v, arg)
+				}
+			}
+			if n.Func == lexer.F_ASORT && len(n.Args) > 1 && v.r.argSubarrays(v.curFunc, n.Args[0]) {
+				// asort() copies subarrays to the destination array
+				if varExpr, ok := n.Args[1].(*ast.VarExpr); ok {
+					v.r.recordSubarrays(v.curFunc, varExpr.Name)
+				}
+			}
+
+		case lexer.F_LENGTH, lexer.F_TYPEOF, lexer.F_ISARRAY:
 			if len(n.Args) > 0 {
 				if varExpr, ok := n.Args[0].(*ast.VarExpr); ok {
-					// In a call to length(x), x may be a scalar or an array,
-					// so set it to unknown for now.
+					// In a call to length(x), typeof(x), or isarray(x), x may
+					// be a scalar or an array, so set it to unknown for now.
 					v.r.recordVar(v.curFunc, varExpr.Name, unknown, varExpr.Pos)
 					return nil
 				}
@@ -486,10 +614,28 @@ func (v *mainVisitor) Visit(node ast.Node) ast.Visitor {
 			if !ok {
 				// Argument is not a variable, process normally.
 				if !funcInfo.Native {
-					paramInfo := v.r.varInfo[n.Name][funcInfo.Params[i]] // type info of corresponding parameter
-					if paramInfo.Type == Array {
+					paramName := funcInfo.Params[i]
+					paramInfo := v.r.varInfo[n.Name][paramName] // type info of corresponding parameter
+					indexExpr, isIndex := arg.(*ast.IndexExpr)
+					if paramInfo.Type == Array && !isIndex { // a[x] may be a subarray
 						panic(ast.PosErrorf(n.Pos, "can't pass scalar %s as array param", arg))
 					}
+					ast.Walk(v, arg)
+					if paramInfo.Type == unknown && isIndex {
+						_, arrayInfo, _, _ := v.r.lookupVar(v.curFunc, indexExpr.Array)
+						if arrayInfo.Subarrays {
+							// a[x] may be a subarray, so the parameter may be
+							// an array (decided once other types are settled)
+							v.r.subarrayArgs[funcParam{n.Name, paramName}] = true
+						}
+					}
+					if paramInfo.Type == Array {
+						// Passing a[x] as an array creates a subarray, and
+						// the parameter may contain subarrays of its own.
+						v.r.recordSubarrays(v.curFunc, indexExpr.Array)
+						v.r.recordSubarrays(n.Name, paramName)
+					}
+					continue
 				}
 				ast.Walk(v, arg)
 				continue
@@ -523,6 +669,50 @@ func (v *mainVisitor) Visit(node ast.Node) ast.Visitor {
 				// is not yet known.
 				v.r.recordVar(v.curFunc, varExpr.Name, unknown, varExpr.Pos)
 			}
+
+			// An array argument and its parameter are the same array, so
+			// if either may contain subarrays, both may.
+			_, varInfo, _, _ = v.r.lookupVar(v.curFunc, varExpr.Name)
+			paramInfo = v.r.varInfo[n.Name][paramName]
+			if varInfo.Subarrays || paramInfo.Subarrays {
+				v.r.recordSubarrays(v.curFunc, varExpr.Name)
+				v.r.recordSubarrays(n.Name, paramName)
+			}
+		}
+
+	case *ast.IndirectCallExpr:
+		ast.Walk(v, n.Func)
+		passesArrays := false
+		for _, arg := range n.Args {
+			switch arg := arg.(type) {
+			case *ast.VarExpr:
+				// The function isn't known until runtime, so a variable
+				// argument may be a scalar or an array, and if it's an array
+				// the function may add subarrays to it.
+				v.r.recordVar(v.curFunc, arg.Name, unknown, arg.Pos)
+				if _, info, _, _ := v.r.lookupVar(v.curFunc, arg.Name); info.Type == Array {
+					v.r.recordSubarrays(v.curFunc, arg.Name)
+				}
+				passesArrays = true
+			case *ast.IndexExpr:
+				// a[x] is passed by reference, and may become a subarray.
+				ast.Walk(v, arg)
+				v.r.recordSubarrays(v.curFunc, arg.Array)
+				passesArrays = true
+			default:
+				ast.Walk(v, arg)
+			}
+		}
+		if passesArrays {
+			// Any function's array parameters may be passed an array with
+			// subarrays by this call.
+			for funcName, info := range v.r.funcInfo {
+				for _, param := range info.Params {
+					if !info.Native && v.r.varInfo[funcName][param].Type == Array {
+						v.r.recordSubarrays(funcName, param)
+					}
+				}
+			}
 		}
 
 	default:
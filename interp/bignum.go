@@ -0,0 +1,454 @@
+// Arbitrary-precision numbers: exact integers too large for a float64, and
+// all arithmetic when Config.Bignum is set
+
+package interp
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/benhoyt/goawk/internal/compiler"
+)
+
+// An arbitrary-precision number: exactly one of i and f is non-nil.
+type bigNum struct {
+	i *big.Int   // Integer value (exact)
+	f *big.Float // Non-integer value (rounded to PREC bits)
+}
+
+// Default values of PREC and ROUNDMODE (as in gawk).
+const (
+	defaultPrecision = 53
+	defaultRoundMode = "N"
+)
+
+// Create a new number value from an arbitrary-precision number. Numbers
+// that are exact as a float64 are stored as ordinary number values. For
+// others, the s field holds the exact number (integers in decimal, and other
+// numbers in big.Float's 'p' format) so that values stay small, and n holds
+// the nearest float64, so that code which doesn't need the full precision
+// (for example boolean tests or substr positions) works unchanged.
+func bigValue(b *bigNum) value {
+	if b.i != nil {
+		if b.i.IsInt64() {
+			return intNum(b.i.Int64())
+		}
+		n, _ := new(big.Float).SetInt(b.i).Float64()
+		return value{typ: typeNum, s: b.i.String(), n: n}
+	}
+	n, accuracy := b.f.Float64()
+	if accuracy == big.Exact {
+		return num(n)
+	}
+	if n == 0 {
+		// Don't let a tiny number underflow to a false value
+		n = math.Copysign(math.SmallestNonzeroFloat64, float64(b.f.Sign()))
+	}
+	return value{typ: typeNum, s: b.f.Text('p', 0), n: n}
+}
+
+// Return the arbitrary-precision number stored in a number value by
+// bigValue, or nil if the value's float64 is exact.
+func (v value) big() *bigNum {
+	if v.typ != typeNum || v.s == "" {
+		return nil
+	}
+	if i, ok := new(big.Int).SetString(v.s, 10); ok {
+		return &bigNum{i: i}
+	}
+	// The 'p' format has at most 4 bits of mantissa per character.
+	f, _, err := new(big.Float).SetPrec(uint(4*len(v.s))).Parse(v.s, 0)
+	if err != nil {
+		return nil
+	}
+	return &bigNum{f: f}
+}
+
+// Return the string form of an arbitrary-precision number, using
+// floatFormat for non-integers. As with float64 values, a non-integer
+// number with an integral value only prints as an integer if it fits in
+// an int64, so 1e400 prints as "1e+400".
+func (b *bigNum) str(floatFormat string) string {
+	if b.i != nil {
+		return b.i.String()
+	}
+	if b.f.IsInf() {
+		if b.f.Sign() < 0 {
+			return "-inf"
+		}
+		return "inf"
+	}
+	if i, accuracy := b.f.Int64(); accuracy == big.Exact {
+		return strconv.FormatInt(i, 10)
+	}
+	return fmt.Sprintf(floatFormat, b.f)
+}
+
+// Return a new big.Float with the current PREC and ROUNDMODE settings.
+func (p *interp) newFloat() *big.Float {
+	return new(big.Float).SetPrec(p.precision).SetMode(p.roundMode)
+}
+
+// Convert value to an arbitrary-precision number. Return false if the value
+// is NaN or infinity, which are handled using float64 arithmetic instead.
+func (p *interp) toBig(v value) (*bigNum, bool) {
+	switch v.typ {
+	case typeNum:
+		if b := v.big(); b != nil {
+			return b, true
+		}
+		return p.floatToBig(v.n)
+	case typeStr, typeNumStr:
+		return p.parseBig(v.s)
+	default: // typeNull
+		return &bigNum{i: new(big.Int)}, true
+	}
+}
+
+// Convert a float64 to an arbitrary-precision number.
+func (p *interp) floatToBig(f float64) (*bigNum, bool) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, false
+	}
+	if f == math.Trunc(f) {
+		i, _ := new(big.Float).SetFloat64(f).Int(nil)
+		return &bigNum{i: i}, true
+	}
+	return &bigNum{f: p.newFloat().SetFloat64(f)}, true
+}
+
+// Convert a number literal to an arbitrary-precision number. The literal
+// is only available as a float64, but its shortest decimal form is the
+// same as the source (the parser keeps the source text of literals with
+// more than 15 significant digits), so 0.1 is parsed as one tenth at PREC
+// bits, not as the float64 nearest to it.
+func (p *interp) bigLiteral(f float64) value {
+	b, ok := p.parseBig(strconv.FormatFloat(f, 'g', -1, 64))
+	if !ok {
+		return num(f)
+	}
+	return bigValue(b)
+}
+
+// Parse the number at the start of s (like parseFloatPrefix) as an
+// arbitrary-precision number. Integers are parsed exactly.
+func (p *interp) parseBig(s string) (*bigNum, bool) {
+	i := 0
+	for i < len(s) && asciiSpace[s[i]] != 0 {
+		i++
+	}
+	start := i
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		i++
+	}
+	if i+2 < len(s) && hasHexPrefix(s[i:]) ||
+		i+3 <= len(s) && (hasNaNPrefix(s[i:]) || hasInfPrefix(s[i:])) {
+		// Rare cases that the float64 parser handles
+		return p.floatToBig(parseFloatPrefix(s))
+	}
+
+	gotDigit := false
+	for i < len(s) && isDigit(s[i]) {
+		gotDigit = true
+		i++
+	}
+	intEnd := i
+	if i < len(s) && s[i] == '.' {
+		i++
+	}
+	for i < len(s) && isDigit(s[i]) {
+		gotDigit = true
+		i++
+	}
+	if !gotDigit {
+		return &bigNum{i: new(big.Int)}, true
+	}
+	end := i
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		if i < len(s) && (s[i] == '+' || s[i] == '-') {
+			i++
+		}
+		for i < len(s) && isDigit(s[i]) {
+			i++
+			end = i
+		}
+	}
+
+	numStr := strings.TrimPrefix(s[start:end], "+")
+	if end == intEnd {
+		n, _ := new(big.Int).SetString(numStr, 10)
+		return &bigNum{i: n}, true
+	}
+	f, _, err := p.newFloat().Parse(numStr, 10)
+	if err != nil {
+		return p.floatToBig(parseFloatPrefix(s))
+	}
+	return &bigNum{f: f}, true
+}
+
+// Return b as a big.Float, rounding integers to PREC bits.
+func (p *interp) bigFloat(b *bigNum) *big.Float {
+	if b.f != nil {
+		return b.f
+	}
+	return p.newFloat().SetInt(b.i)
+}
+
+// Perform an arithmetic operation with arbitrary precision. Operations on
+// integers give an exact integer result, except for division that has a
+// remainder and negative powers. Caller must ensure the divisor of a
+// division or modulo is nonzero.
+func (p *interp) bigArith(op compiler.AugOp, l, r value) value {
+	lb, lok := p.toBig(l)
+	rb, rok := p.toBig(r)
+	if !lok || !rok {
+		// NaN or infinity: fall back to float64 arithmetic
+		switch op {
+		case compiler.AugOpAdd:
+			return addValues(l, r)
+		case compiler.AugOpSub:
+			return subtractValues(l, r)
+		case compiler.AugOpMul:
+			return multiplyValues(l, r)
+		case compiler.AugOpDiv:
+			return divideValues(l, r)
+		case compiler.AugOpPow:
+			return powerValues(l, r)
+		default: // AugOpMod
+			return moduloValues(l, r)
+		}
+	}
+
+	if lb.i != nil && rb.i != nil {
+		x, y := lb.i, rb.i
+		switch op {
+		case compiler.AugOpAdd:
+			return bigValue(&bigNum{i: new(big.Int).Add(x, y)})
+		case compiler.AugOpSub:
+			return bigValue(&bigNum{i: new(big.Int).Sub(x, y)})
+		case compiler.AugOpMul:
+			return bigValue(&bigNum{i: new(big.Int).Mul(x, y)})
+		case compiler.AugOpDiv:
+			q, m := new(big.Int).QuoRem(x, y, new(big.Int))
+			if m.Sign() == 0 {
+				return bigValue(&bigNum{i: q})
+			}
+		case compiler.AugOpPow:
+			if y.Sign() >= 0 {
+				return bigValue(&bigNum{i: new(big.Int).Exp(x, y, nil)})
+			}
+		default: // AugOpMod
+			return bigValue(&bigNum{i: new(big.Int).Rem(x, y)})
+		}
+	}
+
+	x, y := p.bigFloat(lb), p.bigFloat(rb)
+	z := p.newFloat()
+	switch op {
+	case compiler.AugOpAdd:
+		z.Add(x, y)
+	case compiler.AugOpSub:
+		z.Sub(x, y)
+	case compiler.AugOpMul:
+		z.Mul(x, y)
+	case compiler.AugOpDiv:
+		z.Quo(x, y)
+	case compiler.AugOpPow:
+		n, accuracy := y.Int64()
+		if accuracy != big.Exact {
+			// Non-integer powers are calculated using float64
+			f := math.Pow(l.num(), r.num())
+			b, ok := p.floatToBig(f)
+			if !ok {
+				return num(f)
+			}
+			return bigValue(b)
+		}
+		p.bigPow(z, x, n)
+	default: // AugOpMod
+		// Result is x - trunc(x/y)*y, which has the same sign as x. The
+		// quotient is calculated with extra precision so it's exact
+		// enough to truncate.
+		q := new(big.Float).SetPrec(2*p.precision+64).Quo(x, y)
+		qi, _ := q.Int(nil)
+		q.SetInt(qi).Mul(q, y)
+		z.Sub(x, q)
+	}
+	return bigValue(&bigNum{f: z})
+}
+
+// Set z to x**n using exponentiation by squaring.
+func (p *interp) bigPow(z, x *big.Float, n int64) {
+	negative := n < 0
+	if negative {
+		n = -n
+	}
+	base := new(big.Float).SetPrec(z.Prec()).SetMode(z.Mode()).Set(x)
+	z.SetInt64(1)
+	for n > 0 {
+		if n&1 != 0 {
+			z.Mul(z, base)
+		}
+		base.Mul(base, base)
+		n >>= 1
+	}
+	if negative {
+		z.Quo(p.newFloat().SetInt64(1), z)
+	}
+}
+
+// Negate the given value with arbitrary precision.
+func (p *interp) bigNegate(v value) value {
+	b, ok := p.toBig(v)
+	if !ok {
+		return negateValue(v)
+	}
+	if b.i != nil {
+		return bigValue(&bigNum{i: new(big.Int).Neg(b.i)})
+	}
+	return bigValue(&bigNum{f: p.newFloat().Neg(b.f)})
+}
+
+// Convert the given value to an arbitrary-precision number value.
+func (p *interp) bigToNum(v value) value {
+	b, ok := p.toBig(v)
+	if !ok {
+		return v.toNum()
+	}
+	return bigValue(b)
+}
+
+// Compare two numbers with arbitrary precision, returning -1, 0, or 1.
+func (p *interp) bigCompare(l, r value) int {
+	lb, lok := p.toBig(l)
+	rb, rok := p.toBig(r)
+	if !lok || !rok {
+		return compareNums(l.num(), r.num())
+	}
+	if lb.i != nil && rb.i != nil {
+		return lb.i.Cmp(rb.i)
+	}
+	return exactFloat(lb).Cmp(exactFloat(rb))
+}
+
+// Return b as a big.Float without rounding integers.
+func exactFloat(b *bigNum) *big.Float {
+	if b.f != nil {
+		return b.f
+	}
+	return new(big.Float).SetInt(b.i)
+}
+
+// Return the value truncated to an integer, for int().
+func (p *interp) bigInt(v value) value {
+	b, ok := p.toBig(v)
+	if !ok {
+		return num(float64(int64(v.num())))
+	}
+	if b.i != nil {
+		return bigValue(b)
+	}
+	i, _ := b.f.Int(nil)
+	return bigValue(&bigNum{i: i})
+}
+
+// Return the square root of the value with arbitrary precision.
+func (p *interp) bigSqrt(v value) value {
+	b, ok := p.toBig(v)
+	if !ok || b.i != nil && b.i.Sign() < 0 || b.f != nil && b.f.Sign() < 0 {
+		return num(math.Sqrt(v.num()))
+	}
+	z := p.newFloat().Sqrt(p.bigFloat(b))
+	if z.IsInt() {
+		i, _ := z.Int(nil)
+		return bigValue(&bigNum{i: i})
+	}
+	return bigValue(&bigNum{f: z})
+}
+
+// Return the value converted for a printf integer verb like %d.
+func (p *interp) bigFormatInt(v value) any {
+	b, ok := p.toBig(v)
+	if !ok {
+		return v.formatInt()
+	}
+	if b.i != nil {
+		return b.i
+	}
+	i, _ := b.f.Int(nil)
+	return i
+}
+
+// Return the value converted for a printf floating point verb like %f.
+func (p *interp) bigFormatFloat(v value) any {
+	b, ok := p.toBig(v)
+	if !ok {
+		return v.num()
+	}
+	return exactFloat(b)
+}
+
+// Set PREC, which is the number of bits of precision, or one of gawk's
+// names for IEEE 754 formats like "double" or "quad".
+func (p *interp) setPrecision(s string) error {
+	var precision uint
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "half":
+		precision = 11
+	case "single":
+		precision = 24
+	case "double":
+		precision = 53
+	case "quad":
+		precision = 113
+	case "oct":
+		precision = 237
+	default:
+		n, err := strconv.ParseUint(strings.TrimSpace(s), 10, 32)
+		if err != nil || n < 1 || n > big.MaxPrec {
+			return newError("invalid PREC %q", s)
+		}
+		precision = uint(n)
+	}
+	p.precision = precision
+	p.setBigLiterals()
+	return nil
+}
+
+// Set ROUNDMODE, which is one of gawk's rounding modes: "N" (to nearest,
+// ties to even), "A" (to nearest, ties away from zero), "Z" (toward zero),
+// "U" (toward +infinity), or "D" (toward -infinity).
+func (p *interp) setRoundMode(s string) error {
+	mode, ok := roundModes[strings.ToUpper(s)]
+	if !ok {
+		return newError("invalid ROUNDMODE %q", s)
+	}
+	p.roundModeStr = strings.ToUpper(s)
+	p.roundMode = mode
+	p.setBigLiterals()
+	return nil
+}
+
+var roundModes = map[string]big.RoundingMode{
+	"N": big.ToNearestEven,
+	"A": big.ToNearestAway,
+	"Z": big.ToZero,
+	"U": big.ToPositiveInf,
+	"D": big.ToNegativeInf,
+}
+
+// Convert the program's number literals to arbitrary-precision numbers
+// using the current PREC and ROUNDMODE (in bignum mode only).
+func (p *interp) setBigLiterals() {
+	if !p.bignum {
+		return
+	}
+	p.bigNums = make([]value, len(p.nums))
+	for i, n := range p.nums {
+		p.bigNums[i] = p.bigLiteral(n)
+	}
+}
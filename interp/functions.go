@@ -6,13 +6,14 @@ import (
 	"bufio"
 	"errors"
 	"fmt"
+	"math"
 	"reflect"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
 	"unicode/utf8"
 
-	"github.com/benhoyt/goawk/internal/resolver"
 	"github.com/benhoyt/goawk/lexer"
 )
 
@@ -135,6 +136,7 @@ func fromNative(v reflect.Value) value {
 
 // Used for caching native function type information on init
 type nativeFunc struct {
+	name       string
 	isVariadic bool
 	in         []reflect.Type
 	value      reflect.Value
@@ -165,6 +167,7 @@ func (p *interp) initNativeFuncs(funcs map[string]any) error {
 			in[j] = typ.In(j)
 		}
 		p.nativeFuncs[i] = nativeFunc{
+			name:       name,
 			isVariadic: typ.IsVariadic(),
 			in:         in,
 			value:      reflect.ValueOf(f),
@@ -242,7 +245,7 @@ func validNativeType(typ reflect.Type) bool {
 }
 
 // Guts of the split() function
-func (p *interp) split(s string, scope resolver.Scope, index int, sep string, sepIsRegex bool, mode IOMode) (int, error) {
+func (p *interp) split(s string, array map[string]value, sep string, sepIsRegex bool, mode IOMode) (int, error) {
 	var parts []string
 	switch {
 	case mode == CSVMode || mode == TSVMode:
@@ -251,6 +254,7 @@ func (p *interp) split(s string, scope resolver.Scope, index int, sep string, se
 			separator: p.csvInputConfig.Separator,
 			sepLen:    utf8.RuneLen(p.csvInputConfig.Separator),
 			comment:   p.csvInputConfig.Comment,
+			quote:     p.csvInputConfig.QuoteChar,
 			fields:    &parts,
 		}
 		scanner := bufio.NewScanner(strings.NewReader(s))
@@ -276,12 +280,281 @@ func (p *interp) split(s string, scope resolver.Scope, index int, sep string, se
 		}
 		parts = re.Split(s, -1)
 	}
-	array := make(map[string]value, len(parts))
+	// Clear the array in place, as it may be a subarray referenced elsewhere.
+	p.clearArray(array)
 	for i, part := range parts {
 		array[strconv.Itoa(i+1)] = numStr(part)
 	}
-	p.arrays[p.arrayIndex(scope, index)] = array
-	return len(array), nil
+	return len(parts), nil
+}
+
+// Guts of the patsplit() function: store the text matching fieldPat in array,
+// and the text in between (if seps is non-nil) in seps. Return the number of
+// fields found.
+func (p *interp) patsplit(s string, array map[string]value, fieldPat string, seps map[string]value) (int, error) {
+	re, err := p.compileRegex(fieldPat)
+	if err != nil {
+		return 0, err
+	}
+	indices := splitOnFieldPat(re, s)
+	p.clearArray(array)
+	for i, match := range indices {
+		array[strconv.Itoa(i+1)] = numStr(s[match[0]:match[1]])
+	}
+	if seps == nil {
+		return len(indices), nil
+	}
+	p.clearArray(seps)
+	// seps[i] is the separator after field i; seps[0] is the leading
+	// separator (if any), and seps[n] the trailing one (if any).
+	prevIndex := 0
+	for i, match := range indices {
+		if i > 0 || match[0] > 0 {
+			seps[strconv.Itoa(i)] = numStr(s[prevIndex:match[0]])
+		}
+		prevIndex = match[1]
+	}
+	if prevIndex < len(s) {
+		seps[strconv.Itoa(len(indices))] = numStr(s[prevIndex:])
+	}
+	return len(indices), nil
+}
+
+// Return the start and end indices of the fields in s, where each field is
+// a match of the FPAT regex re. Like gawk, an empty match is a field too,
+// unless it directly follows a non-empty one.
+func splitOnFieldPat(re *regexp.Regexp, s string) [][]int {
+	return re.FindAllStringIndex(s, -1)
+}
+
+// Guts of the asort() and asorti() functions. Sort the values (or indexes if
+// indexes is true) of src according to how, and store them in dest with
+// indexes 1 to n. Return n, the number of elements sorted.
+func (p *interp) asort(src, dest map[string]value, how string, indexes bool) (int, error) {
+	if how == "" {
+		how = "@val_type_asc"
+		if indexes {
+			how = "@ind_str_asc"
+		}
+	}
+	keys, err := p.sortedIndexes(src, how)
+	if err != nil {
+		return 0, err
+	}
+	values := make([]value, len(keys))
+	for i, k := range keys {
+		if indexes {
+			values[i] = str(k)
+		} else {
+			values[i] = src[k]
+			if values[i].typ == typeArray {
+				values[i] = p.newSubarray(p.copyArray(p.subarray(values[i])))
+			}
+		}
+	}
+	// Clear the array in place, as it may be a subarray referenced elsewhere.
+	p.clearArray(dest)
+	for i, v := range values {
+		dest[strconv.Itoa(i+1)] = v
+	}
+	return len(values), nil
+}
+
+// Return the indexes of array sorted according to how, which is either one
+// of gawk's predefined orderings like "@ind_str_asc" or "@val_num_desc", or
+// the name of a user-defined comparison function.
+func (p *interp) sortedIndexes(array map[string]value, how string) ([]string, error) {
+	keys := make([]string, 0, len(array))
+	for k := range array {
+		keys = append(keys, k)
+	}
+	if how == "@unsorted" {
+		return keys, nil
+	}
+
+	if !strings.HasPrefix(how, "@") {
+		return p.sortUser(array, keys, how)
+	}
+	var order string
+	var desc bool
+	switch {
+	case strings.HasSuffix(how, "_asc"):
+		order = strings.TrimSuffix(how, "_asc")
+	case strings.HasSuffix(how, "_desc"):
+		order = strings.TrimSuffix(how, "_desc")
+		desc = true
+	}
+	var compare func(k1, k2 string) int
+	switch order {
+	case "@ind_str":
+		compare = strings.Compare
+	case "@ind_num":
+		compare = func(k1, k2 string) int {
+			return compareNumValues(str(k1), str(k2))
+		}
+	case "@val_type":
+		compare = func(k1, k2 string) int {
+			return p.compareValues(array[k1], array[k2])
+		}
+	case "@val_str":
+		compare = func(k1, k2 string) int {
+			v1, v2 := array[k1], array[k2]
+			if v1.typ == typeArray || v2.typ == typeArray {
+				return p.compareArrays(v1, v2)
+			}
+			return strings.Compare(p.toString(v1), p.toString(v2))
+		}
+	case "@val_num":
+		compare = func(k1, k2 string) int {
+			v1, v2 := array[k1], array[k2]
+			if v1.typ == typeArray || v2.typ == typeArray {
+				return p.compareArrays(v1, v2)
+			}
+			return compareNumValues(v1, v2)
+		}
+	default:
+		return nil, newError("invalid sort order %q", how)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		c := compare(keys[i], keys[j])
+		if c == 0 {
+			// Break ties using the index to make the order deterministic
+			c = strings.Compare(keys[i], keys[j])
+		}
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	})
+	return keys, nil
+}
+
+// Sort keys using the user-defined comparison function with the given name,
+// called as f(i1, v1, i2, v2) for each pair of elements. It should return a
+// number less than zero if i1 sorts before i2, zero if they're equal, or
+// greater than zero if i1 sorts after i2. It's an error if the function takes
+// value parameters and an element is a subarray.
+func (p *interp) sortUser(array map[string]value, keys []string, name string) ([]string, error) {
+	funcIndex := -1
+	for i, f := range p.compiled.Functions {
+		if f.Name == name {
+			funcIndex = i
+			break
+		}
+	}
+	if funcIndex < 0 {
+		return nil, newError("sort function %q not defined", name)
+	}
+	f := p.compiled.Functions[funcIndex]
+	numArgs := len(f.Params)
+	if numArgs > 4 {
+		numArgs = 4
+	}
+	for i := 0; i < numArgs; i++ {
+		if f.Arrays[i] {
+			return nil, newError("sort function %q parameter %q must be a scalar", name, f.Params[i])
+		}
+	}
+	if numArgs > 1 {
+		// The values are passed as scalars, so they can't be subarrays
+		for _, k := range keys {
+			if array[k].typ == typeArray {
+				return nil, newError("can't pass subarray %q to sort function %q", k, name)
+			}
+		}
+	}
+
+	var err error
+	sort.SliceStable(keys, func(i, j int) bool {
+		if err != nil {
+			return false
+		}
+		k1, k2 := keys[i], keys[j]
+		args := []value{str(k1), array[k1], str(k2), array[k2]}
+		var r value
+		r, err = p.callUser(funcIndex, args[:numArgs], nil)
+		return r.num() < 0
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Compare two values for the "@val_type" sort order: numbers sort before
+// strings, and strings sort before subarrays.
+func (p *interp) compareValues(v1, v2 value) int {
+	if v1.typ == typeArray || v2.typ == typeArray {
+		return p.compareArrays(v1, v2)
+	}
+	_, isStr1 := v1.isTrueStr()
+	_, isStr2 := v2.isTrueStr()
+	switch {
+	case isStr1 && isStr2:
+		return strings.Compare(p.toString(v1), p.toString(v2))
+	case isStr1:
+		return 1
+	case isStr2:
+		return -1
+	default:
+		return compareNumValues(v1, v2)
+	}
+}
+
+// Compare two values where at least one is a subarray: subarrays sort after
+// scalars, and two subarrays sort by size.
+func (p *interp) compareArrays(v1, v2 value) int {
+	switch {
+	case v1.typ != typeArray:
+		return -1
+	case v2.typ != typeArray:
+		return 1
+	default:
+		return len(p.subarray(v1)) - len(p.subarray(v2))
+	}
+}
+
+// Compare the numeric values of v1 and v2, returning -1, 0, or 1. Integers
+// too large to be exact as a float64 are compared exactly if they fit in an
+// int64.
+func compareNumValues(v1, v2 value) int {
+	n1, n2 := v1.num(), v2.num()
+	if math.Abs(n1) >= maxExactFloat || math.Abs(n2) >= maxExactFloat {
+		if i1, i2, ok := exactInts(v1, v2); ok {
+			switch {
+			case i1 < i2:
+				return -1
+			case i1 > i2:
+				return 1
+			default:
+				return 0
+			}
+		}
+	}
+	return compareNums(n1, n2)
+}
+
+func compareNums(n1, n2 float64) int {
+	switch {
+	case n1 < n2:
+		return -1
+	case n1 > n2:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Return a deep copy of array (including any subarrays).
+func (p *interp) copyArray(array map[string]value) map[string]value {
+	c := make(map[string]value, len(array))
+	for k, v := range array {
+		if v.typ == typeArray {
+			v = p.newSubarray(p.copyArray(p.subarray(v)))
+		}
+		c[k] = v
+	}
+	return c
 }
 
 // Guts of the sub() and gsub() functions
@@ -326,6 +599,127 @@ func (p *interp) sub(regex, repl, in string, global bool) (out string, num int,
 	return out, count, nil
 }
 
+// Guts of the gensub() function. If how is a string starting with "g" or
+// "G", replace all matches of regex in the input, otherwise replace only the
+// Nth match, where N is how converted to a number. Unlike sub(), the
+// replacement can refer to parenthesized subexpressions with "\\1" through
+// "\\9" ("\\0" is the same as "&", the entire matched text). The input
+// string is not modified; the result is returned.
+func (p *interp) gensub(regex, repl string, how value, in string) (string, error) {
+	re, err := p.compileRegex(regex)
+	if err != nil {
+		return "", err
+	}
+	global := false
+	n := 1
+	if s := p.toString(how); len(s) > 0 && (s[0] == 'g' || s[0] == 'G') {
+		global = true
+	} else if how.num() >= 1 {
+		n = int(how.num())
+	}
+
+	var b strings.Builder
+	last := 0
+	for i, m := range re.FindAllStringSubmatchIndex(in, -1) {
+		if !global && i+1 != n {
+			continue
+		}
+		b.WriteString(in[last:m[0]])
+		for j := 0; j < len(repl); j++ {
+			switch repl[j] {
+			case '&':
+				b.WriteString(in[m[0]:m[1]])
+			case '\\':
+				j++
+				if j >= len(repl) {
+					b.WriteByte('\\')
+					break
+				}
+				switch c := repl[j]; {
+				case c >= '0' && c <= '9':
+					group := int(c - '0')
+					if 2*group < len(m) && m[2*group] >= 0 {
+						b.WriteString(in[m[2*group]:m[2*group+1]])
+					}
+				case c == '&' || c == '\\':
+					b.WriteByte(c)
+				default:
+					b.WriteByte('\\')
+					b.WriteByte(c)
+				}
+			default:
+				b.WriteByte(repl[j])
+			}
+		}
+		last = m[1]
+		if !global {
+			break
+		}
+	}
+	b.WriteString(in[last:])
+	return b.String(), nil
+}
+
+// Convert the arguments of a bitwise function like and() to unsigned
+// integers. As in gawk, negative values aren't allowed.
+func bitwiseArgs(name string, args ...value) ([]uint64, error) {
+	ints := make([]uint64, len(args))
+	for i, arg := range args {
+		n := arg.toNum()
+		if exact, ok := n.exactInt(); ok && exact >= 0 {
+			ints[i] = uint64(exact)
+			continue
+		}
+		switch {
+		case n.n < 0:
+			return nil, newError("%s: negative values not allowed", name)
+		case !(n.n < 1<<64): // also catches NaN
+			return nil, newError("%s: values of 2^64 or more not allowed", name)
+		}
+		ints[i] = uint64(n.n)
+	}
+	return ints, nil
+}
+
+// Return the result of a bitwise function as an AWK number, exactly if it
+// fits in an int64.
+func bitwiseResult(n uint64) value {
+	if n <= math.MaxInt64 {
+		return intNum(int64(n))
+	}
+	return num(float64(n))
+}
+
+// Guts of the compl() function. Like gawk, values that fit in 53 bits are
+// complemented within 53 bits, so that the result is still exactly
+// representable as a float64.
+func compl(n uint64) uint64 {
+	if n < maxExactFloat {
+		return ^n & (maxExactFloat - 1)
+	}
+	return ^n & math.MaxInt64
+}
+
+// Guts of the lshift() function. If the result would overflow, it's
+// calculated (inexactly) using floating point.
+func lshift(n, shift uint64) value {
+	if shift < 64 && n <= math.MaxInt64>>shift {
+		return intNum(int64(n << shift))
+	}
+	if shift > 2048 {
+		shift = 2048 // avoid int overflow; result is +Inf anyway
+	}
+	return num(math.Ldexp(float64(n), int(shift)))
+}
+
+// Guts of the rshift() function.
+func rshift(n, shift uint64) value {
+	if shift >= 64 {
+		return intNum(0)
+	}
+	return bitwiseResult(n >> shift)
+}
+
 type cachedFormat struct {
 	format string
 	types  []byte
@@ -415,11 +809,25 @@ func (p *interp) sprintf(format string, args []value) (string, error) {
 		case 's':
 			v = p.toString(a)
 		case 'd':
-			v = int64(a.num())
+			if p.bignum {
+				v = p.bigFormatInt(a)
+			} else {
+				v = a.formatInt()
+			}
 		case 'f':
-			v = a.num()
+			if p.bignum {
+				v = p.bigFormatFloat(a)
+			} else {
+				v = a.num()
+			}
 		case 'u':
-			v = uint64(int64(a.num()))
+			if p.bignum {
+				v = p.bigFormatInt(a)
+			} else if i, ok := a.formatInt().(int64); ok {
+				v = uint64(i)
+			} else {
+				v = a.formatInt()
+			}
 		case 'c':
 			var c []byte
 			n, isStr := a.isTrueStr()
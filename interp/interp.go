@@ -18,12 +18,14 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"math/big"
 	"math/rand"
 	"os"
 	"regexp"
 	"runtime"
 	"strconv"
 	"strings"
+	"time"
 	"unicode/utf8"
 
 	"github.com/benhoyt/goawk/internal/ast"
@@ -39,6 +41,7 @@ var (
 	errNextfile = errors.New("nextfile")
 
 	errCSVSeparator = errors.New("invalid CSV field separator or comment delimiter")
+	errCSVQuote     = errors.New("invalid CSV quote or escape character")
 
 	varRegex = regexp.MustCompile(`^([_a-zA-Z][_a-zA-Z0-9]*)=(.*)`)
 
@@ -80,7 +83,10 @@ type interp struct {
 	inputBuffer   []byte
 	inputStreams  map[string]inputStream
 	outputStreams map[string]outputStream
+	coprocesses   map[string]*coprocStream
 	noExec        bool
+	allowNetwork  bool
+	nonFatalIO    bool
 	noFileWrites  bool
 	noFileReads   bool
 	shellCommand  []string
@@ -95,14 +101,19 @@ type interp struct {
 	sp            int
 	frame         []value
 	arrays        []map[string]value
+	subarrays     []map[string]value // indexed by a typeArray value's n
+	freeSubarrays []int              // indexes of deleted subarrays, for reuse
 	localArrays   [][]int
 	callDepth     int
 	nativeFuncs   []nativeFunc
 	scalarIndexes map[string]int
 	arrayIndexes  map[string]int
+	procInfo      map[string]value
 
 	// File, line, and field handling
 	filename        value
+	errno           value
+	csvError        string
 	line            string
 	lineIsTrueStr   bool
 	lineNum         value
@@ -114,6 +125,8 @@ type interp struct {
 	fieldNames      []string
 	fieldIndexes    map[string]int
 	reparseCSV      bool
+	jsonlNames      []string // field names and values of the last JSON Lines
+	jsonlFields     []string // record scanned (which may not be $0)
 
 	// Built-in variables
 	argc             value
@@ -121,6 +134,11 @@ type interp struct {
 	outputFormat     string
 	fieldSep         string
 	fieldSepRegex    *regexp.Regexp
+	fieldPat         string
+	fieldPatRegex    *regexp.Regexp // non-nil if FPAT is used for splitting
+	fieldWidthsStr   string
+	fieldWidths      []fieldWidth
+	useFieldWidths   bool // true if FIELDWIDTHS is used for splitting
 	recordSep        string
 	recordSepRegex   *regexp.Regexp
 	recordTerminator string
@@ -131,18 +149,28 @@ type interp struct {
 	matchStart       value
 	inputMode        IOMode
 	csvInputConfig   CSVInputConfig
+	jsonFlatten      string
 	outputMode       IOMode
 	csvOutputConfig  CSVOutputConfig
+	jsonOutputConfig JSONOutputConfig
+	precision        uint
+	roundModeStr     string
+	roundMode        big.RoundingMode
 
 	savedFieldSep      string
 	savedFieldSepRegex *regexp.Regexp
+	savedFieldPatRegex *regexp.Regexp
+	savedFieldWidths   []fieldWidth
 
 	// Parsed program, compiled functions and constants
-	program   *parser.Program
-	functions []compiler.Function
-	nums      []float64
-	strs      []string
-	regexes   []*regexp.Regexp
+	program     *parser.Program
+	compiled    *compiler.Program // program.Compiled, or bigCompiled in bignum mode
+	bigCompiled *compiler.Program // program compiled for bignum mode (on first use)
+	functions   []compiler.Function
+	nums        []float64
+	bigNums     []value // nums converted to arbitrary precision (in bignum mode)
+	strs        []string
+	regexes     []*regexp.Regexp
 
 	// Context support (for Interpreter.ExecuteContext)
 	checkCtx bool
@@ -157,8 +185,12 @@ type interp struct {
 	regexCache        map[string]*regexp.Regexp
 	formatCache       map[string]cachedFormat
 	csvJoinFieldsBuf  bytes.Buffer
+	jsonOutputBuf     bytes.Buffer
+	printedRowHeader  map[io.Writer]bool // streams printrow() has written a header to
 	chars             bool
+	bignum            bool
 	newlineOutputCRLF bool
+	now               func() time.Time
 }
 
 // Various const configuration. Could make these part of Config if
@@ -174,6 +206,9 @@ const (
 	inputBufSize     = 64 * 1024
 )
 
+// Default value of FPAT (as in gawk): fields are runs of non-space characters.
+const defaultFieldPat = "[^[:space:]]+"
+
 // NewlineMode specifies how newline characters are handled when reading or
 // writing text.
 type NewlineMode int
@@ -250,7 +285,8 @@ type Config struct {
 	// Set one or more of these to true to prevent unsafe behaviours,
 	// useful when executing untrusted scripts:
 	//
-	// * NoExec prevents system calls via system() or pipe operator
+	// * NoExec prevents system calls via system(), pipe operator, or
+	//   "|&" coprocesses
 	// * NoFileWrites prevents writing to files via '>' or '>>'
 	// * NoFileReads prevents reading from files via getline or the
 	//   filenames in Args
@@ -258,6 +294,14 @@ type Config struct {
 	NoFileWrites bool
 	NoFileReads  bool
 
+	// Set to true to allow opening network connections via the
+	// "/inet/tcp/..." and "/inet/udp/..." special files. Networking is
+	// off by default so that embedding programs don't open sockets
+	// unless they opt in (the goawk command sets this). Because a
+	// network connection both reads and writes, it's also disallowed if
+	// NoFileReads or NoFileWrites is set.
+	AllowNetwork bool
+
 	// Exec args used to run system shell. Typically, this will
 	// be {"/bin/sh", "-c"}
 	ShellCommand []string
@@ -277,8 +321,20 @@ type Config struct {
 	// "encoding/csv" package, but FieldsPerRecord is not supported,
 	// LazyQuotes is always on, and TrimLeadingSpace is always off.
 	//
+	// If set to FixedMode, FS is ignored and fields are split by position,
+	// using the widths in FieldWidths.
+	//
+	// If set to JSONLMode, FS and RS are ignored, and each input line must be
+	// a JSON object (JSON Lines format). Fields are the object's values, and
+	// field names are its keys, so values are available via @"name" and the
+	// FIELDS array. $0 is the raw line. See JSONFlatten for how nested values
+	// are handled.
+	//
 	// You can also enable CSV or TSV input mode by setting INPUTMODE to "csv"
 	// or "tsv" in Vars or in the BEGIN block (those override this setting).
+	// Similarly, setting INPUTMODE to "fixed widths=5,2:3,*" enables fixed
+	// input mode with the given widths, and setting it to "jsonl" enables
+	// JSON Lines input mode.
 	//
 	// For further documentation about GoAWK's CSV support, see the full docs
 	// in "../docs/csv.md".
@@ -294,15 +350,40 @@ type Config struct {
 	//     BEGIN { INPUTMODE="csv separator=| comment=# header" }
 	CSVInput CSVInputConfig
 
+	// Field widths if InputMode is FixedMode, in the same format as gawk's
+	// FIELDWIDTHS variable: a space-separated list of widths, each optionally
+	// preceded by "skip:" to skip that many characters before the field. The
+	// last width may be "*" to include the rest of the record. Widths are in
+	// bytes, or in Unicode chars if Chars is true.
+	FieldWidths string
+
+	// Flattening scheme for nested objects and arrays if InputMode is
+	// JSONLMode. The default (empty string) is to flatten nested values into
+	// separate fields, joining keys with "." to form the field name, for
+	// example "user.id". Array elements are keyed by 1-based index, for
+	// example "tags.1". Set to "none" to disable flattening, in which case
+	// nested values are set to their compact JSON text. Any other value is
+	// used as the key separator, for example "_" gives "user_id".
+	//
+	// You can also set this via INPUTMODE, for example "jsonl flatten=_".
+	JSONFlatten string
+
 	// Mode for print output: default is to use normal OFS and ORS
 	// behaviour. If set to CSVMode or TSVMode, the "print" statement with one
 	// or more arguments outputs fields using CSV or TSV formatting,
 	// respectively. Output is written as per RFC 4180 and the "encoding/csv"
 	// package.
 	//
-	// You can also enable CSV or TSV output mode by setting OUTPUTMODE to
-	// "csv" or "tsv" in Vars or in the BEGIN block (those override this
-	// setting).
+	// If set to JSONLMode, "print" with one or more arguments outputs each
+	// record as a JSON array on a single line, or as a JSON object if
+	// JSONOutput.FieldNames is set. JSONMode is the same, but each record is
+	// indented over multiple lines. Strings are escaped as needed, and
+	// numbers and numeric strings are output as JSON numbers. Uninitialized
+	// values, NaN, and infinity are output as null.
+	//
+	// You can also enable CSV, TSV, or JSON output mode by setting OUTPUTMODE
+	// to "csv", "tsv", "json", or "jsonl" in Vars or in the BEGIN block
+	// (those override this setting).
 	OutputMode IOMode
 
 	// Additional options if OutputMode is CSVMode or TSVMode. The zero value
@@ -314,10 +395,49 @@ type Config struct {
 	//     BEGIN { OUTPUTMODE="csv separator=|" }
 	CSVOutput CSVOutputConfig
 
+	// Additional options if OutputMode is JSONMode or JSONLMode.
+	//
+	// You can also specify these options by setting OUTPUTMODE in the BEGIN
+	// block, for example, to output objects with keys "id" and "name":
+	//
+	//     BEGIN { OUTPUTMODE="jsonl fields=id,name" }
+	JSONOutput JSONOutputConfig
+
 	// Set to true to count using Unicode chars instead of bytes for
 	// index(), length(), match(), substr(), and printf %c.
 	Chars bool
 
+	// Set to true to use arbitrary-precision arithmetic, like gawk's -M
+	// option. Integers are exact however large they get, and other numbers
+	// are floating point with PREC bits of precision (53 by default, the
+	// same as float64) rounded according to ROUNDMODE ("N" by default,
+	// meaning round to nearest even). Both can be set in Vars or in the
+	// program. Functions like sin() and log() are still calculated using
+	// float64.
+	Bignum bool
+
+	// Set to true to make I/O errors non-fatal: if an output redirect or
+	// coprocess can't be opened, a getline file can't be read, close()
+	// fails, or a file in Args can't be opened, ERRNO is set to a message
+	// describing the error and the program continues (getline returns -1).
+	// Scripts can also do this by setting PROCINFO["NONFATAL"] for all I/O,
+	// or PROCINFO[name, "NONFATAL"] for a single file or command.
+	NonFatalIO bool
+
+	// Initial value of PROCINFO["sorted_in"], which controls the order of
+	// "for (k in a)" loops. This is one of gawk's predefined orderings, for
+	// example "@ind_str_asc" or "@val_num_desc", or the name of a
+	// user-defined comparison function. The default is to iterate in an
+	// unspecified order. Scripts can also set PROCINFO["sorted_in"] directly.
+	SortedIn string
+
+	// Now is called to get the current time for systime(), strftime()
+	// without a timestamp, and srand() without a seed. The default is
+	// [time.Now]; set this to a function returning a fixed time to make
+	// scripts that use the current time deterministic (for example, in
+	// tests).
+	Now func() time.Time
+
 	// NewlineOutput specifies how newline characters are handled when writing
 	// output. The default is "smart", meaning no translation on Linux/Unix
 	// and CRLF translation on Windows.
@@ -351,6 +471,18 @@ const (
 
 	// TSVMode uses tab-separated value mode for input or output.
 	TSVMode IOMode = 2
+
+	// FixedMode uses fixed-width fields for input (it's not valid for
+	// output). See Config.FieldWidths for details.
+	FixedMode IOMode = 3
+
+	// JSONLMode uses JSON Lines (one JSON value per line) for input or
+	// output. See Config.JSONFlatten and Config.JSONOutput for details.
+	JSONLMode IOMode = 4
+
+	// JSONMode uses indented JSON for output (it's not valid for input).
+	// See Config.JSONOutput for details.
+	JSONMode IOMode = 5
 )
 
 // CSVInputConfig holds additional configuration for when InputMode is CSVMode
@@ -364,18 +496,115 @@ type CSVInputConfig struct {
 	// leading whitespace) should be ignored as comments.
 	Comment rune
 
+	// Character used to quote fields. If this is zero, it defaults to '"'.
+	QuoteChar rune
+
 	// If true, parse the first row in each input file as a header row (that
 	// is, a list of field names), and enable the @"field" syntax to get a
 	// field by name as well as the FIELDS special array.
 	Header bool
+
+	// If non-nil, use these as the field names, enabling @"field" and the
+	// FIELDS array for input without a header row. Like the names from a
+	// header row, they're set when the first row of each file is read. If
+	// Header is also true, the header row is skipped and these names are
+	// used instead.
+	FieldNames []string
+
+	// If true, parse input strictly instead of leniently. A quote in a
+	// non-quoted field, a quoted field that isn't terminated or is followed
+	// by something other than a separator, and a row with a different
+	// number of fields from the first row (including the header row) are
+	// errors, reported with the file name and line number. See OnError for
+	// how errors are handled.
+	Strict bool
+
+	// If positive, each row must have exactly this many fields, otherwise
+	// it's an error (even if Strict is false). If negative, the number of
+	// fields isn't checked, even if Strict is true.
+	FieldsPerRecord int
+
+	// Specifies what to do when a row has an error (see Strict and
+	// FieldsPerRecord). An error in the header row always aborts.
+	OnError CSVErrorMode
+}
+
+// Report whether c is the zero value (no CSV input options specified).
+func (c CSVInputConfig) isZero() bool {
+	return c.Separator == 0 && c.Comment == 0 && c.QuoteChar == 0 && !c.Header && c.FieldNames == nil &&
+		!c.Strict && c.FieldsPerRecord == 0 && c.OnError == CSVErrorAbort
 }
 
+// CSVErrorMode specifies how errors in CSV or TSV input rows are handled.
+type CSVErrorMode int
+
+const (
+	// CSVErrorAbort stops the program with an error (the default).
+	CSVErrorAbort CSVErrorMode = 0
+
+	// CSVErrorSkip skips the row, printing the error to Config.Error.
+	CSVErrorSkip CSVErrorMode = 1
+
+	// CSVErrorReport passes the row to the program as parsed leniently,
+	// and sets the CSVERROR special variable to the error message.
+	// CSVERROR is set to "" for rows without errors.
+	CSVErrorReport CSVErrorMode = 2
+)
+
 // CSVOutputConfig holds additional configuration for when OutputMode is
 // CSVMode or TSVMode.
 type CSVOutputConfig struct {
 	// Output field separator character. If this is zero, it defaults to ','
 	// when OutputMode is CSVMode and '\t' when OutputMode is TSVMode.
 	Separator rune
+
+	// If true, printrow() outputs a header row of field names before the
+	// first row it writes to each output stream.
+	Header bool
+
+	// Specifies which fields are quoted. The default is CSVQuoteMinimal.
+	Quote CSVQuoteMode
+
+	// Character used to quote fields. If this is zero, it defaults to '"'.
+	QuoteChar rune
+
+	// If nonzero, a quote character inside a quoted field is escaped by
+	// preceding it with this character, rather than by doubling it. The
+	// escape character itself is escaped the same way. With CSVQuoteNever,
+	// it's used to escape separators and quote characters instead.
+	Escape rune
+}
+
+// CSVQuoteMode specifies which fields are quoted in CSV or TSV output.
+type CSVQuoteMode int
+
+const (
+	// CSVQuoteMinimal quotes only fields that need it: those containing the
+	// separator, the quote or escape character, a carriage return or
+	// newline, or starting with a space.
+	CSVQuoteMinimal CSVQuoteMode = 0
+
+	// CSVQuoteAlways quotes every field.
+	CSVQuoteAlways CSVQuoteMode = 1
+
+	// CSVQuoteNonNumeric quotes every field that isn't a decimal number
+	// (as well as numbers that need quoting, per CSVQuoteMinimal).
+	CSVQuoteNonNumeric CSVQuoteMode = 2
+
+	// CSVQuoteNever never quotes fields. It's up to the caller to ensure
+	// fields don't contain separators or newlines, or to set an Escape
+	// character.
+	CSVQuoteNever CSVQuoteMode = 3
+)
+
+// JSONOutputConfig holds additional configuration for when OutputMode is
+// JSONMode or JSONLMode.
+type JSONOutputConfig struct {
+	// Keys to use when printing each record as a JSON object: the first
+	// print argument is output with the first key, and so on. Arguments
+	// beyond the last key are keyed by their 1-based position. If nil,
+	// each record is printed as a JSON array.
+	FieldNames []string
 }
 
 // ExecProgram executes the parsed program using the given interpreter
@@ -396,13 +625,8 @@ func ExecProgram(program *parser.Program, config *Config) (int, error) {
 }
 
 func newInterp(program *parser.Program) *interp {
-	p := &interp{
-		program:   program,
-		functions: program.Compiled.Functions,
-		nums:      program.Compiled.Nums,
-		strs:      program.Compiled.Strs,
-		regexes:   program.Compiled.Regexes,
-	}
+	p := &interp{program: program}
+	p.useCompiled(program.Compiled)
 
 	// Allocate memory for variables and virtual machine stack
 	p.scalarIndexes = make(map[string]int)
@@ -420,6 +644,7 @@ func newInterp(program *parser.Program) *interp {
 	for i := 0; i < len(p.arrayIndexes); i++ {
 		p.arrays[i] = make(map[string]value)
 	}
+	p.procInfo = p.arrays[p.arrayIndexes["PROCINFO"]]
 
 	// Initialize defaults
 	p.regexCache = make(map[string]*regexp.Regexp, 10)
@@ -431,10 +656,14 @@ func newInterp(program *parser.Program) *interp {
 	p.outputFormat = "%.6g"
 	p.fieldSep = " "
 	p.savedFieldSep = " "
+	p.fieldPat = defaultFieldPat
 	p.recordSep = "\n"
 	p.outputFieldSep = " "
 	p.outputRecordSep = "\n"
 	p.subscriptSep = "\x1c"
+	p.precision = defaultPrecision
+	p.roundModeStr = defaultRoundMode
+	p.roundMode = big.ToNearestEven
 	p.lineNum = num(0)
 	p.fileLineNum = num(0)
 	p.numFields = num(0)
@@ -444,11 +673,21 @@ func newInterp(program *parser.Program) *interp {
 
 	p.inputStreams = make(map[string]inputStream)
 	p.outputStreams = make(map[string]outputStream)
+	p.coprocesses = make(map[string]*coprocStream)
 	p.scanners = make(map[string]*bufio.Scanner)
 
 	return p
 }
 
+// Set the compiled code and constants to execute.
+func (p *interp) useCompiled(compiled *compiler.Program) {
+	p.compiled = compiled
+	p.functions = compiled.Functions
+	p.nums = compiled.Nums
+	p.strs = compiled.Strs
+	p.regexes = compiled.Regexes
+}
+
 func (p *interp) setExecuteConfig(config *Config) error {
 	if config == nil {
 		config = &Config{}
@@ -472,11 +711,38 @@ func (p *interp) setExecuteConfig(config *Config) error {
 		if p.csvInputConfig.Separator == 0 {
 			p.csvInputConfig.Separator = '\t'
 		}
+	case FixedMode:
+		if !p.csvInputConfig.isZero() {
+			return newError("CSV input configuration not valid in fixed input mode")
+		}
+		if config.FieldWidths == "" {
+			return newError("fixed input mode requires field widths")
+		}
+		err := p.setFieldWidths(config.FieldWidths)
+		if err != nil {
+			return err
+		}
+	case JSONMode:
+		return newError("JSON mode not valid for input")
+	case JSONLMode:
+		if !p.csvInputConfig.isZero() {
+			return newError("CSV input configuration not valid in JSON Lines input mode")
+		}
+		p.jsonFlatten = config.JSONFlatten
+		if p.jsonFlatten == "" {
+			p.jsonFlatten = "."
+		}
 	case DefaultMode:
-		if p.csvInputConfig != (CSVInputConfig{}) {
+		if !p.csvInputConfig.isZero() {
 			return newError("input mode configuration not valid in default input mode")
 		}
 	}
+	if config.FieldWidths != "" && p.inputMode != FixedMode {
+		return newError("field widths only valid in fixed input mode")
+	}
+	if config.JSONFlatten != "" && p.inputMode != JSONLMode {
+		return newError("JSON flattening only valid in JSON Lines input mode")
+	}
 	p.outputMode = config.OutputMode
 	p.csvOutputConfig = config.CSVOutput
 	switch p.outputMode {
@@ -488,11 +754,22 @@ func (p *interp) setExecuteConfig(config *Config) error {
 		if p.csvOutputConfig.Separator == 0 {
 			p.csvOutputConfig.Separator = '\t'
 		}
+	case FixedMode:
+		return newError("fixed mode not valid for output")
+	case JSONMode, JSONLMode:
+		if p.csvOutputConfig != (CSVOutputConfig{}) {
+			return newError("CSV output configuration not valid in JSON output mode")
+		}
 	case DefaultMode:
 		if p.csvOutputConfig != (CSVOutputConfig{}) {
 			return newError("output mode configuration not valid in default output mode")
 		}
 	}
+	p.jsonOutputConfig = config.JSONOutput
+	p.printedRowHeader = nil
+	if config.JSONOutput.FieldNames != nil && p.outputMode != JSONMode && p.outputMode != JSONLMode {
+		return newError("JSON output configuration only valid in JSON output mode")
+	}
 	if config.OpenFile == nil {
 		p.openFile = os.OpenFile
 	} else {
@@ -507,6 +784,20 @@ func (p *interp) setExecuteConfig(config *Config) error {
 		p.setArrayValue(resolver.Global, argvIndex, strconv.Itoa(i+1), numStr(arg))
 	}
 	p.noArgVars = config.NoArgVars
+	p.bignum = config.Bignum
+	if p.bignum {
+		if p.bigCompiled == nil {
+			compiled, err := compiler.CompileBignum(&p.program.ResolvedProgram)
+			if err != nil {
+				return err
+			}
+			p.bigCompiled = compiled
+		}
+		p.useCompiled(p.bigCompiled)
+	} else {
+		p.useCompiled(p.program.Compiled)
+	}
+	p.setBigLiterals()
 	p.filenameIndex = 1
 	p.hadFiles = false
 	for i := 0; i < len(config.Vars); i += 2 {
@@ -542,6 +833,15 @@ func (p *interp) setExecuteConfig(config *Config) error {
 		}
 	}
 
+	if config.SortedIn != "" {
+		p.procInfo["sorted_in"] = str(config.SortedIn)
+	}
+	p.procInfo["strftime"] = str(defaultTimeFormat)
+	p.now = config.Now
+	if p.now == nil {
+		p.now = time.Now
+	}
+
 	// Set up system shell command
 	if len(config.ShellCommand) != 0 {
 		p.shellCommand = config.ShellCommand
@@ -551,6 +851,8 @@ func (p *interp) setExecuteConfig(config *Config) error {
 
 	// Set up I/O structures
 	p.noExec = config.NoExec
+	p.allowNetwork = config.AllowNetwork
+	p.nonFatalIO = config.NonFatalIO
 	p.noFileWrites = config.NoFileWrites
 	p.noFileReads = config.NoFileReads
 	p.stdin = config.Stdin
@@ -596,6 +898,13 @@ func validateCSVInputConfig(mode IOMode, config CSVInputConfig) error {
 		config.Comment != 0 && !validCSVSeparator(config.Comment) {
 		return errCSVSeparator
 	}
+	if config.OnError < CSVErrorAbort || config.OnError > CSVErrorReport {
+		return newError("invalid CSV error mode %d", config.OnError)
+	}
+	if config.QuoteChar != 0 && (config.QuoteChar == config.Separator ||
+		config.QuoteChar == config.Comment || !validCSVQuote(config.QuoteChar)) {
+		return errCSVQuote
+	}
 	return nil
 }
 
@@ -606,6 +915,13 @@ func validateCSVOutputConfig(mode IOMode, config CSVOutputConfig) error {
 	if !validCSVSeparator(config.Separator) {
 		return errCSVSeparator
 	}
+	if config.Quote < CSVQuoteMinimal || config.Quote > CSVQuoteNever {
+		return newError("invalid CSV quote mode %d", config.Quote)
+	}
+	if config.QuoteChar != 0 && (config.QuoteChar == config.Separator || !validCSVQuote(config.QuoteChar)) ||
+		config.Escape != 0 && (config.Escape == config.Separator || !validCSVQuote(config.Escape)) {
+		return errCSVQuote
+	}
 	return nil
 }
 
@@ -613,11 +929,15 @@ func validCSVSeparator(r rune) bool {
 	return r != 0 && r != '"' && r != '\r' && r != '\n' && utf8.ValidRune(r) && r != utf8.RuneError
 }
 
+func validCSVQuote(r rune) bool {
+	return r != '\r' && r != '\n' && utf8.ValidRune(r) && r != utf8.RuneError
+}
+
 func (p *interp) executeAll() (int, error) {
 	defer p.closeAll()
 
 	// Execute the program: BEGIN, then pattern/actions, then END
-	err := p.execute(p.program.Compiled.Begin)
+	err := p.execute(p.compiled.Begin)
 	if err != nil && err != errExit {
 		if p.checkCtx {
 			ctxErr := p.checkContextNow()
@@ -627,11 +947,12 @@ func (p *interp) executeAll() (int, error) {
 		}
 		return 0, err
 	}
-	if len(p.program.Compiled.Actions) == 0 && len(p.program.Compiled.End) == 0 {
+	compiled := p.compiled
+	if len(compiled.Actions) == 0 && len(compiled.End) == 0 && len(compiled.BeginFile) == 0 && len(compiled.EndFile) == 0 {
 		return p.exitStatus, nil // only BEGIN specified, don't process input
 	}
 	if err != errExit {
-		err = p.execActions(p.program.Compiled.Actions)
+		err = p.execActions(p.compiled.Actions)
 		if err != nil && err != errExit {
 			if p.checkCtx {
 				ctxErr := p.checkContextNow()
@@ -642,7 +963,7 @@ func (p *interp) executeAll() (int, error) {
 			return 0, err
 		}
 	}
-	err = p.execute(p.program.Compiled.End)
+	err = p.execute(p.compiled.End)
 	if err != nil && err != errExit {
 		if p.checkCtx {
 			ctxErr := p.checkContextNow()
@@ -689,6 +1010,15 @@ lineLoop:
 		}
 		p.setLine(line, false)
 		p.reparseCSV = false
+		if p.inputMode == JSONLMode {
+			// Use the fields parsed by jsonlSplitter. Most JSON Lines files
+			// have the same keys on every line, so avoid resetting FIELDS
+			// unless they've changed.
+			p.fields = p.jsonlFields
+			if !stringsEqual(p.jsonlNames, p.fieldNames) {
+				p.setFieldNames(p.jsonlNames)
+			}
+		}
 
 		// Execute all the pattern-action blocks for each line
 		for i, action := range actions {
@@ -748,6 +1078,10 @@ lineLoop:
 			case err == errNextfile:
 				// Tell nextLine to move on to next file
 				p.scanner = nil
+				err := p.endFile()
+				if err != nil {
+					return err
+				}
 				continue lineLoop
 			case err != nil:
 				return err
@@ -775,8 +1109,16 @@ func (p *interp) getSpecial(index int) value {
 		return p.argc
 	case ast.V_CONVFMT:
 		return str(p.convertFormat)
+	case ast.V_CSVERROR:
+		return str(p.csvError)
+	case ast.V_ERRNO:
+		return p.errno
+	case ast.V_FIELDWIDTHS:
+		return str(p.fieldWidthsStr)
 	case ast.V_FILENAME:
 		return p.filename
+	case ast.V_FPAT:
+		return str(p.fieldPat)
 	case ast.V_FS:
 		return str(p.fieldSep)
 	case ast.V_OFMT:
@@ -785,6 +1127,10 @@ func (p *interp) getSpecial(index int) value {
 		return str(p.outputFieldSep)
 	case ast.V_ORS:
 		return str(p.outputRecordSep)
+	case ast.V_PREC:
+		return num(float64(p.precision))
+	case ast.V_ROUNDMODE:
+		return str(p.roundModeStr)
 	case ast.V_RS:
 		return str(p.recordSep)
 	case ast.V_RT:
@@ -792,9 +1138,9 @@ func (p *interp) getSpecial(index int) value {
 	case ast.V_SUBSEP:
 		return str(p.subscriptSep)
 	case ast.V_INPUTMODE:
-		return str(inputModeString(p.inputMode, p.csvInputConfig))
+		return str(inputModeString(p.inputMode, p.csvInputConfig, p.fieldWidthsStr, p.jsonFlatten))
 	case ast.V_OUTPUTMODE:
-		return str(outputModeString(p.outputMode, p.csvOutputConfig))
+		return str(outputModeString(p.outputMode, p.csvOutputConfig, p.jsonOutputConfig))
 	default:
 		panic(fmt.Sprintf("unexpected special variable index: %d", index))
 	}
@@ -854,10 +1200,34 @@ func (p *interp) setSpecial(index int, v value) error {
 		p.argc = v
 	case ast.V_CONVFMT:
 		p.convertFormat = p.toString(v)
+	case ast.V_CSVERROR:
+		p.csvError = p.toString(v)
+	case ast.V_ERRNO:
+		p.errno = v
+	case ast.V_FIELDWIDTHS:
+		// Like gawk, whichever of FS, FPAT, or FIELDWIDTHS was assigned last
+		// determines how fields are split.
+		err := p.setFieldWidths(p.toString(v))
+		if err != nil {
+			return err
+		}
+		p.useFieldWidths = p.fieldWidths != nil
+		p.fieldPatRegex = nil
 	case ast.V_FILENAME:
 		p.filename = v
+	case ast.V_FPAT:
+		p.fieldPat = p.toString(v)
+		re, err := regexp.Compile(compiler.AddRegexFlags(p.fieldPat))
+		if err != nil {
+			return newError("invalid regex %q: %s", p.fieldPat, err)
+		}
+		re.Longest() // other awks use leftmost-longest matching
+		p.fieldPatRegex = re
+		p.useFieldWidths = false
 	case ast.V_FS:
 		p.fieldSep = p.toString(v)
+		p.fieldPatRegex = nil
+		p.useFieldWidths = false
 		if utf8.RuneCountInString(p.fieldSep) > 1 { // compare to interp.ensureFields
 			re, err := regexp.Compile(compiler.AddRegexFlags(p.fieldSep))
 			if err != nil {
@@ -872,6 +1242,10 @@ func (p *interp) setSpecial(index int, v value) error {
 		p.outputFieldSep = p.toString(v)
 	case ast.V_ORS:
 		p.outputRecordSep = p.toString(v)
+	case ast.V_PREC:
+		return p.setPrecision(p.toString(v))
+	case ast.V_ROUNDMODE:
+		return p.setRoundMode(p.toString(v))
 	case ast.V_RS:
 		p.recordSep = p.toString(v)
 		switch { // compare to interp.newScanner
@@ -899,18 +1273,25 @@ func (p *interp) setSpecial(index int, v value) error {
 	case ast.V_SUBSEP:
 		p.subscriptSep = p.toString(v)
 	case ast.V_INPUTMODE:
+		var fieldWidths string
 		var err error
-		p.inputMode, p.csvInputConfig, err = parseInputMode(p.toString(v))
+		p.inputMode, p.csvInputConfig, fieldWidths, p.jsonFlatten, err = parseInputMode(p.toString(v))
 		if err != nil {
 			return err
 		}
+		if p.inputMode == FixedMode {
+			err = p.setFieldWidths(fieldWidths)
+			if err != nil {
+				return err
+			}
+		}
 		err = validateCSVInputConfig(p.inputMode, p.csvInputConfig)
 		if err != nil {
 			return err
 		}
 	case ast.V_OUTPUTMODE:
 		var err error
-		p.outputMode, p.csvOutputConfig, err = parseOutputMode(p.toString(v))
+		p.outputMode, p.csvOutputConfig, p.jsonOutputConfig, err = parseOutputMode(p.toString(v))
 		if err != nil {
 			return err
 		}
@@ -945,6 +1326,92 @@ func (p *interp) localArray(index int) map[string]value {
 	return p.arrays[p.localArrays[len(p.localArrays)-1][index]]
 }
 
+// Return the subarray that the typeArray value v refers to.
+func (p *interp) subarray(v value) map[string]value {
+	array := p.subarrays[int(v.n)]
+	if array == nil {
+		// The subarray was deleted while a reference to it was still on
+		// the stack, for example by f() in a[x][y][f()].
+		array = make(map[string]value)
+	}
+	return array
+}
+
+// Return the array that the typeRef value r refers to an item of.
+func (p *interp) refArray(r value) map[string]value {
+	if r.n < 0 {
+		return p.arrays[-1-int(r.n)]
+	}
+	return p.subarray(r)
+}
+
+// Add array to the subarray table (reusing the slot of a deleted subarray
+// if there is one), and return a typeArray value that refers to it.
+func (p *interp) newSubarray(array map[string]value) value {
+	if n := len(p.freeSubarrays); n > 0 {
+		index := p.freeSubarrays[n-1]
+		p.freeSubarrays = p.freeSubarrays[:n-1]
+		p.subarrays[index] = array
+		return arrayValue(index)
+	}
+	p.subarrays = append(p.subarrays, array)
+	return arrayValue(len(p.subarrays) - 1)
+}
+
+// If v is a subarray value, remove its subarray (and any subarrays nested
+// in it) from the subarray table so the slots can be reused.
+func (p *interp) freeSubarray(v value) {
+	if v.typ != typeArray {
+		return
+	}
+	index := int(v.n)
+	array := p.subarrays[index]
+	if array == nil {
+		return // already freed
+	}
+	p.subarrays[index] = nil
+	p.freeSubarrays = append(p.freeSubarrays, index)
+	for _, item := range array {
+		p.freeSubarray(item)
+	}
+}
+
+// Delete the item with the given index from array, freeing its subarray if
+// it has one. Programs that don't use subarrays skip the extra lookup.
+func (p *interp) deleteItem(array map[string]value, index string) {
+	if len(p.subarrays) > 0 {
+		p.freeSubarray(array[index])
+	}
+	delete(array, index)
+}
+
+// Delete all items from array in place, freeing any subarrays. The delete
+// loop is in the form the Go compiler optimizes to a single map clear.
+func (p *interp) clearArray(array map[string]value) {
+	if len(p.subarrays) > 0 {
+		for _, v := range array {
+			p.freeSubarray(v)
+		}
+	}
+	for k := range array {
+		delete(array, k)
+	}
+}
+
+// Free the subarrays in the current function's local arrays (other than
+// the first numArrayArgs, which are array arguments), as the arrays are
+// about to be discarded.
+func (p *interp) freeLocalSubarrays(numArrayArgs int) {
+	if len(p.subarrays) == 0 {
+		return
+	}
+	for _, index := range p.localArrays[len(p.localArrays)-1][numArrayArgs:] {
+		for _, v := range p.arrays[index] {
+			p.freeSubarray(v)
+		}
+	}
+}
+
 // Set a value in given array by key (index)
 func (p *interp) setArrayValue(scope resolver.Scope, arrayIndex int, index string, v value) {
 	array := p.array(scope, arrayIndex)
@@ -977,12 +1444,41 @@ func (p *interp) getField(index int) value {
 	}
 }
 
-// Get the value of a field by name (for CSV/TSV mode), as in @"name".
+// Get the value of a field by name (for CSV/TSV or JSON Lines mode), as in
+// @"name".
 func (p *interp) getFieldByName(name string) (value, error) {
+	index, err := p.fieldIndexByName(name, false)
+	if err != nil {
+		return null(), err
+	}
+	if index == 0 {
+		return str(""), nil
+	}
+	return p.getField(index), nil
+}
+
+// Set a field by name, as in @"name" = value. If there's no field with that
+// name, add it as a new field after the last one.
+func (p *interp) setFieldByName(name, value string) error {
+	index, err := p.fieldIndexByName(name, true)
+	if err != nil {
+		return err
+	}
+	return p.setField(index, value)
+}
+
+// Return the 1-based index of the field with the given name, or 0 if there
+// isn't one. If create is true, a missing name is added to the field names
+// (and FIELDS) as a new trailing field, and its index is returned.
+func (p *interp) fieldIndexByName(name string, create bool) (int, error) {
+	p.ensureFields() // in JSON Lines mode, field names depend on the record
 	if p.fieldIndexes == nil {
 		// Lazily create map of field names to indexes.
 		if p.fieldNames == nil {
-			return null(), newError(`no field names for @; use -H or add "header" to INPUTMODE, and use "getline" first if in BEGIN`)
+			if p.inputMode == JSONLMode {
+				return 0, newError(`no field names for @; use "getline" first if in BEGIN`)
+			}
+			return 0, newError(`no field names for @; use -H or add "header" to INPUTMODE, and use "getline" first if in BEGIN`)
 		}
 		p.fieldIndexes = make(map[string]int, len(p.fieldNames))
 		for i, n := range p.fieldNames {
@@ -990,10 +1486,17 @@ func (p *interp) getFieldByName(name string) (value, error) {
 		}
 	}
 	index := p.fieldIndexes[name]
-	if index == 0 {
-		return str(""), nil
+	if index == 0 && create {
+		index = len(p.fields) + 1
+		if len(p.fieldNames) >= index {
+			index = len(p.fieldNames) + 1
+		}
+		names := make([]string, index) // unnamed fields in between are ""
+		copy(names, p.fieldNames)
+		names[index-1] = name
+		p.setFieldNames(names)
 	}
-	return p.getField(index), nil
+	return index, nil
 }
 
 // Sets a single field, equivalent to "$index = value"
@@ -1068,7 +1571,7 @@ func getDefaultShellCommand() []string {
 	return []string{executable, "-c"}
 }
 
-func inputModeString(mode IOMode, csvConfig CSVInputConfig) string {
+func inputModeString(mode IOMode, csvConfig CSVInputConfig, fieldWidths, jsonFlatten string) string {
 	var s string
 	var defaultSep rune
 	switch mode {
@@ -1078,6 +1581,13 @@ func inputModeString(mode IOMode, csvConfig CSVInputConfig) string {
 	case TSVMode:
 		s = "tsv"
 		defaultSep = '\t'
+	case FixedMode:
+		return "fixed widths=" + strings.Join(strings.Fields(fieldWidths), ",")
+	case JSONLMode:
+		if jsonFlatten != "." {
+			return "jsonl flatten=" + jsonFlatten
+		}
+		return "jsonl"
 	case DefaultMode:
 		return ""
 	}
@@ -1087,16 +1597,34 @@ func inputModeString(mode IOMode, csvConfig CSVInputConfig) string {
 	if csvConfig.Comment != 0 {
 		s += " comment=" + string([]rune{csvConfig.Comment})
 	}
+	if csvConfig.QuoteChar != 0 && csvConfig.QuoteChar != '"' {
+		s += " quotechar=" + string([]rune{csvConfig.QuoteChar})
+	}
 	if csvConfig.Header {
 		s += " header"
 	}
+	if csvConfig.FieldNames != nil {
+		s += " header=" + strings.Join(csvConfig.FieldNames, ",")
+	}
+	if csvConfig.Strict {
+		s += " strict"
+	}
+	if csvConfig.FieldsPerRecord != 0 {
+		s += " fields=" + strconv.Itoa(csvConfig.FieldsPerRecord)
+	}
+	switch csvConfig.OnError {
+	case CSVErrorSkip:
+		s += " onerror=skip"
+	case CSVErrorReport:
+		s += " onerror=report"
+	}
 	return s
 }
 
-func parseInputMode(s string) (mode IOMode, csvConfig CSVInputConfig, err error) {
+func parseInputMode(s string) (mode IOMode, csvConfig CSVInputConfig, fieldWidths, jsonFlatten string, err error) {
 	fields := strings.Fields(s)
 	if len(fields) == 0 {
-		return DefaultMode, CSVInputConfig{}, nil
+		return DefaultMode, CSVInputConfig{}, "", "", nil
 	}
 	switch fields[0] {
 	case "csv":
@@ -1105,8 +1633,20 @@ func parseInputMode(s string) (mode IOMode, csvConfig CSVInputConfig, err error)
 	case "tsv":
 		mode = TSVMode
 		csvConfig.Separator = '\t'
+	case "fixed":
+		fieldWidths, err = parseFixedInputMode(fields[1:])
+		if err != nil {
+			return DefaultMode, CSVInputConfig{}, "", "", err
+		}
+		return FixedMode, CSVInputConfig{}, fieldWidths, "", nil
+	case "jsonl":
+		jsonFlatten, err = parseJSONLInputMode(fields[1:])
+		if err != nil {
+			return DefaultMode, CSVInputConfig{}, "", "", err
+		}
+		return JSONLMode, CSVInputConfig{}, "", jsonFlatten, nil
 	default:
-		return DefaultMode, CSVInputConfig{}, newError("invalid input mode %q", fields[0])
+		return DefaultMode, CSVInputConfig{}, "", "", newError("invalid input mode %q", fields[0])
 	}
 	for _, field := range fields[1:] {
 		key, val, _ := strings.Cut(field, "=")
@@ -1114,31 +1654,112 @@ func parseInputMode(s string) (mode IOMode, csvConfig CSVInputConfig, err error)
 		case "separator":
 			r, n := utf8.DecodeRuneInString(val)
 			if n == 0 || n < len(val) {
-				return DefaultMode, CSVInputConfig{}, newError("invalid CSV/TSV separator %q", val)
+				return DefaultMode, CSVInputConfig{}, "", "", newError("invalid CSV/TSV separator %q", val)
 			}
 			csvConfig.Separator = r
 		case "comment":
 			r, n := utf8.DecodeRuneInString(val)
 			if n == 0 || n < len(val) {
-				return DefaultMode, CSVInputConfig{}, newError("invalid CSV/TSV comment character %q", val)
+				return DefaultMode, CSVInputConfig{}, "", "", newError("invalid CSV/TSV comment character %q", val)
 			}
 			csvConfig.Comment = r
+		case "quotechar":
+			r, n := utf8.DecodeRuneInString(val)
+			if n == 0 || n < len(val) {
+				return DefaultMode, CSVInputConfig{}, "", "", newError("invalid CSV/TSV quote character %q", val)
+			}
+			csvConfig.QuoteChar = r
 		case "header":
+			switch val {
+			case "", "true":
+				csvConfig.Header = true
+			case "false":
+				csvConfig.Header = false
+				csvConfig.FieldNames = nil
+			default:
+				// Otherwise it's a comma-separated list of field names
+				csvConfig.FieldNames = strings.Split(val, ",")
+			}
+		case "strict":
 			if val != "" && val != "true" && val != "false" {
-				return DefaultMode, CSVInputConfig{}, newError("invalid header value %q", val)
+				return DefaultMode, CSVInputConfig{}, "", "", newError("invalid strict value %q", val)
 			}
-			csvConfig.Header = val == "" || val == "true"
+			csvConfig.Strict = val == "" || val == "true"
+		case "fields":
+			n, err := strconv.Atoi(val)
+			if err != nil {
+				return DefaultMode, CSVInputConfig{}, "", "", newError("invalid fields value %q", val)
+			}
+			csvConfig.FieldsPerRecord = n
+		case "onerror":
+			switch val {
+			case "abort":
+				csvConfig.OnError = CSVErrorAbort
+			case "skip":
+				csvConfig.OnError = CSVErrorSkip
+			case "report":
+				csvConfig.OnError = CSVErrorReport
+			default:
+				return DefaultMode, CSVInputConfig{}, "", "", newError("invalid onerror value %q", val)
+			}
+		default:
+			return DefaultMode, CSVInputConfig{}, "", "", newError("invalid input mode key %q", key)
+		}
+	}
+	return mode, csvConfig, "", "", nil
+}
+
+// Parse the options for "fixed" input mode. The only option is widths, a
+// comma-separated version of FIELDWIDTHS, for example "widths=5,2:3,*".
+func parseFixedInputMode(options []string) (fieldWidths string, err error) {
+	for _, field := range options {
+		key, val, _ := strings.Cut(field, "=")
+		switch key {
+		case "widths":
+			fieldWidths = strings.ReplaceAll(val, ",", " ")
+		default:
+			return "", newError("invalid input mode key %q", key)
+		}
+	}
+	if fieldWidths == "" {
+		return "", newError("fixed input mode requires field widths")
+	}
+	return fieldWidths, nil
+}
+
+// Parse the options for "jsonl" input mode. The only option is flatten, the
+// flattening scheme for nested values (see Config.JSONFlatten), for example
+// "flatten=_" or "flatten=none". The default is "." (flatten with dots).
+func parseJSONLInputMode(options []string) (jsonFlatten string, err error) {
+	jsonFlatten = "."
+	for _, field := range options {
+		key, val, _ := strings.Cut(field, "=")
+		switch key {
+		case "flatten":
+			if val == "" {
+				return "", newError("invalid JSON flatten value %q", val)
+			}
+			jsonFlatten = val
 		default:
-			return DefaultMode, CSVInputConfig{}, newError("invalid input mode key %q", key)
+			return "", newError("invalid input mode key %q", key)
 		}
 	}
-	return mode, csvConfig, nil
+	return jsonFlatten, nil
 }
 
-func outputModeString(mode IOMode, csvConfig CSVOutputConfig) string {
+func outputModeString(mode IOMode, csvConfig CSVOutputConfig, jsonConfig JSONOutputConfig) string {
 	var s string
 	var defaultSep rune
 	switch mode {
+	case JSONMode, JSONLMode:
+		s = "json"
+		if mode == JSONLMode {
+			s = "jsonl"
+		}
+		if jsonConfig.FieldNames != nil {
+			s += " fields=" + strings.Join(jsonConfig.FieldNames, ",")
+		}
+		return s
 	case CSVMode:
 		s = "csv"
 		defaultSep = ','
@@ -1151,13 +1772,30 @@ func outputModeString(mode IOMode, csvConfig CSVOutputConfig) string {
 	if csvConfig.Separator != defaultSep {
 		s += " separator=" + string([]rune{csvConfig.Separator})
 	}
+	if csvConfig.Header {
+		s += " header"
+	}
+	switch csvConfig.Quote {
+	case CSVQuoteAlways:
+		s += " quote=always"
+	case CSVQuoteNonNumeric:
+		s += " quote=nonnumeric"
+	case CSVQuoteNever:
+		s += " quote=never"
+	}
+	if csvConfig.QuoteChar != 0 && csvConfig.QuoteChar != '"' {
+		s += " quotechar=" + string([]rune{csvConfig.QuoteChar})
+	}
+	if csvConfig.Escape != 0 {
+		s += " escape=" + string([]rune{csvConfig.Escape})
+	}
 	return s
 }
 
-func parseOutputMode(s string) (mode IOMode, csvConfig CSVOutputConfig, err error) {
+func parseOutputMode(s string) (mode IOMode, csvConfig CSVOutputConfig, jsonConfig JSONOutputConfig, err error) {
 	fields := strings.Fields(s)
 	if len(fields) == 0 {
-		return DefaultMode, CSVOutputConfig{}, nil
+		return DefaultMode, CSVOutputConfig{}, JSONOutputConfig{}, nil
 	}
 	switch fields[0] {
 	case "csv":
@@ -1166,8 +1804,18 @@ func parseOutputMode(s string) (mode IOMode, csvConfig CSVOutputConfig, err erro
 	case "tsv":
 		mode = TSVMode
 		csvConfig.Separator = '\t'
+	case "json", "jsonl":
+		mode = JSONMode
+		if fields[0] == "jsonl" {
+			mode = JSONLMode
+		}
+		jsonConfig, err = parseJSONOutputMode(fields[1:])
+		if err != nil {
+			return DefaultMode, CSVOutputConfig{}, JSONOutputConfig{}, err
+		}
+		return mode, CSVOutputConfig{}, jsonConfig, nil
 	default:
-		return DefaultMode, CSVOutputConfig{}, newError("invalid output mode %q", fields[0])
+		return DefaultMode, CSVOutputConfig{}, JSONOutputConfig{}, newError("invalid output mode %q", fields[0])
 	}
 	for _, field := range fields[1:] {
 		key, val, _ := strings.Cut(field, "=")
@@ -1175,12 +1823,60 @@ func parseOutputMode(s string) (mode IOMode, csvConfig CSVOutputConfig, err erro
 		case "separator":
 			r, n := utf8.DecodeRuneInString(val)
 			if n == 0 || n < len(val) {
-				return DefaultMode, CSVOutputConfig{}, newError("invalid CSV/TSV separator %q", val)
+				return DefaultMode, CSVOutputConfig{}, JSONOutputConfig{}, newError("invalid CSV/TSV separator %q", val)
 			}
 			csvConfig.Separator = r
+		case "header":
+			if val != "" && val != "true" && val != "false" {
+				return DefaultMode, CSVOutputConfig{}, JSONOutputConfig{}, newError("invalid header value %q", val)
+			}
+			csvConfig.Header = val == "" || val == "true"
+		case "quote":
+			switch val {
+			case "minimal":
+				csvConfig.Quote = CSVQuoteMinimal
+			case "always":
+				csvConfig.Quote = CSVQuoteAlways
+			case "nonnumeric":
+				csvConfig.Quote = CSVQuoteNonNumeric
+			case "never":
+				csvConfig.Quote = CSVQuoteNever
+			default:
+				return DefaultMode, CSVOutputConfig{}, JSONOutputConfig{}, newError("invalid quote value %q", val)
+			}
+		case "quotechar":
+			r, n := utf8.DecodeRuneInString(val)
+			if n == 0 || n < len(val) {
+				return DefaultMode, CSVOutputConfig{}, JSONOutputConfig{}, newError("invalid CSV/TSV quote character %q", val)
+			}
+			csvConfig.QuoteChar = r
+		case "escape":
+			r, n := utf8.DecodeRuneInString(val)
+			if n == 0 || n < len(val) {
+				return DefaultMode, CSVOutputConfig{}, JSONOutputConfig{}, newError("invalid CSV/TSV escape character %q", val)
+			}
+			csvConfig.Escape = r
+		default:
+			return DefaultMode, CSVOutputConfig{}, JSONOutputConfig{}, newError("invalid output mode key %q", key)
+		}
+	}
+	return mode, csvConfig, JSONOutputConfig{}, nil
+}
+
+// Parse the options for "json" or "jsonl" output mode. The only option is
+// fields, a comma-separated list of object keys, for example "fields=id,name".
+func parseJSONOutputMode(options []string) (jsonConfig JSONOutputConfig, err error) {
+	for _, field := range options {
+		key, val, _ := strings.Cut(field, "=")
+		switch key {
+		case "fields":
+			if val == "" {
+				return JSONOutputConfig{}, newError("invalid JSON fields value %q", val)
+			}
+			jsonConfig.FieldNames = strings.Split(val, ",")
 		default:
-			return DefaultMode, CSVOutputConfig{}, newError("invalid output mode key %q", key)
+			return JSONOutputConfig{}, newError("invalid output mode key %q", key)
 		}
 	}
-	return mode, csvConfig, nil
+	return jsonConfig, nil
 }
@@ -8,6 +8,7 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"net"
 	"os"
 	"os/exec"
 	"reflect"
@@ -16,6 +17,8 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"testing/iotest"
+	"time"
 
 	"github.com/benhoyt/goawk/interp"
 	"github.com/benhoyt/goawk/parser"
@@ -193,6 +196,22 @@ BEGIN {
 }
 `, "", "0 0\n", "", ""},
 
+	// switch statement
+	{`BEGIN { switch (2) { case 1: print "a"; case 2: print "b"; case 3: print "c"; break; default: print "d" } }  # !awk !posix`, "", "b\nc\n", "", ""},
+	{`BEGIN { switch (5) { case 1: print "a"; break; default: print "d"; case 2: print "b" } }  # !awk !posix`, "", "d\nb\n", "", ""},
+	{`BEGIN { switch (5) { case 1: print "a" } print "end" }  # !awk !posix`, "", "end\n", "", ""},
+	{`{ switch ($1) { case /^a/: print "regex"; break; case "b": print "str"; break; case -3: print "neg"; break; case 1: print "one"; break; default: print "dflt" } }  # !awk !posix`,
+		"abc\nb\n-3\n1.0\nx\n", "regex\nstr\nneg\none\ndflt\n", "", ""},
+	{`BEGIN { switch ("1.0") { case 1: print "num"; break; case "1.0": print "str" } }  # !awk !posix`, "", "str\n", "", ""},
+	{`BEGIN { switch ("x") { case /x/: print "regex"; break; case "x": print "str" } }  # !awk !posix`, "", "regex\n", "", ""},
+	{`BEGIN { switch (1) { case "1": print "str"; break; case 1: print "num" } }  # !awk !posix`, "", "str\n", "", ""},
+	{`BEGIN { for (i = 1; i <= 4; i++) { switch (i) { case 2: continue; case 4: break; default: print i } } print "done" }  # !awk !posix`, "", "1\n3\ndone\n", "", ""},
+	{`BEGIN { switch (1) { case 1: break } }  # !awk !posix`, "", "", "", ""},
+	{`BEGIN { switch (1) { case 1: print 1; case 1: print 2 } }  # !awk`, "", "", "parse error at 1:45: duplicate case value 1", "duplicate case values"},
+	{`BEGIN { switch (1) { default: print 1; default: print 2 } }  # !awk`, "", "", "parse error at 1:40: more than one default case in switch", "duplicate `default'"},
+	{`BEGIN { switch (1) { case x: print 1 } }`, "", "", "parse error at 1:27: expected number, string, or regex after case instead of name", "syntax error"},
+	{`BEGIN { switch (1) { print 1 } }`, "", "", "parse error at 1:22: expected case or default instead of print", "syntax error"},
+
 	// next and nextfile statements (more tests of nextfile in goawk_test.go)
 	{`{ if (NR==2) next; print }`, "a\nb\nc", "a\nc\n", "", ""},
 	{`{ if (NR==2) f(); print }  function f() { next }`, "a\nb\nc", "a\nc\n", "", ""},
@@ -203,6 +222,20 @@ BEGIN {
 	{`BEGIN { nextfile }`, "", "", "parse error at 1:9: nextfile can't be inside BEGIN or END", "BEGIN"},
 	{`END { nextfile }`, "", "", "parse error at 1:7: nextfile can't be inside BEGIN or END", "END"},
 
+	// BEGINFILE and ENDFILE (more tests with multiple files in goawk_test.go)
+	{`BEGINFILE { print "begin", FNR, ERRNO } { print NR, $0 } ENDFILE { print "end", FNR } END { print "done" }  # !awk !posix`, "a\nb", "begin 0 \n1 a\n2 b\nend 2\ndone\n", "", ""},
+	{`BEGINFILE { print "begin" }  # !awk !posix`, "a\nb", "begin\n", "", ""},
+	{`ENDFILE { print "end", NR }  # !awk !posix`, "a\nb", "end 2\n", "", ""},
+	{`BEGINFILE { nextfile } { print } ENDFILE { print "end" } END { print NR }  # !awk !posix`, "a\nb", "0\n", "", ""},
+	{`{ print; nextfile } ENDFILE { print "end" } END { print NR }  # !awk !posix`, "a\nb", "a\nend\n1\n", "", ""},
+	{`BEGINFILE { exit } { print } ENDFILE { print "end" } END { print "done" }  # !awk !posix`, "a", "done\n", "", ""},
+	{`BEGIN { while ((getline line) > 0) n++; print n } BEGINFILE { print "begin" } ENDFILE { print "end" }  # !awk !posix`, "a\nb", "begin\nend\n2\n", "", ""},
+	{`BEGINFILE { next }  # !awk`, "", "", "parse error at 1:13: next can't be inside BEGINFILE", "next"},
+	{`ENDFILE { next }  # !awk`, "", "", "parse error at 1:11: next can't be inside ENDFILE", "next"},
+	{`ENDFILE { nextfile }  # !awk`, "", "", "parse error at 1:11: nextfile can't be inside ENDFILE", "nextfile"},
+	{`BEGINFILE { getline }  # !awk`, "", "", "parse error at 1:21: non-redirected getline can't be inside BEGINFILE", "getline"},
+	{`BEGINFILE { f() } function f() { next }  # !awk !gawk`, "a", "", "next can't be used in BEGINFILE", ""},
+
 	// Arrays, "in", and delete
 	{`BEGIN { a["x"] = 3; print "x" in a, "y" in a }`, "", "1 0\n", "", ""},
 	{`BEGIN { a["x"] = 3; a["y"] = 4; delete a["x"]; for (k in a) print k, a[k] }`, "", "y 4\n", "", ""},
@@ -213,6 +246,36 @@ BEGIN {
 	{`BEGIN { a["x"] = 3; a["y"] = 4; delete a; for (k in a) print k, a[k] }`, "", "", "", ""},
 	{`function f(a) { print "x" in a, "y" in a }  BEGIN { b["x"] = 3; f(b) }`, "", "1 0\n", "", ""},
 
+	// Arrays of arrays (subarrays)
+	{`BEGIN { a["x"]["y"] = 1; a["x"]["z"] = 2; a["w"] = 3; print length(a), length(a["x"]), length(a["w"]), a["x"]["y"] a["x"]["z"] }  # !awk !posix`, "", "2 2 1 12\n", "", ""},
+	{`BEGIN { a[1][2][3] = "deep"; print a[1][2][3], length(a[1]), length(a[1][2]) }  # !awk !posix`, "", "deep 1 1\n", "", ""},
+	{`function f(a) { return length(a) }  BEGIN { x["k"]["j"] = 1; x["k"]["i"] = 2; print f(x["k"]) }  # !awk !posix`, "", "2\n", "", ""},
+	{`function f(a) { return g(a) }  function g(b) { return length(b) }  BEGIN { x["k"]["j"] = 1; print f(x["k"]), f(x["k"]) }  # !awk !posix`, "", "1 1\n", "", ""},
+	{`BEGIN { a["x"]["y"] = 1; print ("y" in a["x"]), ("z" in a["x"]), ("x" in a), length(a["x"]) }  # !awk !posix`, "", "1 0 1 1\n", "", ""},
+	{`BEGIN { a["x"][1] = 3; a["x"][2] = 4; for (k in a["x"]) s += k * a["x"][k]; print s }  # !awk !posix`, "", "11\n", "", ""},
+	{`BEGIN { a["x"][1] = 3; a["x"][2] = 4; for (k in a["x"]) { n++; break } print n }  # !awk !posix`, "", "1\n", "", ""},
+	{`BEGIN { a[1][1] = 5; a[1][1]++; a[1][1] += 10; a[1][1] *= 2; print a[1][1], ++a[1][1], a[1][1]--, a[1][1] }  # !awk !posix`, "", "32 33 33 32\n", "", ""},
+	{`BEGIN { print (a[1][1] = "y") (a[1][2] = "z"), (a[1][1] += 3), length(a[1]) }  # !awk !posix`, "", "yz 3 2\n", "", ""},
+	{`BEGIN { a[1][1] = 1; a[1][2] = 2; delete a[1][1]; print length(a[1]), (1 in a[1]), (2 in a[1]); delete a[1]; print length(a) }  # !awk !posix`, "", "1 0 1\n0\n", "", ""},
+	{`BEGIN { a[1][1][1] = "x"; a[2][1] = "y"; delete a[1]; a[3][1][1] = "z"; a[4][1] = "w"; print a[2][1], a[3][1][1], a[4][1], length(a), length(a[3]) }  # !awk !posix`, "", "y z w 3 1\n", "", ""},
+	{`function f(n,   l) { l[n]["v"] = n; g[n][n] = n; return length(l[n]) }  BEGIN { for (i = 1; i <= 3; i++) s = s f(i); print s, length(g), g[2][2] }  # !awk !posix`, "", "111 3 2\n", "", ""},
+	{`BEGIN { a["k", 1]["x"] = "multi"; print a["k", 1]["x"], (("k", 1) in a), (("x") in a["k", 1]) }  # !awk !posix`, "", "multi 1 1\n", "", ""},
+	{`BEGIN { n = split("a b c", a["s"]); print n, a["s"][3]; n = split("x:y", a["s"], ":"); print n, a["s"][1], length(a["s"]) }  # !awk !posix`, "", "3 c\n2 x 2\n", "", ""},
+	{`BEGIN { a["k"]["v"] = "hello"; n = gsub(/l/, "L", a["k"]["v"]); print n, a["k"]["v"] }  # !awk !posix`, "", "2 heLLo\n", "", ""},
+	{`BEGIN { getline a[1][2]; print a[1][2], length(a[1]) }  # !awk !posix`, "foo", "foo 1\n", "", ""},
+	{`function fill(arr, n,   i) { for (i = 1; i <= n; i++) arr[i] = i * 10 }
+	  function total(arr,   k, t) { for (k in arr) t += arr[k]; return t }
+	  BEGIN { fill(b["q"], 3); print length(b["q"]), total(b["q"]), b["q"][2] }  # !awk !posix`, "", "3 60 20\n", "", ""},
+	{`function f(l,   k) { l["p"]["q"] = 5; g(l["p"]) }  function g(s,   k) { for (k in s) print k, s[k] }
+	  BEGIN { f(z); print z["p"]["q"] }  # !awk !posix`, "", "q 5\n5\n", "", ""},
+	{`BEGIN { a["x"][1] = 3; a["x"][2] = 4; a["x"][3] = 2; PROCINFO["sorted_in"] = "@val_num_desc"; for (k in a["x"]) printf "%s ", k; print "" }  # !awk !posix`, "", "2 1 3 \n", "", ""},
+	{`BEGIN { a["x"]["y"] = 1; print a["x"] }  # !awk !posix`, "", "", `can't use subarray "x" as scalar`, "attempt to use array"},
+	{`function f(arr) { arr[1][2] = 3 }  function g(arr) { return arr[1] }  BEGIN { f(a); g(a) }  # !awk !posix`, "", "", `can't use subarray "1" as scalar`, "attempt to use array"},
+	{`function g(arr) { return arr[2] }  BEGIN { a[1][2][3] = 1; g(a[1]) }  # !awk !posix`, "", "", `can't use subarray "2" as scalar`, "attempt to use array"},
+	{`BEGIN { split("x y", a[1]); print a[1] }  # !awk !posix`, "", "", `can't use subarray "1" as scalar`, "attempt to use array"},
+	{`BEGIN { a[1][2] = 3; asort(a, b); print b[1] }  # !awk !posix`, "", "", `can't use subarray "1" as scalar`, "attempt to use array"},
+	{`BEGIN { a["x"] = 1; a["x"]["y"] = 2 }  # !awk !posix`, "", "", `can't use scalar "x" as subarray`, "attempt to use scalar"},
+
 	// Unary expressions: ! + -
 	{`BEGIN { print !42, !1, !0, !!42, !!1, !!0 }`, "", "0 0 1 1 1 0\n", "", ""},
 	{`BEGIN { print !42, !1, !0, !!42, !!1, !!0 }`, "", "0 0 1 1 1 0\n", "", ""},
@@ -354,6 +417,21 @@ BEGIN {
 	{`BEGIN { FS="\\" } { print $1, $2 }`, "a\\b", "a b\n", "", ""},
 	{`BEGIN { RS="x"; FS=",.*," } { for (i=1; i<=NF; i++) print $i }`, "one,\n,two", "one\ntwo\n", "", ""},
 	{`BEGIN { FS="x"; RS=",.*," } { print }  # !posix`, "one,\n,two", "one\ntwo\n", "", ""},
+	{`BEGIN { FPAT="([^,]*)|(\"[^\"]*\")" } { for (i=1; i<=NF; i++) print i, "<" $i ">" }  # !awk !posix`,
+		`a,"b, c",,d,` + "\n", "1 <a>\n2 <\"b, c\">\n3 <>\n4 <d>\n5 <>\n", "", ""},
+	{`BEGIN { FPAT="\\[[^]]*\\]|[^ ]+" } { print NF, $2, $3 }  # !awk !posix`,
+		"1.2.3.4 [10/Oct/2000:13:55:36 -0700] GET\n", "3 [10/Oct/2000:13:55:36 -0700] GET\n", "", ""},
+	{`BEGIN { print FPAT; FPAT="[0-9]+" } { print NF, $1; FS=","; $0=$0; print NF, $1 }  # !awk !posix`,
+		"a1,b22\n", "[^[:space:]]+\n2 1\n2 a1\n", "", ""},
+	{`BEGIN { FPAT="[a-z]+"; $0="ab 12 cd"; $2="x"; print NF, $0 }  # !awk !posix`, "", "2 ab x\n", "", ""},
+	{`BEGIN { FPAT="(" }  # !awk !posix`, "", "", "invalid regex \"(\": error parsing regexp: missing closing ): `(?s:()`", ""},
+	{`BEGIN { FIELDWIDTHS="3 2:4 *" } { print NF; for (i=1; i<=NF; i++) print i, "<" $i ">" }  # !awk !posix`,
+		"abcdefghijkl\nab cd\nabcdefg\n", "3\n1 <abc>\n2 <fghi>\n3 <jkl>\n1\n1 <ab >\n2\n1 <abc>\n2 <fg>\n", "", ""},
+	{`BEGIN { FIELDWIDTHS="2 2" } { print NF, $1, $2; FPAT="[0-9]"; $0=$0; print NF, $1; FIELDWIDTHS="1"; $0=$0; print NF, $1, FIELDWIDTHS }  # !awk !posix`,
+		"a1b2c3\n", "2 a1 b2\n3 1\n1 a 1\n", "", ""},
+	{`BEGIN { FIELDWIDTHS="1 1"; $0="abc"; $2="X"; print NF, $0 }  # !awk !posix`, "", "2 a X\n", "", ""},
+	{`BEGIN { FIELDWIDTHS="2 x" }  # !awk !posix`, "", "", `invalid FIELDWIDTHS "2 x"`, ""},
+	{`BEGIN { FIELDWIDTHS="* 2" }  # !awk !posix`, "", "", `invalid FIELDWIDTHS "* 2"`, ""},
 	{`{ print NF }`, "\na\nc d\ne f g", "0\n1\n2\n3\n", "", ""},
 	{`BEGIN { NR = 123; print NR }`, "", "123\n", "", ""},
 	{`{ print NR, $0 }`, "a\nb\nc", "1 a\n2 b\n3 c\n", "", ""},
@@ -616,6 +694,14 @@ function f() {
 	// There's a quirk in Gawk where "a" has to be referenced as an array first, so skip Gawk for now:
 	{`BEGIN { print length(a); a[1]; a[2]=2; a[2]=3; print length(a) }  # !gawk !posix`, "", "0\n2\n", "", ""},
 	{`BEGIN { a[1]=1; a[2]=2; a[3]=3; print len(a) } function len(x) { return length(x) }  # !posix`, "", "3\n", "", ""},
+	{`BEGIN { a[1]; n = 3; s = "s"; print typeof(a), typeof(n), typeof(s), typeof(1+1), typeof("x" 1), typeof(z) }  # !awk !posix`, "", "array number string number string untyped\n", "", ""},
+	{`{ print typeof($1), typeof($2), typeof($0), typeof(NR), typeof(FS) }  # !awk !posix`, "12 abc", "strnum string string number string\n", "", ""},
+	{`BEGIN { a[1]; b["x"]["y"] = 1; print typeof(a[1]), typeof(b["x"]), typeof(b["x"]["y"]) }  # !awk !posix`, "", "unassigned array number\n", "", ""},
+	{`BEGIN { x + 0; print typeof(x); split("1 a", p); print typeof(p[1]), typeof(p[2]) }  # !awk !posix`, "", "unassigned\nstrnum string\n", "", ""},
+	{`BEGIN { print typeof(a[1]), length(a) }  # !awk !posix`, "", "untyped 0\n", "", ""},
+	{`function f(x) { return typeof(x) }  BEGIN { print f(), f(1), f("s") }  # !awk !posix`, "", "untyped number string\n", "", ""},
+	{`function f(x) { return isarray(x) }  BEGIN { a[1]; print f(a) }  # !awk !posix`, "", "1\n", "", ""},
+	{`BEGIN { a[1]; b["x"]["y"] = 1; n = 3; print isarray(a), isarray(b["x"]), isarray(b["x"]["y"]), isarray(n), isarray(1), isarray(z) }  # !awk !posix`, "", "1 1 0 0 0 0\n", "", ""},
 	{`BEGIN { print index("foo", "f"), index("foo0", 0), index("foo", "o"), index("foo", "x") }`, "", "1 4 2 0\n", "", ""},
 	{`BEGIN { print atan2(1, 0.5), atan2(-1, 0) }`, "", "1.10715 -1.5708\n", "", ""},
 	{`BEGIN { print sprintf("%3d", 42) }`, "", " 42\n", "", ""},
@@ -648,6 +734,55 @@ function f() {
 	{`{ n = split($0, a, /./); for (i=1; i<=n; i++) { print i, a[i] } }  # !gawk`, "a.a.a\n", "1 \n2 \n3 \n4 \n5 \n6 \n", "", ""},
 	{`BEGIN { n = split("a b\tc", a, / /); print n; for (i=1; i<=n; i++) print a[i] }`, "", "2\na\nb\tc\n", "", ""},
 	{`BEGIN { n = split("1 2", a); print (n, a[1], a[2], a[1]==1, a[2]==2) }`, "", "2 1 2 1 1\n", "", ""},
+	{`BEGIN { n = patsplit("ab12cd345", a, /[0-9]+/, s); print n, a[1], a[2], s[0], s[1], (2 in s) }  # !awk !posix`, "", "2 12 345 ab cd 0\n", "", ""},
+	{`BEGIN { n = patsplit("12ab34", a, "[0-9]+", s); print n, a[1], a[2], (0 in s), s[1], (2 in s) }  # !awk !posix`, "", "2 12 34 0 ab 0\n", "", ""},
+	{`BEGIN { a[5]; n = patsplit("x y  z", a); print n, a[1], a[3], (5 in a) }  # !awk !posix`, "", "3 x z 0\n", "", ""},
+	{`BEGIN { FPAT="[a-z]"; n = patsplit("a1b", a); print n, a[2] }  # !awk !posix`, "", "2 b\n", "", ""},
+	{`BEGIN { n = patsplit("a=1, b=2", a["s"], /[a-z]=[0-9]/, s["t"]); print n, a["s"][2], s["t"][1] }  # !awk !posix`, "", "2 b=2 , \n", "", ""},
+	{`BEGIN { print and(12, 10), and(15, 7, 5), or(12, 10), or(1, 2, 4, 8), xor(12, 10), xor(1, 3, 7) }  # !awk !posix`, "", "8 5 14 15 6 5\n", "", ""},
+	{`BEGIN { print lshift(1, 10), rshift(1024, 3), rshift(5, 64), lshift(2^40, 22), compl(0), compl(5) }  # !awk !posix`, "", "1024 128 0 4611686018427387904 9007199254740991 9007199254740986\n", "", ""},
+	{`BEGIN { print and("6", "3.9"), or(2^62, 1), lshift(1, 64) }  # !awk !posix`, "", "2 4611686018427387905 1.84467e+19\n", "", ""},
+	{`BEGIN { print and(-1, 3) }  # !awk !posix`, "", "", "and: negative values not allowed", "negative"},
+	{`BEGIN { print compl(-1) }  # !awk !posix`, "", "", "compl: negative values not allowed", "negative"},
+	{`BEGIN { print and(2^64, 1) }  # !awk !gawk`, "", "", "and: values of 2^64 or more not allowed", ""},
+	{`BEGIN { print or(1, -log(0)) }  # !awk !gawk`, "", "", "or: values of 2^64 or more not allowed", ""},
+	{`BEGIN { printf "%d %i %u %x %d\n", 2^63, -2^63, 2^64, 2^70, 1e30 }  # !awk`, "", "9223372036854775808 -9223372036854775808 18446744073709551616 400000000000000000 1000000000000000019884624838656\n", "", ""},
+	{`BEGIN { printf "%d|%5i|%-5d|\n", log(-1), -log(0), log(0) }  # !awk !gawk`, "", "nan|  inf|-inf |\n", "", ""},
+	{`BEGIN { x = 9007199254740993; print x, x+0, x-1, x*1, -x; printf "%d %i %x\n", x, x, x }  # !awk !gawk`, "", "9007199254740993 9007199254740993 9007199254740992 9007199254740993 -9007199254740993\n9007199254740993 9007199254740993 20000000000001\n", "", ""},
+	{`{ x = $1 + 0; print x, $1 + 1, $1 + 2, int($1), x % 10, x / 3 }  # !awk !gawk`, "9223372036854775806\n", "9223372036854775806 9223372036854775807 9.22337e+18 9223372036854775806 6 3074457345618258602\n", "", ""},
+	{`BEGIN { x = 2^62; y = 3^39; y++; print x, x + x, y, y * 3, 9007199254740993 - 1 }  # !awk !gawk`, "", "4611686018427387904 9.22337e+18 4052555153018976268 1.21577e+19 9007199254740992\n", "", ""},
+	{`BEGIN { x = 9007199254740993; y = 9007199254740992; print (x == y), (x != y), (x < y), (x > y), (y <= x), (x >= y); if (x == y) print "equal" }  # !awk !gawk`, "", "0 1 0 1 1 1\n", "", ""},
+	{`$2 == 9007199254740992 { print "eq", $1 } $2 > 9007199254740992 { print "gt", $1 }  # !awk !gawk`, "a 9007199254740993\nb 9007199254740992\n", "gt a\neq b\n", "", ""},
+	{`BEGIN { a["x"] = 9007199254740993; a["y"] = 9007199254740992; a["z"] = -9007199254740993
+	         PROCINFO["sorted_in"] = "@val_num_asc"; for (k in a) print k, a[k]
+	         n = asort(a); print a[1], a[2], a[3] }  # !awk !gawk`, "", "z -9007199254740993\ny 9007199254740992\nx 9007199254740993\n-9007199254740993 9007199254740992 9007199254740993\n", "", ""},
+	{`BEGIN { a["x"]=3; a["y"]="b"; a["z"]=10; a["w"]="a"; n = asort(a); print n, a[1], a[2], a[3], a[4], ("x" in a) }  # !awk !posix`, "", "4 3 10 a b 0\n", "", ""},
+	{`BEGIN { a["x"]=3; a["y"]=1; n = asort(a, b); print n, b[1], b[2], a["x"], a["y"] }  # !awk !posix`, "", "2 1 3 3 1\n", "", ""},
+	{`BEGIN { a["x"]=3; a["y"]=1; a["z"]=2; n = asort(a, b, "@val_num_desc"); print n, b[1], b[2], b[3] }  # !awk !posix`, "", "3 3 2 1\n", "", ""},
+	{`BEGIN { a[1]=10; a[2]=9; a[3]="x"; asort(a, b, "@val_str_asc"); print b[1], b[2], b[3] }  # !awk !posix`, "", "10 9 x\n", "", ""},
+	{`BEGIN { CONVFMT="%.2g"; a[1]=3.14159; a[2]=3.1; asort(a, b, "@val_str_asc"); print b[1], b[2] }  # !awk !posix`, "", "3.14159 3.1\n", "", ""},
+	{`BEGIN { a["b"]; a["c"]; a["a"]; n = asorti(a); print n, a[1], a[2], a[3] }  # !awk !posix`, "", "3 a b c\n", "", ""},
+	{`BEGIN { a[10]; a[9]; a[100]; asorti(a, b); print b[1], b[2], b[3]; asorti(a, b, "@ind_num_asc"); print b[1], b[2], b[3] }  # !awk !posix`, "", "10 100 9\n9 10 100\n", "", ""},
+	{`BEGIN { a["x"]; a["y"]; asorti(a, b, "@ind_str_desc"); print b[1], b[2] }  # !awk !posix`, "", "y x\n", "", ""},
+	{`function cmp(i1, v1, i2, v2) { return length(v1) - length(v2) }
+	  BEGIN { a[1]="ccc"; a[2]="a"; a[3]="bb"; n = asort(a, b, "cmp"); print n, b[1], b[2], b[3] }  # !awk !posix`, "", "3 a bb ccc\n", "", ""},
+	{`function rev(i1, v1, i2, v2) { return i1 < i2 ? 1 : i1 > i2 ? -1 : 0 }
+	  BEGIN { a["p"]; a["q"]; a["r"]; asorti(a, b, "rev"); print b[1], b[2], b[3] }  # !awk !posix`, "", "r q p\n", "", ""},
+	{`BEGIN { a["s"]["b"]=2; a["s"]["a"]=1; n = asorti(a["s"], b["t"]); print n, b["t"][1], b["t"][2] }  # !awk !posix`, "", "2 a b\n", "", ""},
+	{`BEGIN { a[1]["x"]=1; a[2]="z"; a[3]=5; n = asort(a, b); print n, b[1], b[2], length(b[3]), b[3]["x"] }  # !awk !posix`, "", "3 5 z 1 1\n", "", ""},
+	{`BEGIN { a["b"]=1; a["c"]=3; a["a"]=2; PROCINFO["sorted_in"] = "@ind_str_asc"; for (k in a) printf "%s ", k; print "" }  # !awk !posix`, "", "a b c \n", "", ""},
+	{`BEGIN { a["b"]=1; a["c"]=3; a["a"]=2; PROCINFO["sorted_in"] = "@val_num_desc"; for (k in a) printf "%s ", k; print "" }  # !awk !posix`, "", "c a b \n", "", ""},
+	{`BEGIN { a[10]; a[9]; a[100]; PROCINFO["sorted_in"] = "@ind_num_asc"; for (k in a) printf "%s ", k; print "" }  # !awk !posix`, "", "9 10 100 \n", "", ""},
+	{`function cmp(i1, v1, i2, v2) { return length(v1) - length(v2) }
+	  BEGIN { a["x"]="ccc"; a["y"]="a"; a["z"]="bb"; PROCINFO["sorted_in"] = "cmp"; for (k in a) printf "%s ", k; print "" }  # !awk !posix`, "", "y z x \n", "", ""},
+	{`BEGIN { a[1]; a[2]; a[3]; PROCINFO["sorted_in"] = "@ind_num_desc"; for (k in a) { printf "%s ", k; if (k == 2) break }; print "" }  # !awk !posix`, "", "3 2 \n", "", ""},
+	{`function f(a, k) { PROCINFO["sorted_in"] = "@ind_str_asc"; for (k in a) printf "%s ", k; print "" } BEGIN { b["y"]; b["x"]; f(b) }  # !awk !posix`, "", "x y \n", "", ""},
+	{`BEGIN { a[1]; n = asort(a, b, "@foo_asc") }  # !awk !gawk`, "", "", `invalid sort order "@foo_asc"`, ""},
+	{`BEGIN { a[1]; n = asort(a, b, "nope") }  # !awk !gawk`, "", "", `sort function "nope" not defined`, ""},
+	{`function f(x, y) { y[1] } BEGIN { a[1]; a[2]; n = asort(a, b, "f") }  # !awk !gawk`, "", "", `sort function "f" parameter "y" must be a scalar`, ""},
+	{`function cmp(i1, v1, i2, v2) { return 0 } BEGIN { a[1]; a[2][1]; n = asort(a, b, "cmp") }  # !awk !gawk`, "", "", `can't pass subarray "2" to sort function "cmp"`, ""},
+	{`function cmp(i1, v1, i2, v2) { return 0 } BEGIN { a["x"][1]; PROCINFO["sorted_in"] = "cmp"; for (k in a) print k }  # !awk !gawk`, "", "", `can't pass subarray "x" to sort function "cmp"`, ""},
+	{`function cmp(i1) { return 0 } BEGIN { a["x"][1]; PROCINFO["sorted_in"] = "cmp"; for (k in a) print k }  # !awk !gawk`, "", "x\n", "", ""},
 	{`BEGIN { x = "1.2.3"; print sub(/\./, ",", x); print x }`, "", "1\n1,2.3\n", "", ""},
 	{`BEGIN { x = "1.2.3"; print sub(/\./, ",\\", x); print x }`, "", "1\n1,\\2.3\n", "", ""},
 	{`BEGIN { a["x"] = "1.2.3"; print sub(/\./, ",", a[f()]); print a["x"] }  function f() { print "f"; return "x" }`,
@@ -667,7 +802,22 @@ function f() {
 		"invalid regex \"\\\\e \": error parsing regexp: invalid escape sequence: `\\e`", ""},
 	// ensure leftmost-longest matching like other awks
 	{`BEGIN { s = "#!a"; sub(/(#|#!)/, "", s); print s }`, "", "a\n", "", ""},
-
+	{`BEGIN { s = "hello world"; print gensub(/o/, "0", "g", s); print s }  # !awk !posix`, "", "hell0 w0rld\nhello world\n", "", ""},
+	{`BEGIN { print gensub(/o/, "0", "G", "foo") }  # !awk !posix`, "", "f00\n", "", ""},
+	{`BEGIN { s = "a.b.c.d"; print gensub(/\./, "-", 1, s), gensub(/\./, "-", 3, s), gensub(/\./, "-", "2", s), gensub(/\./, "-", 9, s) }  # !awk !posix`, "", "a-b.c.d a.b.c-d a.b-c.d a.b.c.d\n", "", ""},
+	{`BEGIN { print gensub(/([a-z]+) ([a-z]+)/, "\\2 \\1", "g", "hello world foo bar") }  # !awk !posix`, "", "world hello bar foo\n", "", ""},
+	{`BEGIN { print gensub(/(b)(x)?/, "[\\0|&|\\1|\\2|\\&|\\\\]", 1, "abc") }  # !awk !posix`, "", "a[b|b|b||&|\\]c\n", "", ""},
+	{`{ print gensub(/[0-9]+/, "<&>", "g"); print }  # !awk !posix`, "a1 b22", "a<1> b<22>\na1 b22\n", "", ""},
+	{`BEGIN { print gensub(/x*/, "-", "g", "abc") }  # !awk !posix`, "", "-a-b-c-\n", "", ""},
+
+	{`BEGIN { print mktime("2024 03 05 14 07 09", 1), mktime("2024 02 30 00 00 00", 1), mktime("2024 03 05 14 07"), mktime("x y z 1 2 3") }  # !awk !posix`, "", "1709647629 1709251200 -1 -1\n", "", ""},
+	{`BEGIN { print strftime("%Y-%m-%d %H:%M:%S|%a %A %b %B|%e|%j %U %W %V %u %w|%I %p|%y %C|%D %T %F %R|%s %%", 1709647629, 1) }  # !awk !posix`, "",
+		"2024-03-05 14:07:09|Tue Tuesday Mar March| 5|065 09 10 10 2 2|02 PM|24 20|03/05/24 14:07:09 2024-03-05 14:07|1709647629 %\n", "", ""},
+	{`BEGIN { print strftime("%c", 0, 1), strftime("%Q", 0, 1) }  # !awk !posix`, "", "Thu Jan  1 00:00:00 1970 %Q\n", "", ""},
+	{`BEGIN { print strptime("2024-03-05T14:07:09Z"), strptime("2024-03-05T16:07:09.123+02:00"), strptime("2024-03-05"), strptime("") }  # !awk !gawk`, "", "1709647629 1709647629 -1 -1\n", "", ""},
+	{`BEGIN { print strptime("05/Mar/2024:14:07:09 +0000", "%d/%b/%Y:%H:%M:%S %z"), strptime("Tue, 5 march 2024 2:07:09 PM UTC", "%a, %d %B %Y %I:%M:%S %p %Z") }  # !awk !gawk`, "", "1709647629 1709647629\n", "", ""},
+	{`BEGIN { print strptime("1709647629", "%s"), strptime("2024-065 14:07:09 Z", "%Y-%j %T %z"), strptime("2024-03-05 extra", "%F"), strptime("2024", "%Q") }  # !awk !gawk`, "", "1709647629 1709647629 -1 -1\n", "", ""},
+	{`BEGIN { t = 1709647629; print strptime(strftime("%F %T %z", t, 1), "%F %T %z") == t }  # !awk !gawk`, "", "1\n", "", ""},
 	{`BEGIN { print tolower("Foo BaR") }`, "", "foo bar\n", "", ""},
 	{`BEGIN { print toupper("Foo BaR") }`, "", "FOO BAR\n", "", ""},
 	{`
@@ -826,6 +976,36 @@ BEGIN { a[1]=2; f1(a); print f2(a, 1) }
 	{`function add(a, b) { return a+b }  BEGIN { print add(1, 2), add(1), add() }`, "", "3 1 0\n", "", ""},
 	{`function f1(A) {}  function f2(x, A) { x[0]; f1(a); f2(a) }`, "", "", "", ""}, // found via fuzzing
 
+	// Indirect function calls
+	{`function add(a, b) { return a+b }  BEGIN { f = "add"; print @f(1, 2), @f(1), @f() }  # !awk !posix`, "", "3 1 0\n", "", ""},
+	{`function hi(x) { print "hi", x }  function bye(x) { print "bye", x }  { h[$1] = $1 }  { fn = h[$1]; @fn($2) }  # !awk !posix`,
+		"hi a\nbye b\n", "hi a\nbye b\n", "", ""},
+	{`function apply(fn, x) { return @fn(x) }  function sq(n) { return n*n }  BEGIN { print apply("sq", 7) }  # !awk !posix`, "", "49\n", "", ""},
+	{`function fact(n) { f = "fact"; return n <= 1 ? 1 : n * @f(n-1) }  BEGIN { print fact(5) }  # !awk !posix`, "", "120\n", "", ""},
+	{`function f(x, a) { a[1] = x; return length(a) }  BEGIN { g = "f"; print @g(5) }  # !awk !posix`, "", "1\n", "", ""},
+	{`BEGIN { f = "nope"; @f() }  # !awk`, "", "", `indirect call to undefined function "nope"`, "not defined"},
+	{`function add(a, b) { return a+b }  BEGIN { f = "add"; @f(1, 2, 3) }  # !awk !gawk`, "", "", `"add" called with more arguments than declared`, ""},
+	{`function f(x, a) { a[1] = x }  BEGIN { g = "f"; @g(1, 2) }  # !awk !gawk`, "", "", `can't pass scalar as array param "a" in indirect call to "f"`, ""},
+	{`function total(a,  k, t) { for (k in a) t += a[k]; return t }  BEGIN { a[1] = 2; a[2] = 3; f = "total"; print @f(a) }  # !awk !posix`, "", "5\n", "", ""},
+	{`function total(a,  k, t) { for (k in a) t += a[k]; return t }  BEGIN { a["x"][1] = 4; a["x"][2] = 6; f = "total"; print @f(a["x"]) }  # !awk !posix`, "", "10\n", "", ""},
+	{`function fill(a) { a["p"]["q"] = 5 }  BEGIN { f = "fill"; @f(a); @f(b["m"]); print a["p"]["q"], b["m"]["p"]["q"] }  # !awk !posix`, "", "5 5\n", "", ""},
+	{`function sq(n) { return n*n }  BEGIN { a[1] = 7; f = "sq"; print @f(a[1]), length(a) }  # !awk !posix`, "", "49 1\n", "", ""},
+	{`BEGIN { a["x"]; f = "g"; @f(a) }  function g(x) { return x }  # !awk !gawk`, "", "", `can't pass array as scalar param "x" in indirect call to "g"`, ""},
+	{`BEGIN { a["x"]["y"]; f = "g"; @f(a["x"]) }  function g(x) { return x }  # !awk !gawk`, "", "", `can't use subarray "x" as scalar`, ""},
+	{`BEGIN { a["x"] = 1; f = "g"; @f(a["x"]) }  function g(x) { x[1] }  # !awk !gawk`, "", "", `can't use scalar "x" as subarray`, ""},
+
+	// Namespaces
+	{`@namespace "ns"; function f() { x = 1; return x }  BEGIN { print f(), x, ns::x, awk::x "|" }  # !awk !posix`, "", "1 1 1 |\n", "", ""},
+	{`@namespace "ns"; function f(x) { return g(x) }  function g(y) { return y * 2 }  @namespace "awk"; BEGIN { print ns::f(21) }  # !awk !posix`, "", "42\n", "", ""},
+	{`BEGIN { x = "global" }  @namespace "ns"; BEGIN { x = "ns"; print x, awk::x; FOO = 1 }  END { print awk::FOO, NR }  # !awk !posix`, "a", "ns global\n1 1\n", "", ""},
+	{`@namespace "ns"; BEGIN { a["k"] = 1; for (k in a) print k, ns::a[k]; print length(a), toupper("x") }  # !awk !posix`, "", "k 1\n1 X\n", "", ""},
+	{`@namespace "ns"; function f() {}  BEGIN { f = 1 }  # !awk !gawk`, "", "", `parse error at 1:43: global var "ns::f" can't also be a function`, ""},
+	{`@namespace "ns"; function f() {}  BEGIN { awk::f() }  # !awk !gawk`, "", "", `parse error at 1:43: undefined function "f"`, ""},
+	{`function f(a::b) {}  # !awk !gawk`, "", "", `parse error at 1:12: can't use qualified name "a::b" as parameter name`, ""},
+	{`@namespace "for"  # !awk !gawk`, "", "", `parse error at 1:12: invalid namespace name "for"`, ""},
+	{`@namespace "a::b"  # !awk !gawk`, "", "", `parse error at 1:12: invalid namespace name "a::b"`, ""},
+	{`BEGIN { @namespace "ns" }  # !awk !gawk`, "", "", `parse error at 1:9: expected expression instead of @namespace`, ""},
+
 	// Type checking / resolver tests
 	{`BEGIN { a[x]; a=42 }`, "", "", `parse error at 1:15: can't use array "a" as scalar`, "array"},
 	{`BEGIN { s=42; s[x] }`, "", "", `parse error at 1:15: can't use scalar "s" as array`, "array"},
@@ -950,6 +1130,14 @@ BEGIN { x[1]=3; f5(x); print x[1] }
 	{`BEGIN { print >"out"; close("out"); getline <"out"; print >"out" }  # !awk !gawk`, "", "", "can't write to reader stream", ""},
 	{`BEGIN { print >"out"; close("out"); getline <"out"; print |"out" }  # !awk !gawk`, "", "", "can't write to reader stream", ""},
 
+	// Non-fatal I/O errors set ERRNO (gawk's messages are capitalized)
+	{`BEGIN { print (getline x <"nonexistent"), ERRNO }  # !awk !gawk`, "", "-1 no such file or directory\n", "", ""},
+	{`BEGIN { print (getline x <"."), ERRNO }  # !awk !gawk`, "", "-1 is a directory\n", "", ""},
+	{`BEGIN { PROCINFO["NONFATAL"]; print "x" >"nonexistent/out"; print ERRNO; print "done" }  # !awk !gawk`, "", "no such file or directory\ndone\n", "", ""},
+	{`BEGIN { f="nonexistent/out"; PROCINFO[f, "NONFATAL"]=1; printf "x" >f; print ERRNO }  # !awk !gawk`, "", "no such file or directory\n", "", ""},
+	{`BEGIN { print close("never-opened"), ERRNO }  # !awk`, "", "-1 close of redirection that was never opened\n", "", ""},
+	{`BEGIN { PROCINFO["other", "NONFATAL"]=1; print "x" >"nonexistent/out" }  # !awk !gawk`, "", "", "output redirection error: open nonexistent/out: no such file or directory", ""},
+
 	// The value of close() on a pipe emulates gawk behavior. Results are identical for both
 	// input and output. Windows does not do POSIX signals. Windows gawk uses cmd.exe, not sh.exe.
 	{`BEGIN { cmd="read FOO; exit 9"; print "" |cmd; print close(cmd) } # !awk !posix !windows-gawk`, "", "9\n", "", ""},
@@ -957,6 +1145,15 @@ BEGIN { x[1]=3; f5(x); print x[1] }
 	{`BEGIN { cmd="exit 9"; cmd |getline; print close(cmd) } # !awk !posix`, "", "9\n", "", ""},
 	{`BEGIN { cmd="exec /bin/kill -9 $$"; cmd |getline; print close(cmd) } # !awk !posix !windows`, "", "265\n", "", ""},
 
+	// Two-way coprocesses with |&
+	{`BEGIN { c="sort"; print "b" |& c; print "a" |& c; close(c, "to"); while ((c |& getline x) > 0) print x; print close(c) }  # !awk !posix`, "", "a\nb\n0\n", "", ""},
+	{`BEGIN { c="cat"; print "foo" |& c; c |& getline; print; printf "%s\n", "bar" |& c; c |& getline x; print x }  # !awk !posix`, "", "foo\nbar\n", "", ""},
+	{`BEGIN { c="cat"; print "x" |& c; c |& getline; print close(c, "to"); print close(c, "from"); print }  # !awk !posix`, "", "0\n0\nx\n", "", ""},
+	{`BEGIN { c="read x; exit 7"; print "" |& c; close(c, "to"); print close(c) }  # !awk !posix`, "", "7\n", "", ""},
+	{`BEGIN { close("x", "both") }  # !awk !gawk`, "", "", `close: second argument must be "to" or "from", got "both"`, ""},
+	{`BEGIN { print |& "cat"; print |"cat" }  # !awk !gawk`, "", "", `can't redirect to coprocess "cat" without |&`, ""},
+	{`BEGIN { print |"cat"; "cat" |& getline }  # !awk !gawk`, "", "", "can't use writer stream as coprocess", ""},
+
 	// Redirecting to or from a filename of "-" means write to stdout or read from stdin
 	{`BEGIN { print getline x < "-"; print x }`, "a\nb\n", "1\na\n", "", ""},
 	{`{ print $0; print getline x <"-"; print x }`, "one\ntwo\n", "one\n0\n\ntwo\n0\n\n", "", ""},
@@ -1552,6 +1749,29 @@ BEGIN { x=4; y=5; print foo(x), bar(y) }
 			map[string]any{
 				"foo": func(i int) int { return i },
 			}},
+		{`BEGIN { f = "repeat"; print @f("ab", 3); f = "sum"; print @f(), @f(1, 2, 3) }`, "", "ababab\n0 6\n", "",
+			map[string]any{
+				"repeat": strings.Repeat,
+				"sum": func(args ...int) int {
+					sum := 0
+					for _, a := range args {
+						sum += a
+					}
+					return sum
+				},
+			}},
+		{`function foo(n) { return "awk" }  BEGIN { f = "foo"; print @f(1) }`, "", "awk\n", "",
+			map[string]any{
+				"foo": func(n int) string { return "native" },
+			}},
+		{`BEGIN { f = "foo"; print @f(1, 2) }`, "", "", `"foo" called with more arguments than declared`,
+			map[string]any{
+				"foo": func(n int) int { return n },
+			}},
+		{`@namespace "ns"; function foo(n) { return "awk" }  BEGIN { print foo(1), awk::foo(2) }`, "", "awk 4\n", "",
+			map[string]any{
+				"foo": func(n int) int { return n * n },
+			}},
 	}
 	for _, test := range tests {
 		testName := test.src
@@ -1579,7 +1799,11 @@ func TestSafeMode(t *testing.T) {
 		{`$0  # no files`, "1\n2\n", "1\n2\n", "", nil},
 		{`$0  # files`, "1\n2\n", "1\n2\n", "can't read from file due to NoFileReads", []string{"f1"}},
 		{`BEGIN { "echo foo" |getline }`, "", "", "can't read from pipe due to NoExec", nil},
+		{`BEGIN { print "hi" |& "sort" }`, "", "", "can't start coprocess due to NoExec", nil},
+		{`BEGIN { "echo foo" |& getline }`, "", "", "can't start coprocess due to NoExec", nil},
 		{`BEGIN { system("echo foo") }`, "", "", "can't call system() due to NoExec", nil},
+		{`BEGIN { print "hi" |& "/inet/tcp/0/localhost/80" }`, "", "", "can't open network connection unless AllowNetwork is set", nil},
+		{`BEGIN { getline <"/inet/udp/0/localhost/53" }`, "", "", "can't open network connection unless AllowNetwork is set", nil},
 	}
 	for _, test := range tests {
 		testName := test.src
@@ -1597,6 +1821,132 @@ func TestSafeMode(t *testing.T) {
 	}
 }
 
+func TestNonFatalIO(t *testing.T) {
+	tests := []struct {
+		src  string
+		out  string
+		args []string
+	}{
+		{`BEGIN { print "x" >"nonexistent/out"; print ERRNO }`, "no such file or directory\n", nil},
+		{`BEGIN { print "x" |& "/inet/tcp/0/127.0.0.1/1"; print ERRNO != "" }`, "1\n", nil},
+		{`BEGIN { print ("/inet/tcp/0/127.0.0.1/1" |& getline), ERRNO != "" }`, "-1 1\n", nil},
+		{`{ print FILENAME, $0 } END { print ERRNO }`, "error opening \"nonexistent\": no such file or directory\n" +
+			"- foo\nno such file or directory\n", []string{"nonexistent", "-"}},
+	}
+	for _, test := range tests {
+		t.Run(test.src, func(t *testing.T) {
+			testGoAWK(t, test.src, "foo\n", test.out, "", nil, func(config *interp.Config) {
+				config.Args = test.args
+				config.NonFatalIO = true
+				config.AllowNetwork = true
+			})
+		})
+	}
+
+	if runtime.GOOS != "windows" {
+		t.Run("close error", func(t *testing.T) {
+			// The command closes its stdin before replying, so flushing the
+			// output on close fails even though the command exits normally.
+			src := `BEGIN { s = "exec 0<&-; echo ready"; s |& getline; printf "x" |& s; print close(s), ERRNO }`
+			testGoAWK(t, src, "", "-1 broken pipe\n", "", nil, func(config *interp.Config) {
+				config.NonFatalIO = true
+			})
+		})
+	}
+}
+
+func TestNetwork(t *testing.T) {
+	// Echo server for the script to connect to as a TCP client
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, _ = io.Copy(conn, conn)
+				conn.Close()
+			}()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	tests := []struct {
+		src string
+		out string
+		err string
+	}{
+		{`BEGIN { s = "/inet/tcp/0/127.0.0.1/` + port + `"; print "hello" |& s; s |& getline x; print x; print close(s) }`, "hello\n0\n", ""},
+		{`BEGIN { s = "/inet4/tcp/0/127.0.0.1/` + port + `"; print "a" >s; print "b" >s; close(s, "to"); while ((getline x <s) > 0) print x }`, "a\nb\n", ""},
+		{`BEGIN { s = "/inet/tcp/0/127.0.0.1/` + port + `"; print "c" |& s; close(s, "to"); while ((s |& getline x) > 0) print x; print close(s) }`, "c\n0\n", ""},
+		{`BEGIN { print |& "/inet/tcp/0/127.0.0.1" }`, "", `invalid network special file "/inet/tcp/0/127.0.0.1"`},
+		{`BEGIN { print |& "/inet/tcp/0/127.0.0.1/0" }`, "", `invalid network special file "/inet/tcp/0/127.0.0.1/0"`},
+		{`BEGIN { print |& "/inet/raw/0/127.0.0.1/80" }`, "", `invalid network protocol "raw" in "/inet/raw/0/127.0.0.1/80"`},
+	}
+	allowNetwork := func(config *interp.Config) {
+		config.AllowNetwork = true
+	}
+	for _, test := range tests {
+		t.Run(test.src, func(t *testing.T) {
+			testGoAWK(t, test.src, "", test.out, test.err, nil, allowNetwork)
+		})
+	}
+
+	t.Run("server", func(t *testing.T) {
+		// Find a free port for the script to listen on
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, port, _ := net.SplitHostPort(l.Addr().String())
+		l.Close()
+
+		replies := make(chan string, 1)
+		go func() {
+			for i := 0; i < 100; i++ {
+				conn, err := net.Dial("tcp", "127.0.0.1:"+port)
+				if err != nil {
+					time.Sleep(10 * time.Millisecond)
+					continue
+				}
+				fmt.Fprintln(conn, "ping")
+				reply, _ := io.ReadAll(conn)
+				conn.Close()
+				replies <- string(reply)
+				return
+			}
+			replies <- "couldn't connect"
+		}()
+		src := `BEGIN { s = "/inet/tcp/` + port + `/0/0"; s |& getline x; print "got " x |& s; close(s); print x }`
+		testGoAWK(t, src, "", "ping\n", "", nil, allowNetwork)
+		if reply := <-replies; reply != "got ping\n" {
+			t.Fatalf("expected reply %q, got %q", "got ping\n", reply)
+		}
+	})
+
+	t.Run("disallowed", func(t *testing.T) {
+		src := `BEGIN { print "x" |& "/inet/tcp/0/127.0.0.1/` + port + `" }`
+		err := interp.Exec(src, " ", nil, nil)
+		if err == nil || err.Error() != "can't open network connection unless AllowNetwork is set" {
+			t.Fatalf("expected AllowNetwork error, got %v", err)
+		}
+		testGoAWK(t, src, "", "", "can't open network connection unless AllowNetwork is set", nil, nil)
+		testGoAWK(t, src, "", "", "can't open network connection due to NoFileReads", nil, func(config *interp.Config) {
+			config.AllowNetwork = true
+			config.NoFileReads = true
+		})
+		testGoAWK(t, src, "", "", "can't open network connection due to NoFileWrites", nil, func(config *interp.Config) {
+			config.AllowNetwork = true
+			config.NoFileWrites = true
+		})
+	})
+}
+
 func TestConfigVarsCorrect(t *testing.T) {
 	prog, err := parser.ParseProgram([]byte(`BEGIN { print x }`), nil)
 	if err != nil {
@@ -1856,6 +2206,52 @@ BEGIN {
 	})
 }
 
+func TestSortedIn(t *testing.T) {
+	src := `BEGIN { a["b"]=1; a["c"]=3; a["a"]=2; for (k in a) printf "%s ", k; print "" }`
+	testGoAWK(t, src, "", "b a c \n", "", nil, func(config *interp.Config) {
+		config.SortedIn = "@val_num_asc"
+	})
+	testGoAWK(t, src, "", "c b a \n", "", nil, func(config *interp.Config) {
+		config.SortedIn = "@ind_str_desc"
+	})
+	testGoAWK(t, src, "", "", `invalid sort order "@bad"`, nil, func(config *interp.Config) {
+		config.SortedIn = "@bad"
+	})
+}
+
+func TestNow(t *testing.T) {
+	now := func() time.Time {
+		return time.Date(2024, 3, 5, 14, 7, 9, 0, time.UTC)
+	}
+	src := `BEGIN { t = systime(); print t, strftime("%Y-%m-%d %H:%M:%S", t, 1); srand(); print srand() }`
+	testGoAWK(t, src, "", "1709647629 2024-03-05 14:07:09\n1709647629\n", "", nil, func(config *interp.Config) {
+		config.Now = now
+	})
+
+	src = `BEGIN { print strftime() == strftime(PROCINFO["strftime"]); PROCINFO["strftime"] = "%Y"; print strftime() }`
+	testGoAWK(t, src, "", "1\n2024\n", "", nil, func(config *interp.Config) {
+		config.Now = now
+	})
+}
+
+func TestMktimeDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("can't load time zone: %v", err)
+	}
+	oldLocal := time.Local
+	time.Local = loc
+	defer func() { time.Local = oldLocal }()
+
+	src := `BEGIN {
+	print mktime("2024 07 01 12 00 00 0") - mktime("2024 07 01 12 00 00 1")
+	print mktime("2024 07 01 12 00 00 -1") - mktime("2024 07 01 12 00 00")
+	print mktime("2024 01 01 12 00 00 1") - mktime("2024 01 01 12 00 00 0")
+	print mktime("2024 01 01 12 00 00 -1") - mktime("2024 01 01 12 00 00")
+}`
+	testGoAWK(t, src, "", "3600\n0\n-3600\n0\n", "", nil, nil)
+}
+
 func TestExit(t *testing.T) {
 	tests := []struct {
 		src    string
@@ -1969,6 +2365,13 @@ var newlineTests = []newlineTest{{
 		config.OutputMode = interp.CSVMode
 		config.NewlineOutput = interp.CRLFNewlineMode
 	},
+}, {
+	`BEGIN { print 4, "a\r\nb", "c\nd" }`, "", "\"4\",\"a\r\nb\",\"c\r\nd\"\r\n", "",
+	func(config *interp.Config) {
+		config.OutputMode = interp.CSVMode
+		config.CSVOutput.Quote = interp.CSVQuoteAlways
+		config.NewlineOutput = interp.CRLFNewlineMode
+	},
 }}
 
 func TestNewline(t *testing.T) {
@@ -2085,11 +2488,114 @@ var csvTests = []csvTest{
 	{`BEGIN { INPUTMODE="csv header" } NR==1 { for (i=1; i in FIELDS; i++) print i, FIELDS[i] }`, "name,email,age\na,b,c", "1 name\n2 email\n3 age\n", "", nil},
 	{`BEGIN { INPUTMODE="csv" } NR==1 { for (i=1; i in FIELDS; i++) print FIELDS[i] }`, "name,email,age\na,b,c", "", "", nil},
 
+	// Explicit header field names
+	{`BEGIN { INPUTMODE="csv header=name,age" } { print @"age", @"name", FIELDS[2] }`, "Bob,42\nJane,37", "42 Bob age\n37 Jane age\n", "", nil},
+	{`BEGIN { INPUTMODE="tsv header=x" } { print NR, @"x" }`, "a\nb", "1 a\n2 b\n", "", nil},
+	{`BEGIN { INPUTMODE="csv header=true header=a,b" } { print @"b", @"a" }`, "x,y\n1,2", "2 1\n", "", nil},
+	{`BEGIN { INPUTMODE="csv header=a,b"; getline; print @"b" }`, "1,2", "2\n", "", nil},
+	{`BEGIN { INPUTMODE="csv header=a,b" } { print @"c" }`, "1,2", "\n", "", nil},
+	{`{ print @"b" }`, "1,2\n3,4", "2\n4\n", "", func(config *interp.Config) {
+		config.InputMode = interp.CSVMode
+		config.CSVInput.FieldNames = []string{"a", "b"}
+	}},
+	{`{ print @"b" }`, "x,y\n1,2", "2\n", "", func(config *interp.Config) {
+		config.InputMode = interp.CSVMode
+		config.CSVInput.Header = true
+		config.CSVInput.FieldNames = []string{"a", "b"}
+	}},
+
+	// Strict parsing and error handling
+	{`BEGIN { INPUTMODE="csv strict" } { print $2 }`, "a,b\n1,2\n3,4", "b\n2\n4\n", "", nil},
+	{`BEGIN { INPUTMODE="csv strict" } { print $2 }`, "a,b\n1,2\n3\n5,6", "b\n2\n", "error reading from input: -:3: wrong number of fields (expected 2, got 1)", nil},
+	{`BEGIN { INPUTMODE="csv strict" } { print $2 }`, "a,b\n1,x\"y", "b\n", `error reading from input: -:2: bare " in non-quoted field`, nil},
+	{`BEGIN { INPUTMODE="csv strict" } { print $2 }`, "a,\"b\"c\n", "", `error reading from input: -:1: extraneous or missing " in quoted field`, nil},
+	{`BEGIN { INPUTMODE="csv strict" } { print $2 }`, "a,b\n\"x\ny\",\"z", "b\n", `error reading from input: -:2: extraneous or missing " in quoted field`, nil},
+	{`BEGIN { INPUTMODE="csv strict" } { print $1 }`, "a,b\n\"x\ny\",z\n\"q\"r,1", "a\nx\ny\n", `error reading from input: -:4: extraneous or missing " in quoted field`, nil},
+	{`BEGIN { INPUTMODE="csv strict onerror=skip" } { print NR, $2 }`, "a,b\n1\n\"2\"x,y\n3,4", "1 b\nskipping row: -:2: wrong number of fields (expected 2, got 1)\nskipping row: -:3: extraneous or missing \" in quoted field\n2 4\n", "", nil},
+	{`BEGIN { INPUTMODE="csv strict onerror=report" } { print NR, $2, CSVERROR }`, "a,b\n1\n3,4", "1 b \n2  -:2: wrong number of fields (expected 2, got 1)\n3 4 \n", "", nil},
+	{`BEGIN { INPUTMODE="csv header strict onerror=report" } { print @"b", CSVERROR }`, "a,b\n1,2,3", "2 -:2: wrong number of fields (expected 2, got 3)\n", "", nil},
+	{`BEGIN { INPUTMODE="csv strict onerror=report" } { print $1 "|" $2 "|" CSVERROR }`, "\"a\"b,c\nd,e", "a\"b,c||-:1: extraneous or missing \" in quoted field\nd|e|\n", "", nil},
+	{`BEGIN { INPUTMODE="csv header strict onerror=skip" } { print @"b" }`, "a,\"b\n1,2", "", `error reading from input: -:1: extraneous or missing " in quoted field`, nil},
+	{`BEGIN { INPUTMODE="csv fields=2" } { print $2 }`, "a,b\n1,2\n3", "b\n2\n", "error reading from input: -:3: wrong number of fields (expected 2, got 1)", nil},
+	{`BEGIN { INPUTMODE="csv strict fields=-1" } { print NF }`, "a,b\n1\n3,4,5", "2\n1\n3\n", "", nil},
+	{`BEGIN { INPUTMODE="csv fields=1" } { print $1 }`, "x\"y", "x\"y\n", "", nil},
+	{`{ print NR, CSVERROR }`, "a,b\n1,2,3", "1 \n2 -:2: wrong number of fields (expected 2, got 3)\n", "", func(config *interp.Config) {
+		config.InputMode = interp.CSVMode
+		config.CSVInput.Strict = true
+		config.CSVInput.OnError = interp.CSVErrorReport
+	}},
+	{`{ print NR, $2 }`, "a,b\n1\n3,4\n", "1 b\nskipping row: -:2: wrong number of fields (expected 2, got 1)\n2 4\n", "", func(config *interp.Config) {
+		// Ensure rows after a skipped row are read when EOF is seen early
+		config.Stdin = iotest.DataErrReader(strings.NewReader("a,b\n1\n3,4\n"))
+		config.InputMode = interp.CSVMode
+		config.CSVInput.Strict = true
+		config.CSVInput.OnError = interp.CSVErrorSkip
+	}},
+	{`{}`, "", "", "invalid CSV error mode 3", func(config *interp.Config) {
+		config.InputMode = interp.CSVMode
+		config.CSVInput.OnError = 3
+	}},
+
+	// Output quoting policy, quote and escape characters
+	{`BEGIN { OUTPUTMODE="csv quote=always"; print "a", 1, "", "x,y" }`, "", "\"a\",\"1\",\"\",\"x,y\"\n", "", nil},
+	{`BEGIN { OUTPUTMODE="csv quote=nonnumeric"; print "a", 1, -2.5, "1e3", "1x", "", " 3" }`, "", "\"a\",1,-2.5,1e3,\"1x\",\"\",\" 3\"\n", "", nil},
+	{`BEGIN { OUTPUTMODE="csv quote=never"; print "a b", "x,y", "\"q\"" }`, "", "a b,x,y,\"q\"\n", "", nil},
+	{`BEGIN { OUTPUTMODE="csv quote=never escape=\\"; print "x,y", "\"q\"", "a\\b" }`, "", "x\\,y,\\\"q\\\",a\\\\b\n", "", nil},
+	{`BEGIN { OUTPUTMODE="csv quotechar='"; print "x,y", "it's", "\"q\"", "a" }`, "", "'x,y','it''s',\"q\",a\n", "", nil},
+	{`BEGIN { OUTPUTMODE="csv escape=\\"; print "x\"y", "a\\b", "c" }`, "", "\"x\\\"y\",\"a\\\\b\",c\n", "", nil},
+	{`BEGIN { OUTPUTMODE="tsv quote=always"; print "a", "b\nc" }`, "", "\"a\"\t\"b\nc\"\n", "", nil},
+	{`BEGIN { OUTPUTMODE=INPUTMODE="csv quotechar=|" } { $1 = $1 "!"; print; print $1, $2 }`, "|a,b|,c", "|a,b!|,c\n|a,b!|,c\n", "", nil},
+	{`BEGIN { INPUTMODE="csv quotechar='" } { print $1 "|" $2 "|" NF }`, "'a,b',c\n'it''s',\"q\"", "a,b|c|2\nit's|\"q\"|2\n", "", nil},
+	{`BEGIN { INPUTMODE="csv quotechar=' strict" } { print $2 }`, "a,b\"c", "b\"c\n", "", nil},
+	{`{ print $2; print "x y", 1 }`, "'a,b','c d'", "\"c d\"\n\"x y\",1\n", "", func(config *interp.Config) {
+		config.InputMode = interp.CSVMode
+		config.CSVInput.QuoteChar = '\''
+		config.OutputMode = interp.CSVMode
+		config.CSVOutput.Quote = interp.CSVQuoteNonNumeric
+	}},
+	{`BEGIN { printf "%s", OUTPUTMODE }`, "", "csv quote=never escape=\\", "", func(config *interp.Config) {
+		config.OutputMode = interp.CSVMode
+		config.CSVOutput.Quote = interp.CSVQuoteNever
+		config.CSVOutput.Escape = '\\'
+	}},
+	{`{}`, "", "", "invalid CSV quote mode 4", func(config *interp.Config) {
+		config.OutputMode = interp.CSVMode
+		config.CSVOutput.Quote = 4
+	}},
+
+	// printrow() and OFIELDS
+	{`BEGIN { OUTPUTMODE="csv"; OFIELDS[1]="name"; OFIELDS[2]="age"; a["age"]=42; a["name"]="Bob, Jr"; a["x"]=1; printrow(a) }`, "", "\"Bob, Jr\",42\n", "", nil},
+	{`BEGIN { OUTPUTMODE="csv header"; OFIELDS[1]="name"; OFIELDS[2]="age"; a["name"]="Bob"; a["age"]=42; printrow(a); a["name"]="Jo"; delete a["age"]; print printrow(a) }`, "", "name,age\nBob,42\nJo,\n2\n", "", nil},
+	{`BEGIN { OUTPUTMODE="tsv"; f[1]="b"; f[2]="a"; OFIELDS[1]="x"; a["a"]=1; a["b"]=2; printrow(a, f) }`, "", "2\t1\n", "", nil},
+	{`BEGIN { OUTPUTMODE="csv header"; a["b"]=2; a["a"]=1; a["c"]=3; printrow(a) }`, "", "a,b,c\n1,2,3\n", "", nil},
+	{`BEGIN { OUTPUTMODE="csv"; a[10]="x"; a[9]="y"; a[1]="z"; printrow(a); n=split("a b c", s); printrow(s) }`, "", "z,y,x\na,b,c\n", "", nil},
+	{`BEGIN { OUTPUTMODE="csv"; a["x"][1]=1; a["x"][2]=2; f["x"][1]=2; printrow(a["x"]); printrow(a["x"], f["x"]) }`, "", "1,2\n2\n", "", nil},
+	{`BEGIN { OFS="-"; a["x"]=1; a["y"]=2; printrow(a) }`, "", "1-2\n", "", nil},
+	{`BEGIN { OUTPUTMODE="jsonl"; a["name"]="Bob"; a["age"]=42; printrow(a) }`, "", `{"age":42,"name":"Bob"}` + "\n", "", nil},
+	{`BEGIN { printrow(a) }`, "", "\n", "", nil},
+	{`BEGIN { OUTPUTMODE="csv header"; a["x"]=1; printrow(a) | "cat"; printrow(a) | "cat"; close("cat"); printrow(a); printrow(a) > "-" }`, "", "x\n1\n1\nx\n1\n1\n", "", nil},
+	{`BEGIN { OUTPUTMODE="csv header"; a["x"]=1; printrow(a) | "cat"; close("cat"); printrow(a) | "cat" }`, "", "x\n1\nx\n1\n", "", nil},
+	{`BEGIN { OUTPUTMODE="jsonl"; a["x"]=1; printrow(a) > "/dev/stderr" }`, "", `{"x":1}` + "\n", "", nil},
+	{`BEGIN { OUTPUTMODE="csv"; a["x"]=3; n = printrow(a) > 0; printrow(a) >> "-" }`, "", "3\n3\n", "", nil},
+	{`BEGIN { OUTPUTMODE="csv"; a["x"]=1; a["y"][1]=2; printrow(a) }`, "", "", `can't use subarray "y" as scalar`, nil},
+	{`BEGIN { OUTPUTMODE="csv"; a["x"]=1; f[1]="x"; f[2][1]="y"; printrow(a, f) }`, "", "", `can't use subarray "2" as scalar`, nil},
+	{`BEGIN { x=1; printrow(x) }`, "", "", "parse error at 1:23: can't use scalar \"x\" as array", nil},
+
 	// Parsing and formatting of INPUTMODE and OUTPUTMODE special variables
 	{`BEGIN { INPUTMODE="csv separator=,"; print INPUTMODE }`, "", "csv\n", "", nil},
 	{`BEGIN { INPUTMODE="csv header=true comment=# separator=|"; print INPUTMODE }`, "", "csv separator=| comment=# header\n", "", nil},
+	{`BEGIN { INPUTMODE="csv header=name,age separator=;"; print INPUTMODE }`, "", "csv separator=; header=name,age\n", "", nil},
+	{`BEGIN { INPUTMODE="csv header=a,b header=false"; print INPUTMODE }`, "", "csv\n", "", nil},
+	{`BEGIN { INPUTMODE="csv header header=a,b"; print INPUTMODE }`, "", "csv header header=a,b\n", "", nil},
+	{`BEGIN { INPUTMODE="tsv strict=true fields=3 onerror=report"; print INPUTMODE }`, "", "tsv strict fields=3 onerror=report\n", "", nil},
+	{`BEGIN { INPUTMODE="csv strict=false onerror=skip onerror=abort"; print INPUTMODE }`, "", "csv\n", "", nil},
 	{`BEGIN { OUTPUTMODE="csv separator=,"; printf "%s", OUTPUTMODE }`, "", "csv", "", nil},
 	{`BEGIN { OUTPUTMODE="csv separator=|"; printf "%s", OUTPUTMODE }`, "", "csv separator=|", "", nil},
+	{`BEGIN { OUTPUTMODE="tsv header=true"; printf "%s", OUTPUTMODE }`, "", "tsv header", "", nil},
+	{`BEGIN { OUTPUTMODE="csv header=x" }`, "", "", `invalid header value "x"`, nil},
+	{`BEGIN { OUTPUTMODE="csv quote=nonnumeric quotechar=' escape=\\"; printf "%s", OUTPUTMODE }`, "", "csv quote=nonnumeric quotechar=' escape=\\", "", nil},
+	{`BEGIN { OUTPUTMODE="csv quote=always quote=minimal quotechar=\""; printf "%s", OUTPUTMODE }`, "", "csv", "", nil},
+	{`BEGIN { INPUTMODE="tsv quotechar='"; print INPUTMODE }`, "", "tsv quotechar='\n", "", nil},
 
 	// Ignores UTF-8 byte order mark (BOM) at start of CSV file
 	{`BEGIN { INPUTMODE="csv" } { print $1=="foo" }`, "\ufefffoo,bar\n\ufefffoo,bar", "1\n0\n", "", nil},
@@ -2123,17 +2629,108 @@ BEGIN {
 	{`BEGIN { INPUTMODE="xyz" }`, "", "", `invalid input mode "xyz"`, nil},
 	{`BEGIN { INPUTMODE="csv separator=foo" }`, "", "", `invalid CSV/TSV separator "foo"`, nil},
 	{`BEGIN { INPUTMODE="csv comment=bar" }`, "", "", `invalid CSV/TSV comment character "bar"`, nil},
-	{`BEGIN { INPUTMODE="csv header=x" }`, "", "", `invalid header value "x"`, nil},
+	{`BEGIN { INPUTMODE="csv strict=x" }`, "", "", `invalid strict value "x"`, nil},
+	{`BEGIN { INPUTMODE="csv quotechar=ab" }`, "", "", `invalid CSV/TSV quote character "ab"`, nil},
+	{`BEGIN { INPUTMODE="csv quotechar=," }`, "", "", `invalid CSV quote or escape character`, nil},
+	{`BEGIN { OUTPUTMODE="csv quote=some" }`, "", "", `invalid quote value "some"`, nil},
+	{`BEGIN { OUTPUTMODE="csv quotechar=" }`, "", "", `invalid CSV/TSV quote character ""`, nil},
+	{`BEGIN { OUTPUTMODE="csv escape=xy" }`, "", "", `invalid CSV/TSV escape character "xy"`, nil},
+	{`BEGIN { OUTPUTMODE="csv separator=| escape=|" }`, "", "", `invalid CSV quote or escape character`, nil},
+	{`BEGIN { INPUTMODE="csv fields=x" }`, "", "", `invalid fields value "x"`, nil},
+	{`BEGIN { INPUTMODE="csv onerror=ignore" }`, "", "", `invalid onerror value "ignore"`, nil},
 	{`BEGIN { INPUTMODE="csv foo=bar" }`, "", "", `invalid input mode key "foo"`, nil},
 	{`BEGIN { OUTPUTMODE="xyz" }`, "", "", `invalid output mode "xyz"`, nil},
 	{`BEGIN { OUTPUTMODE="csv separator=foo" }`, "", "", `invalid CSV/TSV separator "foo"`, nil},
 	{`BEGIN { OUTPUTMODE="csv foo=bar" }`, "", "", `invalid output mode key "foo"`, nil},
 
-	// Other errors
-	{`BEGIN { @"x" = "y" }`, "", "", "parse error at 1:14: assigning @ expression not supported", nil},
-	{`BEGIN { x="a"; @x = "y" }`, "", "", "parse error at 1:19: assigning @ expression not supported", nil},
-	{`BEGIN { @"x" += "y" }`, "", "", "parse error at 1:14: assigning @ expression not supported", nil},
-	{`BEGIN { x="a"; @x += "y" }`, "", "", "parse error at 1:19: assigning @ expression not supported", nil},
+	// Named field assignment
+	{`BEGIN { INPUTMODE=OUTPUTMODE="csv header" } { @"id" = 42; print }`, "id,name\n1,Bob\n2,Jo", "42,Bob\n42,Jo\n", "", nil},
+	{`BEGIN { INPUTMODE=OUTPUTMODE="csv header" } { @"total" += $3; x="n"; @x++; print }`, "n,total,x\n1,10,5\n2,20,7", "2,15,5\n3,27,7\n", "", nil},
+	{`BEGIN { INPUTMODE="csv header" } { print ++@"n", @"n"--, @"n", (@"n" *= 10), (@"s" = "x") }`, "n,s\n1,y", "2 2 1 10 x\n", "", nil},
+	{`BEGIN { INPUTMODE=OUTPUTMODE="csv header" } { @"new" = $1 $2; print; print NF, FIELDS[3] }`, "a,b\n1,2\n3,4", "1,2,12\n3,new\n3,4,34\n3,new\n", "", nil},
+	{`BEGIN { INPUTMODE=OUTPUTMODE="csv header" } { @"c"++; print }`, "a,b\n1\n1,2,3", "1,,1\n1,2,4\n", "", nil},
+	{`BEGIN { INPUTMODE=OUTPUTMODE="csv header" } { @"d" = "x"; print; for (i=1; i<=NF; i++) printf "%s|", FIELDS[i]; print "" }`, "a,b\n1,2,3", "1,2,3,x\na|b||d|\n", "", nil},
+	{`BEGIN { INPUTMODE="jsonl" } { @"b" = @"a" + 1; print NF, @"b", FIELDS[NF] }`, `{"a":1}` + "\n" + `{"a":5,"c":0}`, "2 2 b\n3 6 b\n", "", nil},
+	{`BEGIN { @"x" = "y" }`, "", "", "no field names for @; use -H or add \"header\" to INPUTMODE, and use \"getline\" first if in BEGIN", nil},
+	{`BEGIN { x="a"; @x += "y" }`, "", "", "no field names for @; use -H or add \"header\" to INPUTMODE, and use \"getline\" first if in BEGIN", nil},
+	{`{ @"x"++ }`, "a b", "", "no field names for @; use -H or add \"header\" to INPUTMODE, and use \"getline\" first if in BEGIN", nil},
+
+	// JSON Lines input mode
+	{`{ print NF, $2, @"id" }`, `{"id": 1, "name": "Bob"}` + "\n" + `{"id":2,"name":"J\u00f6"}`, "2 Bob 1\n2 Jö 2\n", "", jsonlInput},
+	{`{ print }`, `{"id": 1, "name": "Bob"}` + "\n" + `{"id":2}`, `{"id": 1, "name": "Bob"}` + "\n" + `{"id":2}` + "\n", "", jsonlInput},
+	{`{ print @"user.id", @"user.tags.2", @"empty", NF }`, `{"user":{"id":42,"tags":["a","b"]},"empty":[]}`, "42 b [] 4\n", "", jsonlInput},
+	{`{ for (i=1; i in FIELDS; i++) print i, FIELDS[i], $i }`, `{"a":true,"b":false,"c":null,"d":1.5e3}`, "1 a 1\n2 b 0\n3 c \n4 d 1.5e3\n", "", jsonlInput},
+	{`{ print @"d" + 1, (@"d" < 2) }`, `{"d":1.5e3}`, "1501 0\n", "", jsonlInput},
+	{`{ print NR, NF, @"x" }`, `{"x":1}` + "\n\n" + `{"y":2}` + "\n{}\n", "1 1 1\n2 1 \n3 0 \n", "", jsonlInput},
+	{`{ $0 = "{\"b\":2}"; print @"b", NF, FIELDS[1] }`, `{"a":1}`, "2 1 b\n", "", jsonlInput},
+	{`NR==1 { getline line; print $1, @"a", NF, FIELDS[1], line }`, `{"a":"main1"}` + "\n" + `{"b":"x","c":2}`, `main1 main1 1 a {"b":"x","c":2}` + "\n", "", jsonlInput},
+	{`{ "echo {}" | getline line; print $1, @"a", NF, line }`, `{"a":"main1"}`, "main1 main1 1 {}\n", "", jsonlInput},
+	{`{ print @"user" }`, `{"user": {"id": 42, "tags": ["a", "b"]}}`, `{"id":42,"tags":["a","b"]}` + "\n", "", func(config *interp.Config) {
+		config.InputMode = interp.JSONLMode
+		config.JSONFlatten = "none"
+	}},
+	{`{ print @"user_tags_1" }`, `{"user": {"tags": ["a", "b"]}}`, "a\n", "", func(config *interp.Config) {
+		config.InputMode = interp.JSONLMode
+		config.JSONFlatten = "_"
+	}},
+	{`BEGIN { INPUTMODE="jsonl"; print INPUTMODE } { print @"a" }`, `{"a":"x"}`, "jsonl\nx\n", "", nil},
+	{`BEGIN { INPUTMODE="jsonl flatten=/"; print INPUTMODE } { print @"a/b" }`, `{"a":{"b":"y"}}`, "jsonl flatten=/\ny\n", "", nil},
+	{`BEGIN { INPUTMODE="jsonl"; getline; print @"a" }`, `{"a":"x"}`, "x\n", "", nil},
+	{`BEGIN { INPUTMODE="jsonl"; print @"a" }`, "", "", `no field names for @; use "getline" first if in BEGIN`, nil},
+	{`BEGIN { INPUTMODE="jsonl flatten=" }`, "", "", `invalid JSON flatten value ""`, nil},
+	{`BEGIN { INPUTMODE="jsonl header" }`, "", "", `invalid input mode key "header"`, nil},
+	{`{}`, `{"a":1}` + "\n" + `{"a":`, "", "error reading from input: -:2: invalid JSON: unexpected EOF", jsonlInput},
+	{`{}`, `[1, 2]`, "", "error reading from input: -:1: invalid JSON: record is not a JSON object", jsonlInput},
+	{`{}`, `{"a":1} {"a":2}`, "", "error reading from input: -:1: invalid JSON: unexpected data after JSON object", jsonlInput},
+	{`{}`, "", "", "JSON flattening only valid in JSON Lines input mode", func(config *interp.Config) {
+		config.JSONFlatten = "_"
+	}},
+	{`{}`, "", "", "CSV input configuration not valid in JSON Lines input mode", func(config *interp.Config) {
+		config.InputMode = interp.JSONLMode
+		config.CSVInput.Header = true
+	}},
+	{`{}`, "", "", "JSON mode not valid for input", func(config *interp.Config) {
+		config.InputMode = interp.JSONMode
+	}},
+
+	// JSON output modes
+	{`BEGIN { print "a", 1, 2.5, x, -3 }`, "", `["a",1,2.5,null,-3]` + "\n", "", jsonlOutput},
+	{`BEGIN { print "q\"b\\s\n\t\r\001 <&> é" }`, "", `["q\"b\\s\n\t\r\u0001 <&> é"]` + "\n", "", jsonlOutput},
+	{`BEGIN { print "\xff" }`, "", `["\ufffd"]` + "\n", "", jsonlOutput},
+	{`BEGIN { print log(-1), -log(0), 1e300*1e300 }`, "", "[null,null,null]\n", "", jsonlOutput},
+	{`BEGIN { print "42", 42, "007"+0 }`, "", `["42",42,7]` + "\n", "", jsonlOutput},
+	{`{ print $1, $2, $3, $4, $5, $6 }`, "42 007 1e3 -0.5 .5 abc", `[42,"007",1e3,-0.5,".5","abc"]` + "\n", "", jsonlOutput},
+	{`BEGIN { OFMT="%.2f"; print 3.14159; OFMT="%x"; print 1.5 }`, "", "[3.14]\n[1.5]\n", "", jsonlOutput},
+	{`{ print }`, "a b\n", "a b\n", "", jsonlOutput},
+	{`{ print $2, $1 }`, "a b\nc d", "[\n  \"b\",\n  \"a\"\n]\n[\n  \"d\",\n  \"c\"\n]\n", "", func(config *interp.Config) {
+		config.OutputMode = interp.JSONMode
+	}},
+	{`{ print $1, $2, $3 }`, "Bob 42 x", `{"name":"Bob","age":42,"3":"x"}` + "\n", "", func(config *interp.Config) {
+		config.OutputMode = interp.JSONLMode
+		config.JSONOutput.FieldNames = []string{"name", "age"}
+	}},
+	{`BEGIN { OUTPUTMODE="jsonl fields=id,name"; x=OUTPUTMODE; print 1, x }`, "", `{"id":1,"name":"jsonl fields=id,name"}` + "\n", "", nil},
+	{`BEGIN { OUTPUTMODE="json"; x=OUTPUTMODE; print x }`, "", "[\n  \"json\"\n]\n", "", nil},
+	{`BEGIN { INPUTMODE="jsonl"; OUTPUTMODE="jsonl fields=name,id" } { print @"name", @"id" }`, `{"id":1,"name":"Bob"}`, `{"name":"Bob","id":1}` + "\n", "", nil},
+	{`BEGIN { OUTPUTMODE="jsonl fields=" }`, "", "", `invalid JSON fields value ""`, nil},
+	{`BEGIN { OUTPUTMODE="json separator=|" }`, "", "", `invalid output mode key "separator"`, nil},
+	{`BEGIN { INPUTMODE="json" }`, "", "", `invalid input mode "json"`, nil},
+	{`{}`, "", "", "CSV output configuration not valid in JSON output mode", func(config *interp.Config) {
+		config.OutputMode = interp.JSONMode
+		config.CSVOutput.Separator = '|'
+	}},
+	{`{}`, "", "", "JSON output configuration only valid in JSON output mode", func(config *interp.Config) {
+		config.OutputMode = interp.CSVMode
+		config.JSONOutput.FieldNames = []string{"a"}
+	}},
+}
+
+func jsonlInput(config *interp.Config) {
+	config.InputMode = interp.JSONLMode
+}
+
+func jsonlOutput(config *interp.Config) {
+	config.OutputMode = interp.JSONLMode
 }
 
 func TestCSV(t *testing.T) {
@@ -2148,6 +2745,99 @@ func TestCSV(t *testing.T) {
 	}
 }
 
+func TestFixedMode(t *testing.T) {
+	tests := []struct {
+		src       string
+		in        string
+		out       string
+		err       string
+		configure func(config *interp.Config)
+	}{
+		{`{ print $2, $1 }`, "ab  cdef", "cdef ab\n", "", func(config *interp.Config) {
+			config.InputMode = interp.FixedMode
+			config.FieldWidths = "2 2:*"
+		}},
+		{`{ print $2 "|" $3 }`, "héllo wörld", "llo| wörld\n", "", func(config *interp.Config) {
+			config.InputMode = interp.FixedMode
+			config.FieldWidths = "2 3 *"
+			config.Chars = true
+		}},
+		{`{ print $1 "|" $2 }`, "héllo", "h\xc3|\xa9ll\n", "", func(config *interp.Config) {
+			config.InputMode = interp.FixedMode
+			config.FieldWidths = "2 3"
+		}},
+		{`BEGIN { FS="," } { print $1 }`, "a,bc", "a,\n", "", func(config *interp.Config) {
+			config.InputMode = interp.FixedMode
+			config.FieldWidths = "2"
+		}},
+		{`BEGIN { INPUTMODE="fixed widths=1,1:2"; print INPUTMODE, FIELDWIDTHS } { print $1, $2 }`, "abcd", "fixed widths=1,1:2 1 1:2\na cd\n", "", nil},
+		{`BEGIN { INPUTMODE="fixed" }`, "", "", "fixed input mode requires field widths", nil},
+		{`BEGIN { INPUTMODE="fixed widths=1,x" }`, "", "", `invalid FIELDWIDTHS "1 x"`, nil},
+		{`BEGIN { INPUTMODE="fixed separator=," }`, "", "", `invalid input mode key "separator"`, nil},
+		{`{}`, "", "", "fixed input mode requires field widths", func(config *interp.Config) {
+			config.InputMode = interp.FixedMode
+		}},
+		{`{}`, "", "", "field widths only valid in fixed input mode", func(config *interp.Config) {
+			config.FieldWidths = "1 2"
+		}},
+		{`{}`, "", "", "fixed mode not valid for output", func(config *interp.Config) {
+			config.OutputMode = interp.FixedMode
+		}},
+	}
+	for _, test := range tests {
+		testName := test.src
+		if len(testName) > 70 {
+			testName = testName[:70]
+		}
+		t.Run(testName, func(t *testing.T) {
+			testGoAWK(t, test.src, test.in, test.out, test.err, nil, test.configure)
+		})
+	}
+}
+
+func TestBignum(t *testing.T) {
+	bignum := func(config *interp.Config) {
+		config.Bignum = true
+	}
+	tests := []struct {
+		src       string
+		in        string
+		out       string
+		err       string
+		configure func(config *interp.Config)
+	}{
+		{`BEGIN { print 2^100, 2^100 + 1, -2^64 * 3, 12 / 4, 7 % 3, -7 % 3 }`, "", "1267650600228229401496703205376 1267650600228229401496703205377 -55340232221128654848 3 1 -1\n", "", bignum},
+		{`BEGIN { print 2^100, 2^100 + 1 }`, "", "1.26765e+30 1.26765e+30\n", "", nil},
+		{`{ s += $1 } END { print s, s - 1, (s > 12345678901234567890123456789) }`, "12345678901234567890123456789\n1\n", "12345678901234567890123456790 12345678901234567890123456789 1\n", "", bignum},
+		{`BEGIN { print 1/3, 10/4, -7.5 % 2, 0.1 + 0.2, int(-3.9), sqrt(16), sqrt(2) }`, "", "0.333333 2.5 -1.5 0.3 -3 4 1.41421\n", "", bignum},
+		{`BEGIN { PREC = 100; printf "%.30f\n", 1/3 }`, "", "0.333333333333333333333333333333\n", "", bignum},
+		{`BEGIN { printf "%.35g %s %s\n", 1/3, PREC, ROUNDMODE }`, "", "0.33333333333333333333333333333333332 113 N\n", "", func(config *interp.Config) {
+			config.Bignum = true
+			config.Vars = []string{"PREC", "quad"}
+		}},
+		{`BEGIN { PREC = 10; ROUNDMODE = "Z"; printf "%.10g ", 2/3; ROUNDMODE = "U"; printf "%.10g\n", 2/3 }`, "", "0.666015625 0.6669921875\n", "", bignum},
+		{`BEGIN { printf "%d %x %o %.3e\n", 2^70, 2^70, 8, 2^70 }`, "", "1180591620717411303424 400000000000000000 10 1.181e+21\n", "", bignum},
+		{`BEGIN { x = 2^64; x++; x += 2; y = x--; print x, y, -x, (x == 18446744073709551618) }`, "", "18446744073709551618 18446744073709551619 -18446744073709551618 1\n", "", bignum},
+		{`BEGIN { a[2^65] = 1; for (k in a) print k }`, "", "36893488147419103232\n", "", bignum},
+		{`BEGIN { print log(-1), -log(0), 2^0.5 }`, "", "nan inf 1.41421\n", "", bignum},
+		{`BEGIN { print 1e400, -1e400 * 10, 1e-400, 1e3, (1e400 > 1e399) }`, "", "1e+400 -1e+401 1e-400 1000 1\n", "", bignum},
+		{`BEGIN { PREC = 100; printf "%.25f\n", 3.14159265358979323846264338 }`, "", "3.1415926535897932384626434\n", "", bignum},
+		{`BEGIN { print 1/0 }`, "", "", "division by zero", bignum},
+		{`BEGIN { x = 0; x %= 0 }`, "", "", "division by zero in mod", bignum},
+		{`BEGIN { PREC = "x" }`, "", "", `invalid PREC "x"`, bignum},
+		{`BEGIN { ROUNDMODE = "Q" }`, "", "", `invalid ROUNDMODE "Q"`, bignum},
+	}
+	for _, test := range tests {
+		testName := test.src
+		if len(testName) > 70 {
+			testName = testName[:70]
+		}
+		t.Run(testName, func(t *testing.T) {
+			testGoAWK(t, test.src, test.in, test.out, test.err, nil, test.configure)
+		})
+	}
+}
+
 func TestCSVMultiRead(t *testing.T) {
 	tests := []struct {
 		name  string
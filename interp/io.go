@@ -5,15 +5,23 @@ package interp
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"math"
+	"net"
 	"os"
 	"os/exec"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 	"unicode/utf8"
 
 	"github.com/benhoyt/goawk/internal/resolver"
@@ -41,6 +49,11 @@ func (p *interp) printArgs(writer io.Writer, args []value) error {
 		if err != nil {
 			return err
 		}
+	case JSONMode, JSONLMode:
+		err := p.writeJSON(writer, args, p.jsonOutputConfig.FieldNames)
+		if err != nil {
+			return err
+		}
 	default:
 		// Print OFS-separated args followed by ORS (usually newline).
 		for i, arg := range args {
@@ -63,6 +76,84 @@ func (p *interp) printArgs(writer io.Writer, args []value) error {
 	return nil
 }
 
+// Print array a as a single row to output (for the "printrow" function),
+// returning the number of fields printed. The fields array lists the keys of
+// a to print in order, for example fields[1]="name", fields[2]="age". If
+// fields is empty, all keys are printed, sorted numerically if they're all
+// integers, otherwise sorted as strings. In CSV or TSV output mode with the
+// "header" option, the keys are printed as a header row before the first row
+// written to each output stream. It's an error if a printed element of a (or
+// an element of fields) is a subarray.
+func (p *interp) printRow(output io.Writer, a, fields map[string]value) (int, error) {
+	var keys []string
+	for i := 1; ; i++ {
+		v, ok := fields[strconv.Itoa(i)]
+		if !ok {
+			break
+		}
+		if v.typ == typeArray {
+			return 0, newError("can't use subarray %q as scalar", strconv.Itoa(i))
+		}
+		keys = append(keys, p.toString(v))
+	}
+	if len(keys) == 0 {
+		keys = sortedRowKeys(a)
+	}
+	row := make([]value, len(keys))
+	for i, key := range keys {
+		row[i] = a[key] // missing keys are output as empty (or null)
+		if row[i].typ == typeArray {
+			return 0, newError("can't use subarray %q as scalar", key)
+		}
+	}
+
+	switch p.outputMode {
+	case CSVMode, TSVMode:
+		if p.csvOutputConfig.Header && !p.printedRowHeader[output] {
+			if p.printedRowHeader == nil {
+				p.printedRowHeader = make(map[io.Writer]bool)
+			}
+			p.printedRowHeader[output] = true
+			err := p.writeCSV(output, keys)
+			if err != nil {
+				return 0, err
+			}
+		}
+		fields := make([]string, len(row))
+		for i, v := range row {
+			fields[i] = v.str(p.outputFormat)
+		}
+		return len(row), p.writeCSV(output, fields)
+	case JSONMode, JSONLMode:
+		return len(row), p.writeJSON(output, row, keys)
+	default:
+		return len(row), p.printArgs(output, row)
+	}
+}
+
+// Return the keys of a sorted numerically if they're all integers, otherwise
+// sorted as strings.
+func sortedRowKeys(a map[string]value) []string {
+	keys := make([]string, 0, len(a))
+	numeric := true
+	for k := range a {
+		keys = append(keys, k)
+		if _, err := strconv.Atoi(k); err != nil {
+			numeric = false
+		}
+	}
+	if numeric {
+		sort.Slice(keys, func(i, j int) bool {
+			x, _ := strconv.Atoi(keys[i])
+			y, _ := strconv.Atoi(keys[j])
+			return x < y
+		})
+	} else {
+		sort.Strings(keys)
+	}
+	return keys
+}
+
 func (p *interp) writeCSV(output io.Writer, fields []string) error {
 	// If output is already a *bufio.Writer (the common case), csv.NewWriter
 	// will use it directly. This is not explicitly documented, but
@@ -82,9 +173,23 @@ func (p *interp) writeCSV(output io.Writer, fields []string) error {
 		flush = p.csvOutput.Flush
 	}
 
+	config := &p.csvOutputConfig
+	if config.Quote != CSVQuoteMinimal || config.QuoteChar != 0 && config.QuoteChar != '"' || config.Escape != 0 {
+		// encoding/csv doesn't support other quoting policies, so write
+		// these ourselves.
+		err := p.writeCSVQuoted(output.(*bufio.Writer), fields)
+		if err != nil {
+			return err
+		}
+		if flush != nil {
+			return flush()
+		}
+		return nil
+	}
+
 	// Given the above, creating a new one of these is cheap.
 	writer := csv.NewWriter(output)
-	writer.Comma = p.csvOutputConfig.Separator
+	writer.Comma = config.Separator
 	writer.UseCRLF = p.newlineOutputCRLF
 	err := writer.Write(fields)
 	if err != nil {
@@ -96,10 +201,233 @@ func (p *interp) writeCSV(output io.Writer, fields []string) error {
 	return nil
 }
 
+// Write fields as a CSV row according to the output config's quoting
+// policy, quote character, and escape character.
+func (p *interp) writeCSVQuoted(w *bufio.Writer, fields []string) error {
+	config := &p.csvOutputConfig
+	quote := config.QuoteChar
+	if quote == 0 {
+		quote = '"'
+	}
+	escape := config.Escape
+	for i, field := range fields {
+		if i > 0 {
+			w.WriteRune(config.Separator)
+		}
+		var quoted bool
+		switch config.Quote {
+		case CSVQuoteAlways:
+			quoted = true
+		case CSVQuoteNonNumeric:
+			quoted = !csvNumberRegex.MatchString(field) || p.csvFieldNeedsQuotes(field, quote)
+		case CSVQuoteNever:
+			quoted = false
+		default:
+			quoted = p.csvFieldNeedsQuotes(field, quote)
+		}
+		if !quoted {
+			if escape == 0 {
+				w.WriteString(field)
+				continue
+			}
+			// Unquoted fields can only contain special characters in
+			// CSVQuoteNever mode; escape them.
+			for j := 0; j < len(field); {
+				r, size := utf8.DecodeRuneInString(field[j:])
+				if r == config.Separator || r == quote || r == escape {
+					w.WriteRune(escape)
+				}
+				w.WriteString(field[j : j+size])
+				j += size
+			}
+			continue
+		}
+
+		w.WriteRune(quote)
+		for j := 0; j < len(field); {
+			r, size := utf8.DecodeRuneInString(field[j:])
+			switch {
+			case r == quote && escape != 0:
+				w.WriteRune(escape)
+			case r == quote:
+				w.WriteRune(quote)
+			case r == escape && escape != 0:
+				w.WriteRune(escape)
+			case r == '\r' && p.newlineOutputCRLF:
+				j += size
+				continue // like encoding/csv, write "\n" as "\r\n" instead
+			case r == '\n' && p.newlineOutputCRLF:
+				w.WriteByte('\r')
+			}
+			w.WriteString(field[j : j+size])
+			j += size
+		}
+		w.WriteRune(quote)
+	}
+	var err error
+	if p.newlineOutputCRLF {
+		_, err = w.WriteString("\r\n")
+	} else {
+		err = w.WriteByte('\n')
+	}
+	return err
+}
+
+// Report whether field needs quoting in CSVQuoteMinimal mode. This is the
+// same logic as encoding/csv uses, but with a custom quote character, and
+// fields containing the escape character are also quoted.
+func (p *interp) csvFieldNeedsQuotes(field string, quote rune) bool {
+	if field == "" {
+		return false
+	}
+	if field == `\.` {
+		return true
+	}
+	for _, r := range field {
+		if r == p.csvOutputConfig.Separator || r == quote || r == '\r' || r == '\n' ||
+			r == p.csvOutputConfig.Escape && r != 0 {
+			return true
+		}
+	}
+	r, _ := utf8.DecodeRuneInString(field)
+	return unicode.IsSpace(r)
+}
+
+// Fields that look like this are considered numbers by CSVQuoteNonNumeric.
+var csvNumberRegex = regexp.MustCompile(`^[-+]?([0-9]+\.?[0-9]*|\.[0-9]+)([eE][-+]?[0-9]+)?$`)
+
+// Write args as a JSON array, or as a JSON object keyed by names if names is
+// not nil, followed by a newline.
+func (p *interp) writeJSON(output io.Writer, args []value, names []string) error {
+	buf := &p.jsonOutputBuf
+	buf.Reset()
+	if names == nil {
+		buf.WriteByte('[')
+	} else {
+		buf.WriteByte('{')
+	}
+	for i, arg := range args {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if names != nil {
+			if i < len(names) {
+				writeJSONString(buf, names[i])
+			} else {
+				writeJSONString(buf, strconv.Itoa(i+1))
+			}
+			buf.WriteByte(':')
+		}
+		p.writeJSONValue(buf, arg)
+	}
+	if names == nil {
+		buf.WriteByte(']')
+	} else {
+		buf.WriteByte('}')
+	}
+
+	out := buf.Bytes()
+	if p.outputMode == JSONMode {
+		var indented bytes.Buffer
+		err := json.Indent(&indented, out, "", "  ")
+		if err != nil {
+			return err
+		}
+		out = indented.Bytes()
+	}
+	out = append(out, '\n')
+	return writeOutput(output, string(out), p.newlineOutputCRLF)
+}
+
+// Write a single value as JSON. Numbers and numeric strings are written as
+// JSON numbers (numeric strings only if they're already in JSON number
+// format, for example "007" stays a string), uninitialized values, NaN,
+// and infinity as null, and everything else as a JSON string.
+func (p *interp) writeJSONValue(buf *bytes.Buffer, v value) {
+	switch v.typ {
+	case typeNull:
+		buf.WriteString("null")
+	case typeNum:
+		if v.s == "" && (math.IsNaN(v.n) || math.IsInf(v.n, 0)) {
+			buf.WriteString("null")
+			return
+		}
+		s := v.str(p.outputFormat)
+		if !jsonNumberRegex.MatchString(s) {
+			// OFMT may produce something that's not a JSON number.
+			s = strconv.FormatFloat(v.n, 'g', -1, 64)
+		}
+		buf.WriteString(s)
+	case typeNumStr:
+		if _, isStr := v.isTrueStr(); !isStr && jsonNumberRegex.MatchString(v.s) {
+			buf.WriteString(v.s)
+			return
+		}
+		writeJSONString(buf, v.s)
+	default:
+		writeJSONString(buf, v.s)
+	}
+}
+
+var jsonNumberRegex = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][-+]?[0-9]+)?$`)
+
+// Write s as a quoted and escaped JSON string. Unlike encoding/json, this
+// doesn't escape HTML characters. Invalid UTF-8 is replaced with U+FFFD.
+func writeJSONString(buf *bytes.Buffer, s string) {
+	const hex = "0123456789abcdef"
+	buf.WriteByte('"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c >= utf8.RuneSelf {
+			r, size := utf8.DecodeRuneInString(s[i:])
+			if r == utf8.RuneError && size == 1 {
+				buf.WriteString(`\ufffd`)
+			} else {
+				buf.WriteString(s[i : i+size])
+			}
+			i += size
+			continue
+		}
+		switch {
+		case c == '"' || c == '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		case c == '\n':
+			buf.WriteString(`\n`)
+		case c == '\r':
+			buf.WriteString(`\r`)
+		case c == '\t':
+			buf.WriteString(`\t`)
+		case c < 0x20 || c == 0x7f:
+			buf.WriteString(`\u00`)
+			buf.WriteByte(hex[c>>4])
+			buf.WriteByte(hex[c&0xf])
+		default:
+			buf.WriteByte(c)
+		}
+		i++
+	}
+	buf.WriteByte('"')
+}
+
 // Determine the output stream for given redirect token and
 // destination (file or pipe name)
 func (p *interp) getOutputStream(redirect lexer.Token, destValue value) (io.Writer, error) {
 	name := p.toString(destValue)
+	if redirect == lexer.PIPE_AND || isNetworkName(name) {
+		// Network special files are two-way streams, like coprocesses
+		s, err := p.getCoprocess(name)
+		if s == nil {
+			if err != nil {
+				return nil, err
+			}
+			return io.Discard, nil // non-fatal error, ERRNO has been set
+		}
+		return s, nil
+	}
+	if _, ok := p.coprocesses[name]; ok {
+		return nil, newError("can't redirect to coprocess %q without |&", name)
+	}
 	if _, ok := p.inputStreams[name]; ok {
 		return nil, newError("can't write to reader stream")
 	}
@@ -137,6 +465,10 @@ func (p *interp) getOutputStream(redirect lexer.Token, destValue value) (io.Writ
 		}
 		f, err := p.openFile(name, flags, 0644)
 		if err != nil {
+			if p.nonFatal(name) {
+				p.setErrno(err)
+				return io.Discard, nil
+			}
 			return nil, newError("output redirection error: %s", err)
 		}
 		out := newOutFileStream(f, outputBufSize)
@@ -166,6 +498,138 @@ func (p *interp) getOutputStream(redirect lexer.Token, destValue value) (io.Writ
 	}
 }
 
+// Get the two-way stream for a "|&" coprocess, starting the command if it's
+// not already running. If the command or network connection can't be
+// started and I/O errors are non-fatal, set ERRNO and return nil, nil.
+func (p *interp) getCoprocess(name string) (*coprocStream, error) {
+	if s, ok := p.coprocesses[name]; ok {
+		return s, nil
+	}
+	if _, ok := p.inputStreams[name]; ok {
+		return nil, newError("can't use reader stream as coprocess")
+	}
+	if _, ok := p.outputStreams[name]; ok {
+		return nil, newError("can't use writer stream as coprocess")
+	}
+	if isNetworkName(name) {
+		s, err := p.openNetwork(name)
+		if s == nil {
+			return nil, err
+		}
+		p.coprocesses[name] = s
+		p.scanners[name] = p.newScanner(s, name, make([]byte, inputBufSize))
+		return s, nil
+	}
+	if p.noExec {
+		return nil, newError("can't start coprocess due to NoExec")
+	}
+	cmd := p.execShell(name)
+	cmd.Stderr = p.errorOutput
+	p.flushOutputAndError() // ensure synchronization
+	s, err := newCoprocStream(cmd)
+	if err != nil {
+		if p.nonFatal(name) {
+			p.setErrno(err)
+			return nil, nil
+		}
+		return nil, newError("coprocess error: %s", err)
+	}
+	p.coprocesses[name] = s
+	p.scanners[name] = p.newScanner(s, name, make([]byte, inputBufSize))
+	return s, nil
+}
+
+// Report whether name is a network special file like "/inet/tcp/0/host/80".
+func isNetworkName(name string) bool {
+	return strings.HasPrefix(name, "/inet/") ||
+		strings.HasPrefix(name, "/inet4/") ||
+		strings.HasPrefix(name, "/inet6/")
+}
+
+// Open a network connection for a special file of the form
+// "/inet/protocol/localport/remotehost/remoteport", as in gawk. The
+// protocol is "tcp" or "udp", and "/inet4" or "/inet6" may be used to force
+// IPv4 or IPv6. If remoteport is 0, act as a server: listen on localport and
+// accept a single client (for TCP) or reply to whoever sent the last
+// datagram (for UDP). Otherwise connect to remotehost:remoteport, from
+// localport if it's not 0.
+//
+// Like getCoprocess, return nil, nil if the connection fails with non-fatal
+// I/O errors enabled.
+func (p *interp) openNetwork(name string) (*coprocStream, error) {
+	switch {
+	case !p.allowNetwork:
+		return nil, newError("can't open network connection unless AllowNetwork is set")
+	case p.noFileReads:
+		return nil, newError("can't open network connection due to NoFileReads")
+	case p.noFileWrites:
+		return nil, newError("can't open network connection due to NoFileWrites")
+	}
+	parts := strings.Split(name, "/")
+	if len(parts) != 6 || (parts[3] == "0" && parts[5] == "0") {
+		return nil, newError("invalid network special file %q", name)
+	}
+	protocol, localPort, remoteHost, remotePort := parts[2], parts[3], parts[4], parts[5]
+	if protocol != "tcp" && protocol != "udp" {
+		return nil, newError("invalid network protocol %q in %q", protocol, name)
+	}
+	network := protocol + strings.TrimPrefix(parts[1], "inet")
+
+	ctx := context.Background()
+	if p.checkCtx {
+		ctx = p.ctx
+	}
+	p.flushOutputAndError() // ensure synchronization
+	var conn net.Conn
+	var err error
+	if remotePort == "0" {
+		conn, err = listenOne(ctx, network, net.JoinHostPort("", localPort))
+	} else {
+		dialer := net.Dialer{}
+		if localPort != "0" {
+			dialer.LocalAddr, err = localAddr(network, localPort)
+		}
+		if err == nil {
+			conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(remoteHost, remotePort))
+		}
+	}
+	if err != nil {
+		if p.nonFatal(name) {
+			p.setErrno(err)
+			return nil, nil
+		}
+		return nil, newError("network error: %s", err)
+	}
+	return newNetStream(conn), nil
+}
+
+// Listen on addr and return a connection to the first client: for TCP, the
+// first connection accepted, and for UDP, a socket that replies to senders.
+func listenOne(ctx context.Context, network, addr string) (net.Conn, error) {
+	var lc net.ListenConfig
+	if strings.HasPrefix(network, "udp") {
+		pc, err := lc.ListenPacket(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &udpServerConn{UDPConn: pc.(*net.UDPConn)}, nil
+	}
+	listener, err := lc.Listen(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	defer listener.Close()
+	return listener.Accept()
+}
+
+// Return the local address to dial from for the given local port.
+func localAddr(network, port string) (net.Addr, error) {
+	if strings.HasPrefix(network, "udp") {
+		return net.ResolveUDPAddr(network, net.JoinHostPort("", port))
+	}
+	return net.ResolveTCPAddr(network, net.JoinHostPort("", port))
+}
+
 // Executes code using configured system shell
 func (p *interp) execShell(code string) *exec.Cmd {
 	executable := p.shellCommand[0]
@@ -185,9 +649,19 @@ func (p *interp) execShell(code string) *exec.Cmd {
 
 // Get input Scanner to use for "getline" based on file name
 func (p *interp) getInputScannerFile(name string) (*bufio.Scanner, error) {
+	if isNetworkName(name) {
+		s, err := p.getCoprocess(name)
+		if s == nil {
+			return nil, err
+		}
+		return p.scanners[name], nil
+	}
 	if _, ok := p.outputStreams[name]; ok {
 		return nil, newError("can't read from writer stream")
 	}
+	if _, ok := p.coprocesses[name]; ok {
+		return nil, newError("can't read from coprocess %q without |&", name)
+	}
 	if _, ok := p.inputStreams[name]; ok {
 		return p.scanners[name], nil
 	}
@@ -196,7 +670,7 @@ func (p *interp) getInputScannerFile(name string) (*bufio.Scanner, error) {
 		if scanner, ok := p.scanners["-"]; ok {
 			return scanner, nil
 		}
-		scanner := p.newScanner(p.stdin, make([]byte, inputBufSize))
+		scanner := p.newScanner(p.stdin, name, make([]byte, inputBufSize))
 		p.scanners[name] = scanner
 		return scanner, nil
 	}
@@ -208,7 +682,7 @@ func (p *interp) getInputScannerFile(name string) (*bufio.Scanner, error) {
 		return nil, err // fs.ErrNotExist is handled by caller (getline returns -1)
 	}
 	in := newInFileStream(f)
-	scanner := p.newScanner(in, make([]byte, inputBufSize))
+	scanner := p.newScanner(in, name, make([]byte, inputBufSize))
 	p.scanners[name] = scanner
 	p.inputStreams[name] = in
 	return scanner, nil
@@ -219,6 +693,9 @@ func (p *interp) getInputScannerPipe(name string) (*bufio.Scanner, error) {
 	if _, ok := p.outputStreams[name]; ok {
 		return nil, newError("can't read from writer stream")
 	}
+	if _, ok := p.coprocesses[name]; ok {
+		return nil, newError("can't read from coprocess %q without |&", name)
+	}
 	if _, ok := p.inputStreams[name]; ok {
 		return p.scanners[name], nil
 	}
@@ -235,14 +712,15 @@ func (p *interp) getInputScannerPipe(name string) (*bufio.Scanner, error) {
 		return bufio.NewScanner(strings.NewReader("")), nil
 	}
 
-	scanner := p.newScanner(in, make([]byte, inputBufSize))
+	scanner := p.newScanner(in, name, make([]byte, inputBufSize))
 	p.inputStreams[name] = in
 	p.scanners[name] = scanner
 	return scanner, nil
 }
 
-// Create a new buffered Scanner for reading input records
-func (p *interp) newScanner(input io.Reader, buffer []byte) *bufio.Scanner {
+// Create a new buffered Scanner for reading input records. The name of the
+// file or command is used in CSV and JSON Lines error messages.
+func (p *interp) newScanner(input io.Reader, name string, buffer []byte) *bufio.Scanner {
 	scanner := bufio.NewScanner(input)
 	switch {
 	case p.inputMode == CSVMode || p.inputMode == TSVMode:
@@ -250,9 +728,25 @@ func (p *interp) newScanner(input io.Reader, buffer []byte) *bufio.Scanner {
 			separator:     p.csvInputConfig.Separator,
 			sepLen:        utf8.RuneLen(p.csvInputConfig.Separator),
 			comment:       p.csvInputConfig.Comment,
+			quote:         p.csvInputConfig.QuoteChar,
 			header:        p.csvInputConfig.Header,
+			fieldNames:    p.csvInputConfig.FieldNames,
+			name:          name,
+			strict:        p.csvInputConfig.Strict,
+			numFields:     p.csvInputConfig.FieldsPerRecord,
+			onError:       p.csvInputConfig.OnError,
 			fields:        &p.fields,
 			setFieldNames: p.setFieldNames,
+			csvError:      &p.csvError,
+			warn:          func(msg string) { p.printErrorf("skipping row: %s\n", msg) },
+		}
+		scanner.Split(splitter.scan)
+	case p.inputMode == JSONLMode:
+		splitter := jsonlSplitter{
+			flatten: p.jsonFlatten,
+			name:    name,
+			names:   &p.jsonlNames,
+			fields:  &p.jsonlFields,
 		}
 		scanner.Split(splitter.scan)
 	case p.recordSep == "\n":
@@ -411,10 +905,19 @@ func (s regexSplitter) scan(data []byte, atEOF bool) (advance int, token []byte,
 
 // Splitter that splits records in CSV or TSV format.
 type csvSplitter struct {
-	separator rune
-	sepLen    int
-	comment   rune
-	header    bool
+	separator  rune
+	sepLen     int
+	comment    rune
+	quote      rune // zero means '"'
+	header     bool
+	fieldNames []string
+
+	// Strict parsing and error handling. If numFields is zero and strict
+	// is true, it's set to the number of fields in the first row.
+	name      string
+	strict    bool
+	numFields int
+	onError   CSVErrorMode
 
 	recordBuffer []byte
 	fieldIndexes []int
@@ -422,15 +925,21 @@ type csvSplitter struct {
 
 	fields        *[]string
 	setFieldNames func(names []string)
+	csvError      *string
+	warn          func(msg string)
 	rowNum        int
+	lineNum       int
 }
 
 // The structure of this code is taken from the stdlib encoding/csv Reader
 // code, which is licensed under a compatible BSD-style license.
 //
-// We don't support all encoding/csv features: FieldsPerRecord is not
-// supported, LazyQuotes is always on, and TrimLeadingSpace is always off.
+// We don't support all encoding/csv features: TrimLeadingSpace is always
+// off, and LazyQuotes is on unless strict is true (in which case errors
+// are handled according to onError).
 func (s *csvSplitter) scan(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	input := data
+
 	// Some CSV files are saved with a UTF-8 BOM at the start; skip it.
 	if !s.noBOMCheck && len(data) >= 3 && data[0] == 0xEF && data[1] == 0xBB && data[2] == 0xBF {
 		data = data[3:]
@@ -444,6 +953,7 @@ func (s *csvSplitter) scan(data []byte, atEOF bool) (advance int, token []byte,
 		return 0, nil, nil
 	}
 
+	lines := 0 // number of lines read, only added to s.lineNum on success
 	readLine := func() []byte {
 		newline := bytes.IndexByte(data, '\n')
 		var line []byte
@@ -460,6 +970,7 @@ func (s *csvSplitter) scan(data []byte, atEOF bool) (advance int, token []byte,
 			// Need more data
 			return nil
 		}
+		lines++
 
 		// For backwards compatibility, drop trailing \r before EOF.
 		if len(line) > 0 && atEOF && line[len(line)-1] == '\r' {
@@ -491,14 +1002,27 @@ func (s *csvSplitter) scan(data []byte, atEOF bool) (advance int, token []byte,
 		break
 	}
 
-	// Parse each field in the record.
-	const quoteLen = len(`"`)
+	// Parse each field in the record. In strict mode, record the first
+	// error, but keep parsing leniently in case the error is reported to
+	// the program rather than aborting.
+	quote := s.quote
+	if quote == 0 {
+		quote = '"'
+	}
+	quoteLen := utf8.RuneLen(quote)
 	tokenHasCR := false
+	startLine := s.lineNum + lines
+	var errMsg string
+	setError := func(line int, msg string) {
+		if errMsg == "" {
+			errMsg = fmt.Sprintf("%s:%d: %s", s.name, line, msg)
+		}
+	}
 	s.recordBuffer = s.recordBuffer[:0]
 	s.fieldIndexes = s.fieldIndexes[:0]
 parseField:
 	for {
-		if len(line) == 0 || line[0] != '"' {
+		if len(line) == 0 || nextRune(line) != quote {
 			// Non-quoted string field
 			i := bytes.IndexRune(line, s.separator)
 			field := line
@@ -509,6 +1033,9 @@ parseField:
 				advance += len(field)
 				field = field[:len(field)-lenNewline(field)]
 			}
+			if s.strict && bytes.IndexRune(field, quote) >= 0 {
+				setError(s.lineNum+lines, `bare " in non-quoted field`)
+			}
 			s.recordBuffer = append(s.recordBuffer, field...)
 			s.fieldIndexes = append(s.fieldIndexes, len(s.recordBuffer))
 			if i >= 0 {
@@ -521,16 +1048,16 @@ parseField:
 			line = line[quoteLen:]
 			advance += quoteLen
 			for {
-				i := bytes.IndexByte(line, '"')
+				i := bytes.IndexRune(line, quote)
 				if i >= 0 {
 					// Hit next quote.
 					s.recordBuffer = append(s.recordBuffer, line[:i]...)
 					line = line[i+quoteLen:]
 					advance += i + quoteLen
 					switch rn := nextRune(line); {
-					case rn == '"':
+					case rn == quote:
 						// `""` sequence (append quote).
-						s.recordBuffer = append(s.recordBuffer, '"')
+						s.recordBuffer = utf8.AppendRune(s.recordBuffer, quote)
 						line = line[quoteLen:]
 						advance += quoteLen
 					case rn == s.separator:
@@ -546,7 +1073,16 @@ parseField:
 						break parseField
 					default:
 						// `"` sequence (bare quote).
-						s.recordBuffer = append(s.recordBuffer, '"')
+						s.recordBuffer = utf8.AppendRune(s.recordBuffer, quote)
+						if s.strict {
+							// Like encoding/csv, end the record at the end of
+							// this line so that the next row parses normally.
+							setError(s.lineNum+lines, `extraneous or missing " in quoted field`)
+							s.recordBuffer = append(s.recordBuffer, line[:len(line)-lenNewline(line)]...)
+							s.fieldIndexes = append(s.fieldIndexes, len(s.recordBuffer))
+							advance += len(line)
+							break parseField
+						}
 					}
 				} else if len(line) > 0 {
 					// Hit end of line (copy all data so far).
@@ -565,6 +1101,9 @@ parseField:
 					}
 				} else {
 					// Abrupt end of file.
+					if s.strict {
+						setError(startLine, `extraneous or missing " in quoted field`)
+					}
 					s.fieldIndexes = append(s.fieldIndexes, len(s.recordBuffer))
 					advance += len(line)
 					break parseField
@@ -585,7 +1124,42 @@ parseField:
 
 	s.noBOMCheck = true
 
-	if s.rowNum == 0 && s.header {
+	if s.numFields == 0 && s.strict && errMsg == "" {
+		s.numFields = len(fields)
+	}
+	if s.numFields > 0 && len(fields) != s.numFields {
+		setError(startLine, fmt.Sprintf("wrong number of fields (expected %d, got %d)", s.numFields, len(fields)))
+	}
+	if errMsg != "" {
+		if s.rowNum == 0 && s.header || s.onError == CSVErrorAbort {
+			return 0, nil, errors.New(errMsg)
+		}
+		if s.onError == CSVErrorSkip {
+			s.warn(errMsg)
+			s.lineNum += lines
+			if atEOF {
+				// Scanner stops if we return no token at EOF, so parse
+				// the next row now.
+				n, token, err := s.scan(input[advance:], atEOF)
+				return advance + n, token, err
+			}
+			return advance, nil, nil
+		}
+	}
+	if s.onError == CSVErrorReport {
+		*s.csvError = errMsg
+	}
+	s.lineNum += lines
+
+	if s.rowNum == 0 && s.fieldNames != nil {
+		// Use explicitly-provided field names. If there's also a header row,
+		// skip it.
+		s.setFieldNames(s.fieldNames)
+		if s.header {
+			s.rowNum++
+			return advance, nil, nil
+		}
+	} else if s.rowNum == 0 && s.header {
 		// Set header field names and advance, but don't return a line (token).
 		s.rowNum++
 		s.setFieldNames(fields)
@@ -603,6 +1177,163 @@ parseField:
 	return advance, token, nil
 }
 
+// Splitter that splits records in JSON Lines format: one JSON object per
+// line. Each object is parsed into field names and fields, which are only
+// applied to the current record when the main input loop makes it $0 (so
+// "getline var" doesn't change the current fields). Blank lines are skipped.
+type jsonlSplitter struct {
+	flatten string
+	name    string // name of file or command, for error messages
+
+	names   *[]string
+	fields  *[]string
+	lineNum int
+}
+
+func (s *jsonlSplitter) scan(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	advance, token, err = bufio.ScanLines(data, atEOF)
+	if err != nil || token == nil {
+		return advance, token, err
+	}
+	s.lineNum++
+	if len(bytes.TrimSpace(token)) == 0 {
+		return advance, nil, nil
+	}
+	names, fields, err := parseJSONLine(string(token), s.flatten)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%s:%d: invalid JSON: %v", s.name, s.lineNum, err)
+	}
+	*s.names = names
+	*s.fields = fields
+	return advance, token, nil
+}
+
+// Parse a JSON Lines record (a single JSON object) into field names and
+// values, flattening nested objects and arrays according to flatten (see
+// Config.JSONFlatten).
+func parseJSONLine(line string, flatten string) (names, fields []string, err error) {
+	if strings.TrimSpace(line) == "" {
+		return []string{}, nil, nil
+	}
+	decoder := json.NewDecoder(strings.NewReader(line))
+	var raw json.RawMessage
+	err = decoder.Decode(&raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, nil, errors.New("unexpected data after JSON object")
+	}
+	if raw[0] != '{' {
+		return nil, nil, errors.New("record is not a JSON object")
+	}
+	f := jsonFlattener{flatten: flatten, names: []string{}}
+	err = f.addNested("", raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f.names, f.fields, nil
+}
+
+type jsonFlattener struct {
+	flatten string
+	names   []string
+	fields  []string
+}
+
+// Add a single JSON value as a field with the given name, flattening it if
+// it's an object or array. Booleans are converted to 1 or 0, and null to an
+// empty string.
+func (f *jsonFlattener) add(name string, raw json.RawMessage) error {
+	var field string
+	switch raw[0] {
+	case '{', '[':
+		if f.flatten != "none" {
+			return f.addNested(name, raw)
+		}
+		var buf bytes.Buffer
+		err := json.Compact(&buf, raw)
+		if err != nil {
+			return err
+		}
+		field = buf.String()
+	case '"':
+		err := json.Unmarshal(raw, &field)
+		if err != nil {
+			return err
+		}
+	case 't':
+		field = "1"
+	case 'f':
+		field = "0"
+	case 'n':
+		field = ""
+	default:
+		field = string(raw)
+	}
+	f.names = append(f.names, name)
+	f.fields = append(f.fields, field)
+	return nil
+}
+
+// Add the values of a JSON object or array as fields, using the keys (or
+// 1-based indexes for an array) appended to prefix as the field names. An
+// empty object or array is added as a single field ("{}" or "[]").
+func (f *jsonFlattener) addNested(prefix string, raw json.RawMessage) error {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	delim, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	n := 0
+	for decoder.More() {
+		n++
+		var key string
+		if delim == json.Delim('{') {
+			token, err := decoder.Token()
+			if err != nil {
+				return err
+			}
+			key = token.(string)
+		} else {
+			key = strconv.Itoa(n)
+		}
+		if prefix != "" {
+			key = prefix + f.flatten + key
+		}
+		var value json.RawMessage
+		err := decoder.Decode(&value)
+		if err != nil {
+			return err
+		}
+		err = f.add(key, value)
+		if err != nil {
+			return err
+		}
+	}
+	if n == 0 && prefix != "" {
+		f.names = append(f.names, prefix)
+		if delim == json.Delim('{') {
+			f.fields = append(f.fields, "{}")
+		} else {
+			f.fields = append(f.fields, "[]")
+		}
+	}
+	return nil
+}
+
+func stringsEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
 // lenNewline reports the number of bytes for the trailing \n.
 func lenNewline(b []byte) int {
 	if len(b) > 0 && b[len(b)-1] == '\n' {
@@ -640,6 +1371,11 @@ func (p *interp) setLine(line string, isTrueStr bool) {
 	// split fields lazily, we need to save FS here.
 	p.savedFieldSep = p.fieldSep
 	p.savedFieldSepRegex = p.fieldSepRegex
+	p.savedFieldPatRegex = p.fieldPatRegex
+	p.savedFieldWidths = nil
+	if p.inputMode == FixedMode || p.useFieldWidths {
+		p.savedFieldWidths = p.fieldWidths
+	}
 }
 
 // Splits on FS as a regex, appending each field to fields and returning the
@@ -660,6 +1396,83 @@ func (p *interp) splitOnFieldSepRegex(fields []string, line string) []string {
 	return fields
 }
 
+// A single FIELDWIDTHS entry: skip that many chars, then take a field of the
+// given width (a width of -1 means the rest of the record).
+type fieldWidth struct {
+	skip  int
+	width int
+}
+
+// Parse and set FIELDWIDTHS, for example "5 2:3 *". An empty string means
+// FIELDWIDTHS is not used.
+func (p *interp) setFieldWidths(s string) error {
+	fields := strings.Fields(s)
+	widths := make([]fieldWidth, 0, len(fields))
+	for i, field := range fields {
+		var w fieldWidth
+		skipStr, widthStr, hasSkip := strings.Cut(field, ":")
+		if !hasSkip {
+			skipStr, widthStr = "0", field
+		}
+		var err error
+		w.skip, err = strconv.Atoi(skipStr)
+		if err != nil || w.skip < 0 {
+			return newError("invalid FIELDWIDTHS %q", s)
+		}
+		if widthStr == "*" && i == len(fields)-1 {
+			w.width = -1
+		} else {
+			w.width, err = strconv.Atoi(widthStr)
+			if err != nil || w.width < 0 {
+				return newError("invalid FIELDWIDTHS %q", s)
+			}
+		}
+		widths = append(widths, w)
+	}
+	p.fieldWidthsStr = s
+	p.fieldWidths = nil
+	if len(widths) > 0 {
+		p.fieldWidths = widths
+	}
+	return nil
+}
+
+// Splits line using the saved FIELDWIDTHS, appending each field to fields and
+// returning the new slice. Widths are in chars if p.chars is set, otherwise
+// bytes. Like gawk, fields that start past the end of line are not included.
+func (p *interp) splitOnFieldWidths(fields []string, line string) []string {
+	pos := 0
+	for _, w := range p.savedFieldWidths {
+		pos = p.advance(line, pos, w.skip)
+		if pos >= len(line) {
+			break
+		}
+		end := len(line)
+		if w.width >= 0 {
+			end = p.advance(line, pos, w.width)
+		}
+		fields = append(fields, line[pos:end])
+		pos = end
+	}
+	return fields
+}
+
+// Return the byte index n chars (or bytes) after pos in s, stopping at the
+// end of s.
+func (p *interp) advance(s string, pos, n int) int {
+	if !p.chars {
+		if n > len(s)-pos {
+			return len(s)
+		}
+		return pos + n
+	}
+	for ; n > 0 && pos < len(s); n-- {
+		_, size := utf8.DecodeRuneInString(s[pos:])
+		pos += size
+	}
+	return pos
+}
+
 // Ensure that the current line is parsed into fields, splitting it
 // into fields if it hasn't been already
 func (p *interp) ensureFields() {
@@ -678,6 +1491,7 @@ func (p *interp) ensureFields() {
 				separator: p.csvInputConfig.Separator,
 				sepLen:    utf8.RuneLen(p.csvInputConfig.Separator),
 				comment:   p.csvInputConfig.Comment,
+				quote:     p.csvInputConfig.QuoteChar,
 				fields:    &p.fields,
 			}
 			scanner.Split(splitter.scan)
@@ -685,6 +1499,24 @@ func (p *interp) ensureFields() {
 				p.fields = nil
 			}
 		}
+	case p.inputMode == JSONLMode:
+		// Normally fields have already been parsed by jsonlSplitter. If $0
+		// was assigned and isn't valid JSON, there are no fields.
+		if p.reparseCSV {
+			names, fields, err := parseJSONLine(p.line, p.jsonFlatten)
+			if err == nil {
+				p.setFieldNames(names)
+			}
+			p.fields = fields
+		}
+	case p.savedFieldWidths != nil:
+		p.fields = p.splitOnFieldWidths(p.fields[:0], p.line)
+	case p.savedFieldPatRegex != nil:
+		// FPAT is set, so fields are the text matching it, not separators
+		p.fields = p.fields[:0]
+		for _, match := range splitOnFieldPat(p.savedFieldPatRegex, p.line) {
+			p.fields = append(p.fields, p.line[match[0]:match[1]])
+		}
 	case p.savedFieldSep == " ":
 		// FS space (default) means split fields on any whitespace
 		p.fields = strings.Fields(p.line)
@@ -701,7 +1533,8 @@ func (p *interp) ensureFields() {
 	// Special case for when RS=="" and FS is single character,
 	// split on newline in addition to FS. See more here:
 	// https://www.gnu.org/software/gawk/manual/html_node/Multiple-Line.html
-	if p.inputMode == DefaultMode && p.recordSep == "" && utf8.RuneCountInString(p.savedFieldSep) == 1 {
+	if p.inputMode == DefaultMode && p.recordSep == "" && p.savedFieldPatRegex == nil &&
+		p.savedFieldWidths == nil && utf8.RuneCountInString(p.savedFieldSep) == 1 {
 		fields := make([]string, 0, len(p.fields))
 		for _, field := range p.fields {
 			lines := strings.Split(field, "\n")
@@ -781,16 +1614,48 @@ func (p *interp) nextLine() (string, error) {
 					}
 					input, err := p.openFile(filename, os.O_RDONLY, 0)
 					if err != nil {
-						return "", err
+						if len(p.compiled.BeginFile) == 0 {
+							if p.nonFatal(filename) {
+								// Skip unreadable file, but let the user know
+								p.setErrno(err)
+								p.printErrorf("error opening %q: %s\n", filename, errnoString(err))
+								p.input = nil
+								continue
+							}
+							return "", err
+						}
+						// With BEGINFILE, an open error isn't fatal if the
+						// BEGINFILE block skips the file using nextfile.
+						p.input = nil
+						p.setFile(filename)
+						p.setErrno(err)
+						skip, beginErr := p.beginFile()
+						if beginErr != nil {
+							return "", beginErr
+						}
+						if !skip {
+							return "", err
+						}
+						continue
 					}
 					p.input = input
 					p.setFile(filename)
 				}
 			}
+			if len(p.compiled.BeginFile) > 0 {
+				p.errno = str("")
+				skip, err := p.beginFile()
+				if err != nil {
+					return "", err
+				}
+				if skip {
+					continue
+				}
+			}
 			if p.inputBuffer == nil { // reuse buffer from last input file
 				p.inputBuffer = make([]byte, inputBufSize)
 			}
-			p.scanner = p.newScanner(p.input, p.inputBuffer)
+			p.scanner = p.newScanner(p.input, p.toString(p.filename), p.inputBuffer)
 		}
 		p.recordTerminator = p.recordSep // will be overridden if RS is "" or multiple chars
 		if p.scanner.Scan() {
@@ -803,6 +1668,10 @@ func (p *interp) nextLine() (string, error) {
 		}
 		// Signal loop to move onto next file
 		p.scanner = nil
+		err = p.endFile()
+		if err != nil {
+			return "", err
+		}
 	}
 
 	// Got a line (record) of input, return it
@@ -811,6 +1680,63 @@ func (p *interp) nextLine() (string, error) {
 	return p.scanner.Text(), nil
 }
 
+// Execute the BEGINFILE blocks (if any) for a new input file. Return true if
+// they executed nextfile to skip the file.
+func (p *interp) beginFile() (bool, error) {
+	err := p.execute(p.compiled.BeginFile)
+	switch err {
+	case errNextfile:
+		return true, nil
+	case errNext:
+		return false, newError("next can't be used in BEGINFILE")
+	default:
+		return false, err
+	}
+}
+
+// Execute the ENDFILE blocks (if any) after the end of an input file.
+func (p *interp) endFile() error {
+	err := p.execute(p.compiled.EndFile)
+	if err == errNext || err == errNextfile {
+		return newError("%s can't be used in ENDFILE", err)
+	}
+	return err
+}
+
+// Return a short error message for ERRNO, like "no such file or directory"
+// instead of the full "open foo: no such file or directory".
+func errnoString(err error) string {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return pathErr.Err.Error()
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return opErr.Err.Error()
+	}
+	return err.Error()
+}
+
+// Set ERRNO to a short message describing err.
+func (p *interp) setErrno(err error) {
+	p.errno = str(errnoString(err))
+}
+
+// Report whether I/O errors on the named file, command, or network
+// connection are non-fatal, meaning they set ERRNO and return -1 rather
+// than stopping the program. This is the case if Config.NonFatalIO is set,
+// or if PROCINFO["NONFATAL"] or PROCINFO[name, "NONFATAL"] exists.
+func (p *interp) nonFatal(name string) bool {
+	if p.nonFatalIO {
+		return true
+	}
+	if _, ok := p.procInfo["NONFATAL"]; ok {
+		return true
+	}
+	_, ok := p.procInfo[name+p.subscriptSep+"NONFATAL"]
+	return ok
+}
+
 // Write output string to given writer, producing correct line endings
 // on Windows (CR LF).
 func writeOutput(w io.Writer, s string, crlfNewline bool) error {
@@ -825,6 +1751,80 @@ func writeOutput(w io.Writer, s string, crlfNewline bool) error {
 	return err
 }
 
+// Close the named stream for close(), and return its exit code (-1 if there's
+// no such stream or closing it failed, in which case ERRNO is set).
+func (p *interp) closeStream(name string) int {
+	var err error
+	code := -1
+	if stream := p.inputStreams[name]; stream != nil {
+		// Close input stream
+		delete(p.inputStreams, name)
+		delete(p.scanners, name)
+		err = stream.Close()
+		code = stream.ExitCode()
+	} else if stream := p.outputStreams[name]; stream != nil {
+		// Close output stream
+		delete(p.outputStreams, name)
+		delete(p.printedRowHeader, stream)
+		err = stream.Close()
+		code = stream.ExitCode()
+	} else if stream := p.coprocesses[name]; stream != nil {
+		// Close both halves of coprocess and wait for it to exit
+		delete(p.coprocesses, name)
+		delete(p.scanners, name)
+		delete(p.printedRowHeader, stream)
+		err = stream.Close()
+		code = stream.ExitCode()
+	} else {
+		p.errno = str("close of redirection that was never opened")
+	}
+	if err != nil {
+		code = -1
+		p.setErrno(err)
+		if !p.nonFatal(name) {
+			p.printErrorf("error closing %q: %v\n", name, err)
+		}
+	}
+	return code
+}
+
+// Close one half of a coprocess for close(cmd, how), where how is "to" or
+// "from". When both halves are closed, wait for the command and return its
+// exit code. Other streams are closed entirely.
+func (p *interp) closeCoprocess(name, how string) (int, error) {
+	how = strings.ToLower(how)
+	if how != "to" && how != "from" {
+		return 0, newError(`close: second argument must be "to" or "from", got %q`, how)
+	}
+	stream := p.coprocesses[name]
+	if stream == nil {
+		return p.closeStream(name), nil
+	}
+	var err error
+	if how == "to" {
+		err = stream.CloseTo()
+	} else {
+		err = stream.CloseFrom()
+	}
+	code := 0
+	if stream.Done() {
+		delete(p.coprocesses, name)
+		delete(p.scanners, name)
+		if waitErr := stream.Wait(); waitErr != nil && err == nil {
+			err = waitErr
+		}
+		code = stream.ExitCode()
+	}
+	if err != nil {
+		code = -1
+		p.setErrno(err)
+		if !p.nonFatal(name) {
+			p.printErrorf("error closing %q: %v\n", name, err)
+		}
+	}
+	return code, nil
+}
+
 // Close all streams and so on (after program execution).
 func (p *interp) closeAll() {
 	if prevInput, ok := p.input.(io.Closer); ok {
@@ -836,6 +1836,9 @@ func (p *interp) closeAll() {
 	for _, w := range p.outputStreams {
 		_ = w.Close()
 	}
+	for _, c := range p.coprocesses {
+		_ = c.Close()
+	}
 	if f, ok := p.output.(flusher); ok {
 		_ = f.Flush()
 	}
@@ -853,6 +1856,11 @@ func (p *interp) flushAll() bool {
 			allGood = false
 		}
 	}
+	for name, c := range p.coprocesses {
+		if !c.toClosed && !p.flushWriter(name, c) {
+			allGood = false
+		}
+	}
 	if !p.flushWriter("stdout", p.output) {
 		allGood = false
 	}
@@ -862,7 +1870,10 @@ func (p *interp) flushAll() bool {
 // Flush a single, named output stream, and report whether it was flushed
 // successfully (logging an error if not).
 func (p *interp) flushStream(name string) bool {
-	writer := p.outputStreams[name]
+	var writer io.Writer = p.outputStreams[name]
+	if c := p.coprocesses[name]; c != nil && !c.toClosed {
+		writer = c
+	}
 	if writer == nil {
 		p.printErrorf("error flushing %q: not an output file or pipe\n", name)
 		return false
@@ -7,6 +7,7 @@ import (
 	"bufio"
 	"errors"
 	"io"
+	"net"
 	"os/exec"
 	"syscall"
 )
@@ -222,3 +223,181 @@ func (s *inCmdStream) Close() error {
 func (s *inCmdStream) ExitCode() int {
 	return s.exitCode
 }
+
+// A coprocStream is a two-way pipe to a command started with "|&": print
+// writes to the command's stdin, and getline reads from its stdout. Either
+// half can be closed separately with close(cmd, "to") or close(cmd, "from").
+// It's also used for network connections opened via /inet special files.
+type coprocStream struct {
+	*bufio.Writer
+	stdin      io.WriteCloser
+	stdout     io.ReadCloser
+	cmd        *exec.Cmd // nil for a network connection
+	conn       net.Conn  // nil for a command
+	exitCode   int
+	toClosed   bool
+	fromClosed bool
+}
+
+func newCoprocStream(cmd *exec.Cmd) (*coprocStream, error) {
+	w, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, newError("error connecting to stdin pipe: %v", err)
+	}
+	r, err := cmd.StdoutPipe()
+	if err != nil {
+		_ = w.Close()
+		return nil, newError("error connecting to stdout pipe: %v", err)
+	}
+	err = cmd.Start()
+	if err != nil {
+		_ = w.Close()
+		_ = r.Close()
+		return nil, err
+	}
+	s := &coprocStream{bufio.NewWriterSize(w, outputBufSize), w, r, cmd, nil, notClosedExitCode, false, false}
+	return s, nil
+}
+
+func newNetStream(conn net.Conn) *coprocStream {
+	w := connWriter{conn}
+	r := connReader{conn}
+	return &coprocStream{bufio.NewWriterSize(w, outputBufSize), w, r, nil, conn, notClosedExitCode, false, false}
+}
+
+// A connWriter is the write half of a network connection: closing it shuts
+// down writing (if supported) but leaves the connection open for reading.
+type connWriter struct {
+	net.Conn
+}
+
+func (c connWriter) Close() error {
+	if cw, ok := c.Conn.(interface{ CloseWrite() error }); ok {
+		return ignoreNotConn(cw.CloseWrite())
+	}
+	return nil
+}
+
+// A connReader is the read half of a network connection.
+type connReader struct {
+	net.Conn
+}
+
+func (c connReader) Close() error {
+	if cr, ok := c.Conn.(interface{ CloseRead() error }); ok {
+		return ignoreNotConn(cr.CloseRead())
+	}
+	return nil
+}
+
+// Shutting down half of a TCP connection fails with ENOTCONN if the peer has
+// already closed it, but that's not a problem when closing.
+func ignoreNotConn(err error) error {
+	if errors.Is(err, syscall.ENOTCONN) {
+		return nil
+	}
+	return err
+}
+
+func (s *coprocStream) Write(b []byte) (int, error) {
+	if s.toClosed {
+		return 0, newError("can't write to coprocess after closing \"to\"")
+	}
+	return s.Writer.Write(b)
+}
+
+func (s *coprocStream) Read(b []byte) (int, error) {
+	if s.fromClosed {
+		return 0, io.EOF
+	}
+	// Send any buffered output before waiting on the command's reply.
+	if !s.toClosed {
+		if err := s.Writer.Flush(); err != nil {
+			return 0, err
+		}
+	}
+	return s.stdout.Read(b)
+}
+
+// CloseTo closes the command's stdin, for example so it sees end of input.
+func (s *coprocStream) CloseTo() error {
+	if s.toClosed {
+		return errDoubleClose
+	}
+	s.toClosed = true
+	flushErr := s.Writer.Flush()
+	closeErr := s.stdin.Close()
+	return firstError(flushErr, closeErr)
+}
+
+// CloseFrom closes the read half of the pipe to the command's stdout.
+func (s *coprocStream) CloseFrom() error {
+	if s.fromClosed {
+		return errDoubleClose
+	}
+	s.fromClosed = true
+	return s.stdout.Close()
+}
+
+// Done reports whether both halves have been closed.
+func (s *coprocStream) Done() bool {
+	return s.toClosed && s.fromClosed
+}
+
+// Wait waits for the command to exit and records its exit code, or closes the
+// network connection. Both halves must already be closed.
+func (s *coprocStream) Wait() error {
+	if s.conn != nil {
+		s.exitCode = 0
+		if err := s.conn.Close(); err != nil {
+			s.exitCode = -1
+			return err
+		}
+		return nil
+	}
+	var err error
+	s.exitCode, err = waitExitCode(s.cmd)
+	return err
+}
+
+func (s *coprocStream) Close() error {
+	if s.Done() {
+		return errDoubleClose
+	}
+	var toErr, fromErr error
+	if !s.toClosed {
+		toErr = s.CloseTo()
+	}
+	if !s.fromClosed {
+		fromErr = s.CloseFrom()
+	}
+	waitErr := s.Wait()
+	return firstError(waitErr, toErr, fromErr)
+}
+
+func (s *coprocStream) ExitCode() int {
+	return s.exitCode
+}
+
+// A udpServerConn is a UDP socket listening on a local port: reads receive
+// datagrams from any peer, and writes reply to the sender of the most recent
+// datagram.
+type udpServerConn struct {
+	*net.UDPConn
+	peer *net.UDPAddr
+}
+
+func (c *udpServerConn) Read(b []byte) (int, error) {
+	n, addr, err := c.UDPConn.ReadFromUDP(b)
+	if addr != nil {
+		c.peer = addr
+	}
+	return n, err
+}
+
+func (c *udpServerConn) Write(b []byte) (int, error) {
+	if c.peer == nil {
+		return 0, errors.New("can't write to UDP server before receiving from a client")
+	}
+	return c.UDPConn.WriteToUDP(b, c.peer)
+}
@@ -5,6 +5,7 @@ package interp
 import (
 	"context"
 	"math"
+	"math/big"
 
 	"github.com/benhoyt/goawk/internal/resolver"
 	"github.com/benhoyt/goawk/parser"
@@ -63,13 +64,17 @@ func (p *Interpreter) Execute(config *Config) (int, error) {
 
 // Array returns a map representing the items in the named AWK array. AWK
 // numbers are included as type float64, strings (including "numeric strings")
-// are included as type string. If the named array does not exist, return nil.
+// are included as type string, and subarrays (as in a[x][y]) are included as
+// type map[string]any. If the named array does not exist, return nil.
 func (p *Interpreter) Array(name string) map[string]any {
 	index, exists := p.interp.arrayIndexes[name]
 	if !exists {
 		return nil
 	}
-	array := p.interp.array(resolver.Global, index)
+	return p.interp.arrayToMap(p.interp.array(resolver.Global, index))
+}
+
+func (p *interp) arrayToMap(array map[string]value) map[string]any {
 	result := make(map[string]any, len(array))
 	for k, v := range array {
 		switch v.typ {
@@ -77,6 +82,8 @@ func (p *Interpreter) Array(name string) map[string]any {
 			result[k] = v.n
 		case typeStr, typeNumStr:
 			result[k] = v.s
+		case typeArray:
+			result[k] = p.arrayToMap(p.subarray(v))
 		default:
 			result[k] = ""
 		}
@@ -96,12 +103,17 @@ func (p *interp) resetCore() {
 	for k := range p.outputStreams {
 		delete(p.outputStreams, k)
 	}
+	for k := range p.coprocesses {
+		delete(p.coprocesses, k)
+	}
 
 	p.sp = 0
 	p.localArrays = p.localArrays[:0]
 	p.callDepth = 0
 
 	p.filename = null()
+	p.errno = null()
+	p.csvError = ""
 	p.line = ""
 	p.lineIsTrueStr = false
 	p.lineNum = num(0)
@@ -130,6 +142,8 @@ func (p *interp) resetVars() {
 			delete(array, k)
 		}
 	}
+	p.subarrays = nil
+	p.freeSubarrays = nil
 
 	// Reset special variables
 	p.convertFormat = "%.6g"
@@ -138,12 +152,22 @@ func (p *interp) resetVars() {
 	p.fieldSepRegex = nil
 	p.savedFieldSep = " "
 	p.savedFieldSepRegex = nil
+	p.fieldPat = defaultFieldPat
+	p.fieldPatRegex = nil
+	p.savedFieldPatRegex = nil
+	p.fieldWidthsStr = ""
+	p.fieldWidths = nil
+	p.useFieldWidths = false
+	p.savedFieldWidths = nil
 	p.recordSep = "\n"
 	p.recordSepRegex = nil
 	p.recordTerminator = ""
 	p.outputFieldSep = " "
 	p.outputRecordSep = "\n"
 	p.subscriptSep = "\x1c"
+	p.precision = defaultPrecision
+	p.roundModeStr = defaultRoundMode
+	p.roundMode = big.ToNearestEven
 }
 
 // ResetVars resets this interpreter's variables, setting scalar variables to
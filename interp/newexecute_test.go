@@ -111,6 +111,27 @@ func TestResetRand(t *testing.T) {
 	}
 }
 
+func TestNewExecuteBignum(t *testing.T) {
+	interpreter := newInterp(t, `BEGIN { x = 2^64; x++; print x, 0.1 + 0.2 }`)
+	for _, test := range []struct {
+		bignum   bool
+		expected string
+	}{
+		{true, "18446744073709551617 0.3\n"},
+		{false, "1.84467e+19 0.3\n"},
+		{true, "18446744073709551617 0.3\n"},
+	} {
+		var output bytes.Buffer
+		_, err := interpreter.Execute(&interp.Config{Output: &output, Bignum: test.bignum})
+		if err != nil {
+			t.Fatalf("error executing: %v", err)
+		}
+		if output.String() != test.expected {
+			t.Fatalf("expected %q with Bignum %v, got %q", test.expected, test.bignum, output.String())
+		}
+	}
+}
+
 func TestGetArrayValue(t *testing.T) {
 	interpreter := newInterp(t, `
 BEGIN { Arr["key"]; f(); g(Arr) }
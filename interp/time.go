@@ -0,0 +1,436 @@
+// Time functions: systime(), strftime(), mktime(), and strptime().
+
+package interp
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default format for strftime() with no arguments (the initial value of
+// PROCINFO["strftime"]), same as gawk's.
+const defaultTimeFormat = "%a %b %e %H:%M:%S %Z %Y"
+
+// Format t according to the C strftime-style format string. Unknown
+// conversions are output unchanged.
+func strftime(format string, t time.Time) string {
+	var b strings.Builder
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+		if c != '%' || i+1 >= len(format) {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		c = format[i]
+		if (c == 'E' || c == 'O') && i+1 < len(format) {
+			// Ignore the POSIX locale modifiers, as in %Ey or %Od
+			i++
+			c = format[i]
+		}
+		switch c {
+		case 'a':
+			b.WriteString(t.Weekday().String()[:3])
+		case 'A':
+			b.WriteString(t.Weekday().String())
+		case 'b', 'h':
+			b.WriteString(t.Month().String()[:3])
+		case 'B':
+			b.WriteString(t.Month().String())
+		case 'c':
+			b.WriteString(strftime("%a %b %e %H:%M:%S %Y", t))
+		case 'C':
+			writePadded(&b, t.Year()/100, 2, '0')
+		case 'd':
+			writePadded(&b, t.Day(), 2, '0')
+		case 'D', 'x':
+			b.WriteString(strftime("%m/%d/%y", t))
+		case 'e':
+			writePadded(&b, t.Day(), 2, ' ')
+		case 'F':
+			b.WriteString(strftime("%Y-%m-%d", t))
+		case 'g':
+			year, _ := t.ISOWeek()
+			writePadded(&b, year%100, 2, '0')
+		case 'G':
+			year, _ := t.ISOWeek()
+			b.WriteString(strconv.Itoa(year))
+		case 'H':
+			writePadded(&b, t.Hour(), 2, '0')
+		case 'I':
+			writePadded(&b, hour12(t), 2, '0')
+		case 'j':
+			writePadded(&b, t.YearDay(), 3, '0')
+		case 'k':
+			writePadded(&b, t.Hour(), 2, ' ')
+		case 'l':
+			writePadded(&b, hour12(t), 2, ' ')
+		case 'm':
+			writePadded(&b, int(t.Month()), 2, '0')
+		case 'M':
+			writePadded(&b, t.Minute(), 2, '0')
+		case 'n':
+			b.WriteByte('\n')
+		case 'p':
+			if t.Hour() < 12 {
+				b.WriteString("AM")
+			} else {
+				b.WriteString("PM")
+			}
+		case 'r':
+			b.WriteString(strftime("%I:%M:%S %p", t))
+		case 'R':
+			b.WriteString(strftime("%H:%M", t))
+		case 's':
+			b.WriteString(strconv.FormatInt(t.Unix(), 10))
+		case 'S':
+			writePadded(&b, t.Second(), 2, '0')
+		case 't':
+			b.WriteByte('\t')
+		case 'T', 'X':
+			b.WriteString(strftime("%H:%M:%S", t))
+		case 'u':
+			writePadded(&b, (int(t.Weekday())+6)%7+1, 1, '0')
+		case 'U':
+			writePadded(&b, (t.YearDay()+6-int(t.Weekday()))/7, 2, '0')
+		case 'V':
+			_, week := t.ISOWeek()
+			writePadded(&b, week, 2, '0')
+		case 'w':
+			writePadded(&b, int(t.Weekday()), 1, '0')
+		case 'W':
+			writePadded(&b, (t.YearDay()+6-(int(t.Weekday())+6)%7)/7, 2, '0')
+		case 'y':
+			writePadded(&b, t.Year()%100, 2, '0')
+		case 'Y':
+			b.WriteString(strconv.Itoa(t.Year()))
+		case 'z':
+			b.WriteString(t.Format("-0700"))
+		case 'Z':
+			b.WriteString(t.Format("MST"))
+		case '%':
+			b.WriteByte('%')
+		default:
+			b.WriteByte('%')
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// Write n to b, left-padded to the given width with pad.
+func writePadded(b *strings.Builder, n, width int, pad byte) {
+	s := strconv.Itoa(n)
+	for i := len(s); i < width; i++ {
+		b.WriteByte(pad)
+	}
+	b.WriteString(s)
+}
+
+func hour12(t time.Time) int {
+	h := t.Hour() % 12
+	if h == 0 {
+		h = 12
+	}
+	return h
+}
+
+// Guts of the mktime() function. Convert a date specification in the form
+// "YYYY MM DD HH MM SS [DST]" to seconds since the epoch. Values outside
+// their normal ranges are normalized (for example, 25 hours adds a day).
+// As in C, a DST flag of 0 means the time is standard time, a positive flag
+// means it's daylight saving time, and -1 (the default) means determine
+// which from the date. Return -1 if the specification is invalid.
+func mktime(spec string, loc *time.Location) int64 {
+	fields := strings.Fields(spec)
+	if len(fields) < 6 || len(fields) > 7 {
+		return -1
+	}
+	var values [6]int
+	for i := range values {
+		n, err := strconv.Atoi(fields[i])
+		if err != nil {
+			return -1
+		}
+		values[i] = n
+	}
+	t := time.Date(values[0], time.Month(values[1]), values[2], values[3], values[4], values[5], 0, loc)
+	if len(fields) == 7 {
+		dst, err := strconv.Atoi(fields[6])
+		switch {
+		case err != nil:
+			// Like gawk, treat an invalid flag as -1
+		case dst == 0 && t.IsDST():
+			t = t.Add(dstOffset(t))
+		case dst > 0 && !t.IsDST():
+			t = t.Add(-dstOffset(t))
+		}
+	}
+	return t.Unix()
+}
+
+// Return how far ahead of standard time daylight saving time is in t's
+// location and year, or 0 if there's no daylight saving time that year.
+func dstOffset(t time.Time) time.Duration {
+	_, jan := time.Date(t.Year(), 1, 1, 0, 0, 0, 0, t.Location()).Zone()
+	_, jul := time.Date(t.Year(), 7, 1, 0, 0, 0, 0, t.Location()).Zone()
+	if jan > jul {
+		jan, jul = jul, jan // southern hemisphere
+	}
+	return time.Duration(jul-jan) * time.Second
+}
+
+// Guts of the strptime() function, the inverse of strftime(). Parse s
+// according to the strftime-style format, which supports the conversions
+// %Y %C %y %m %d %e %j %H %k %I %l %M %S %p %b %h %B %a %A %z %Z %s, and the
+// composites %T %D %F %R %c. If format is "", parse s as an RFC 3339
+// timestamp. Fractional seconds are allowed after %S and are discarded.
+// Times without a zone offset are in loc.
+func strptime(s, format string, loc *time.Location) (int64, error) {
+	if format == "" {
+		format = "%Y-%m-%dT%H:%M:%S%z"
+	}
+	tp := timeParser{s: s, year: 1970, month: 1, day: 1, loc: loc}
+	err := tp.parse(format)
+	if err != nil {
+		return 0, err
+	}
+	tp.skipSpace()
+	if tp.pos < len(tp.s) {
+		return 0, errors.New("extra text at end of time")
+	}
+	if tp.hasUnix {
+		return tp.unix, nil
+	}
+	if tp.pm && tp.hour < 12 {
+		tp.hour += 12
+	} else if tp.am && tp.hour == 12 {
+		tp.hour = 0
+	}
+	if tp.yearDay > 0 {
+		tp.month = 1
+		tp.day = tp.yearDay
+	}
+	t := time.Date(tp.year, time.Month(tp.month), tp.day, tp.hour, tp.minute, tp.second, 0, tp.loc)
+	return t.Unix(), nil
+}
+
+// State of a strptime() parse.
+type timeParser struct {
+	s   string
+	pos int
+
+	year, month, day, yearDay int
+	hour, minute, second      int
+	am, pm                    bool
+	loc                       *time.Location
+	unix                      int64
+	hasUnix                   bool
+}
+
+func (tp *timeParser) parse(format string) error {
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+		if c == ' ' || c == '\t' || c == '\n' {
+			tp.skipSpace()
+			continue
+		}
+		if c != '%' || i+1 >= len(format) {
+			if tp.pos >= len(tp.s) || tp.s[tp.pos] != c {
+				return errors.New("time doesn't match format")
+			}
+			tp.pos++
+			continue
+		}
+		i++
+		c = format[i]
+		if (c == 'E' || c == 'O') && i+1 < len(format) {
+			i++
+			c = format[i]
+		}
+		var err error
+		switch c {
+		case 'Y':
+			tp.year, err = tp.number(4, true)
+		case 'C':
+			var century int
+			century, err = tp.number(2, false)
+			tp.year = century*100 + tp.year%100
+		case 'y':
+			var year int
+			year, err = tp.number(2, false)
+			if year < 69 {
+				year += 2000
+			} else {
+				year += 1900
+			}
+			tp.year = year
+		case 'm':
+			tp.month, err = tp.number(2, false)
+		case 'd', 'e':
+			tp.skipSpace()
+			tp.day, err = tp.number(2, false)
+		case 'j':
+			tp.yearDay, err = tp.number(3, false)
+		case 'H', 'k', 'I', 'l':
+			tp.skipSpace()
+			tp.hour, err = tp.number(2, false)
+		case 'M':
+			tp.minute, err = tp.number(2, false)
+		case 'S':
+			tp.second, err = tp.number(2, false)
+			if err == nil && tp.pos+1 < len(tp.s) && (tp.s[tp.pos] == '.' || tp.s[tp.pos] == ',') && isDigit(tp.s[tp.pos+1]) {
+				// Skip fractional seconds
+				tp.pos++
+				for tp.pos < len(tp.s) && isDigit(tp.s[tp.pos]) {
+					tp.pos++
+				}
+			}
+		case 'p':
+			switch {
+			case tp.consumeFold("AM"):
+				tp.am = true
+			case tp.consumeFold("PM"):
+				tp.pm = true
+			default:
+				err = errors.New("expected AM or PM")
+			}
+		case 'b', 'h', 'B':
+			tp.month, err = tp.name(monthNames[:])
+		case 'a', 'A':
+			_, err = tp.name(dayNames[:])
+		case 'z':
+			err = tp.zoneOffset()
+		case 'Z':
+			start := tp.pos
+			for tp.pos < len(tp.s) && isZoneChar(tp.s[tp.pos]) {
+				tp.pos++
+			}
+			switch tp.s[start:tp.pos] {
+			case "":
+				err = errors.New("expected time zone name")
+			case "UTC", "GMT", "Z":
+				tp.loc = time.UTC
+			}
+		case 's':
+			start := tp.pos
+			if tp.pos < len(tp.s) && tp.s[tp.pos] == '-' {
+				tp.pos++
+			}
+			for tp.pos < len(tp.s) && isDigit(tp.s[tp.pos]) {
+				tp.pos++
+			}
+			tp.unix, err = strconv.ParseInt(tp.s[start:tp.pos], 10, 64)
+			tp.hasUnix = true
+		case 'T':
+			err = tp.parse("%H:%M:%S")
+		case 'D':
+			err = tp.parse("%m/%d/%y")
+		case 'F':
+			err = tp.parse("%Y-%m-%d")
+		case 'R':
+			err = tp.parse("%H:%M")
+		case 'c':
+			err = tp.parse("%a %b %e %H:%M:%S %Y")
+		case 'n', 't':
+			tp.skipSpace()
+		case '%':
+			if tp.pos >= len(tp.s) || tp.s[tp.pos] != '%' {
+				err = errors.New("time doesn't match format")
+			}
+			tp.pos++
+		default:
+			err = errors.New("invalid time format %" + string(c))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Parse a decimal number of up to maxDigits digits (and an optional sign
+// if signed is true).
+func (tp *timeParser) number(maxDigits int, signed bool) (int, error) {
+	start := tp.pos
+	if signed && tp.pos < len(tp.s) && (tp.s[tp.pos] == '-' || tp.s[tp.pos] == '+') {
+		tp.pos++
+	}
+	digitsStart := tp.pos
+	for tp.pos < len(tp.s) && tp.pos-digitsStart < maxDigits && isDigit(tp.s[tp.pos]) {
+		tp.pos++
+	}
+	if tp.pos == digitsStart {
+		return 0, errors.New("expected number in time")
+	}
+	return strconv.Atoi(tp.s[start:tp.pos])
+}
+
+// Parse a month or day name (full or abbreviated to three letters, case
+// insensitive) and return its 1-based index in names.
+func (tp *timeParser) name(names []string) (int, error) {
+	for i, name := range names {
+		if tp.consumeFold(name) || tp.consumeFold(name[:3]) {
+			return i + 1, nil
+		}
+	}
+	return 0, errors.New("expected month or day name")
+}
+
+// Parse a zone offset such as "Z", "+0700", or "-07:00".
+func (tp *timeParser) zoneOffset() error {
+	if tp.consumeFold("Z") {
+		tp.loc = time.UTC
+		return nil
+	}
+	if tp.pos >= len(tp.s) || (tp.s[tp.pos] != '+' && tp.s[tp.pos] != '-') {
+		return errors.New("expected time zone offset")
+	}
+	sign := 1
+	if tp.s[tp.pos] == '-' {
+		sign = -1
+	}
+	tp.pos++
+	hours, err := tp.number(2, false)
+	if err != nil {
+		return err
+	}
+	if tp.pos < len(tp.s) && tp.s[tp.pos] == ':' {
+		tp.pos++
+	}
+	minutes, err := tp.number(2, false)
+	if err != nil {
+		return err
+	}
+	tp.loc = time.FixedZone("", sign*(hours*3600+minutes*60))
+	return nil
+}
+
+func (tp *timeParser) consumeFold(prefix string) bool {
+	if len(tp.s)-tp.pos >= len(prefix) && strings.EqualFold(tp.s[tp.pos:tp.pos+len(prefix)], prefix) {
+		tp.pos += len(prefix)
+		return true
+	}
+	return false
+}
+
+func (tp *timeParser) skipSpace() {
+	for tp.pos < len(tp.s) && (tp.s[tp.pos] == ' ' || tp.s[tp.pos] == '\t' || tp.s[tp.pos] == '\n') {
+		tp.pos++
+	}
+}
+
+func isZoneChar(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'
+}
+
+var monthNames = [...]string{
+	"January", "February", "March", "April", "May", "June", "July",
+	"August", "September", "October", "November", "December",
+}
+
+var dayNames = [...]string{
+	"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday",
+}
@@ -5,6 +5,7 @@ package interp
 import (
 	"fmt"
 	"math"
+	"math/big"
 	"strconv"
 	"strings"
 )
@@ -16,15 +17,21 @@ const (
 	typeStr
 	typeNum
 	typeNumStr
+	typeArray // subarray stored in an array item, like a[x] in a[x][y]
+	typeRef   // reference to an array item (only used on the stack)
 )
 
 // An AWK value (these are passed around by value)
 type value struct {
 	typ valueType // Type of value
-	s   string    // String value (for typeStr and typeNumStr)
-	n   float64   // Numeric value (for typeNum)
+	s   string    // String value (for typeStr and typeNumStr), index (for typeRef), or exact number if n isn't exact (for typeNum)
+	n   float64   // Numeric value (for typeNum), subarray index (for typeArray), or array referred to (for typeRef)
 }
 
+// Integers with an absolute value up to this are represented exactly by a
+// float64.
+const maxExactFloat = 1 << 53
+
 // Create a new null value
 func null() value {
 	return value{}
@@ -35,6 +42,14 @@ func num(n float64) value {
 	return value{typ: typeNum, n: n}
 }
 
+// Create a new number value with an exact integer value
+func intNum(i int64) value {
+	if i > -maxExactFloat && i < maxExactFloat {
+		return value{typ: typeNum, n: float64(i)}
+	}
+	return value{typ: typeNum, s: strconv.FormatInt(i, 10), n: float64(i)}
+}
+
 // Create a new string value
 func str(s string) value {
 	return value{typ: typeStr, s: s}
@@ -45,6 +60,26 @@ func numStr(s string) value {
 	return value{typ: typeNumStr, s: s}
 }
 
+// Create a new subarray value. Subarrays are stored in a table in the
+// interpreter (so that values stay small), and the value holds the index of
+// the subarray in that table.
+func arrayValue(subarrayIndex int) value {
+	return value{typ: typeArray, n: float64(subarrayIndex)}
+}
+
+// Create a reference to the item with the given index in p.arrays[arrayIndex].
+// The array index is stored as a negative number to distinguish it from a
+// subarray index.
+func arrayRef(arrayIndex int, index string) value {
+	return value{typ: typeRef, s: index, n: float64(-1 - arrayIndex)}
+}
+
+// Create a reference to the item with the given index in the subarray that
+// the typeArray value sub refers to.
+func subarrayRef(sub value, index string) value {
+	return value{typ: typeRef, s: index, n: sub.n}
+}
+
 // Create a numeric value from a Go bool
 func boolean(b bool) value {
 	if b {
@@ -62,6 +97,10 @@ func (v value) String() string {
 		return fmt.Sprintf("num(%s)", v.str("%.6g"))
 	case typeNumStr:
 		return fmt.Sprintf("numStr(%q)", v.s)
+	case typeArray:
+		return fmt.Sprintf("array(%d)", int(v.n))
+	case typeRef:
+		return fmt.Sprintf("ref(%q)", v.s)
 	default:
 		return "null()"
 	}
@@ -85,6 +124,27 @@ func (v value) isTrueStr() (float64, bool) {
 	}
 }
 
+// Return the name of v's type, as returned by typeof(): "string",
+// "number", "strnum" (a numeric string from input), "array", or
+// "unassigned".
+func (v value) typeName() string {
+	switch v.typ {
+	case typeStr:
+		return "string"
+	case typeNum:
+		return "number"
+	case typeNumStr:
+		if _, isStr := v.isTrueStr(); isStr {
+			return "string"
+		}
+		return "strnum"
+	case typeArray:
+		return "array"
+	default: // typeNull
+		return "unassigned"
+	}
+}
+
 // Return Go bool value of AWK value. For numbers or numeric strings,
 // zero is false and everything else is true. For strings, empty
 // string is false and everything else is true.
@@ -133,6 +193,8 @@ func parseFloat(s string) (float64, error) {
 func (v value) str(floatFormat string) string {
 	if v.typ == typeNum {
 		switch {
+		case v.s != "":
+			return v.big().str(floatFormat)
 		case math.IsNaN(v.n):
 			return "nan"
 		case math.IsInf(v.n, 0):
@@ -166,6 +228,227 @@ func (v value) num() float64 {
 	}
 }
 
+// Return value converted to a number value. Integers too large to be exact
+// as a float64 are parsed exactly if they fit in an int64.
+func (v value) toNum() value {
+	switch v.typ {
+	case typeNum:
+		return v
+	case typeStr, typeNumStr:
+		f := parseFloatPrefix(v.s)
+		if (f <= -maxExactFloat || f >= maxExactFloat) && f == math.Trunc(f) && !math.IsInf(f, 0) {
+			if i, ok := parseIntPrefix(v.s); ok {
+				return intNum(i)
+			}
+		}
+		return num(f)
+	default: // typeNull
+		return num(0)
+	}
+}
+
+// Return the integer value of a number value, and true if it's an integer
+// that's exactly representable as an int64.
+func (v value) exactInt() (int64, bool) {
+	if v.s != "" {
+		// An integer too large to be exact as a float64, or a
+		// non-integer in bignum mode (which won't parse)
+		i, err := strconv.ParseInt(v.s, 10, 64)
+		return i, err == nil
+	}
+	if v.n > -maxExactFloat && v.n < maxExactFloat && v.n == math.Trunc(v.n) {
+		return int64(v.n), true
+	}
+	return 0, false
+}
+
+// Return the integer values of two values, and true if they're both exact
+// integers.
+func exactInts(l, r value) (int64, int64, bool) {
+	li, lok := l.toNum().exactInt()
+	ri, rok := r.toNum().exactInt()
+	return li, ri, lok && rok
+}
+
+// Return value's number value truncated to an integer, for printf's integer
+// verbs like %d. This is an int64 if it's in range. Otherwise, like gawk, the
+// result is formatted exactly (using a *big.Int), or as "nan", "inf", or
+// "-inf" for values that aren't finite.
+func (v value) formatInt() any {
+	n := v.toNum()
+	if i, ok := n.exactInt(); ok {
+		return i
+	}
+	switch {
+	case math.IsNaN(n.n) || math.IsInf(n.n, 0):
+		return nonFiniteNum(n.n)
+	case n.n >= -(1<<63) && n.n < 1<<63:
+		return int64(n.n)
+	default:
+		i, _ := big.NewFloat(n.n).Int(nil)
+		return i
+	}
+}
+
+// A nonFiniteNum formats as AWK prints it ("nan", "inf", or "-inf") for any
+// printf verb, respecting the width and "-" flag.
+type nonFiniteNum float64
+
+func (n nonFiniteNum) Format(f fmt.State, verb rune) {
+	format := "%*s"
+	if f.Flag('-') {
+		format = "%-*s"
+	}
+	width, _ := f.Width()
+	fmt.Fprintf(f, format, width, num(float64(n)).str(""))
+}
+
+// The following functions perform arithmetic on AWK values. If the operands
+// and result are in the range where float64 is exact, the float64 result is
+// used (the fast path). Otherwise if both operands are integers and the
+// result fits in an int64, the result is exact.
+
+func addValues(l, r value) value {
+	ln, rn := l.num(), r.num()
+	if math.Abs(ln)+math.Abs(rn) < maxExactFloat {
+		return num(ln + rn)
+	}
+	return addExact(l, r, ln+rn)
+}
+
+func addExact(l, r value, sum float64) value {
+	if li, ri, ok := exactInts(l, r); ok {
+		if isum := li + ri; (isum > li) == (ri > 0) {
+			return intNum(isum)
+		}
+	}
+	return num(sum)
+}
+
+func subtractValues(l, r value) value {
+	ln, rn := l.num(), r.num()
+	if math.Abs(ln)+math.Abs(rn) < maxExactFloat {
+		return num(ln - rn)
+	}
+	return subtractExact(l, r, ln-rn)
+}
+
+func subtractExact(l, r value, diff float64) value {
+	if li, ri, ok := exactInts(l, r); ok {
+		if idiff := li - ri; (idiff < li) == (ri > 0) {
+			return intNum(idiff)
+		}
+	}
+	return num(diff)
+}
+
+func multiplyValues(l, r value) value {
+	product := l.num() * r.num()
+	if math.Abs(product) < maxExactFloat {
+		return num(product)
+	}
+	return multiplyExact(l, r, product)
+}
+
+func multiplyExact(l, r value, product float64) value {
+	if li, ri, ok := exactInts(l, r); ok {
+		if iproduct, ok := multiplyInts(li, ri); ok {
+			return intNum(iproduct)
+		}
+	}
+	return num(product)
+}
+
+// Caller must ensure r is nonzero.
+func divideValues(l, r value) value {
+	ln, rn := l.num(), r.num()
+	if math.Abs(ln) >= maxExactFloat || math.Abs(rn) >= maxExactFloat {
+		li, ri, ok := exactInts(l, r)
+		if ok && li%ri == 0 && !(li == math.MinInt64 && ri == -1) {
+			return intNum(li / ri)
+		}
+	}
+	return num(ln / rn)
+}
+
+// Caller must ensure r is nonzero.
+func moduloValues(l, r value) value {
+	ln, rn := l.num(), r.num()
+	if math.Abs(ln) >= maxExactFloat || math.Abs(rn) >= maxExactFloat {
+		if li, ri, ok := exactInts(l, r); ok {
+			return intNum(li % ri)
+		}
+	}
+	return num(math.Mod(ln, rn))
+}
+
+func powerValues(l, r value) value {
+	result := math.Pow(l.num(), r.num())
+	if math.Abs(result) >= maxExactFloat {
+		if base, exp, ok := exactInts(l, r); ok && exp >= 0 {
+			iresult := int64(1)
+			for ok && exp > 0 {
+				if exp&1 != 0 {
+					iresult, ok = multiplyInts(iresult, base)
+				}
+				exp >>= 1
+				if ok && exp > 0 {
+					base, ok = multiplyInts(base, base)
+				}
+			}
+			if ok {
+				return intNum(iresult)
+			}
+		}
+	}
+	return num(result)
+}
+
+func negateValue(v value) value {
+	n := v.num()
+	if math.Abs(n) >= maxExactFloat {
+		if i, ok := v.toNum().exactInt(); ok && i != math.MinInt64 {
+			return intNum(-i)
+		}
+	}
+	return num(-n)
+}
+
+// Return a*b, and false if the result overflows an int64.
+func multiplyInts(a, b int64) (int64, bool) {
+	product := a * b
+	if a != 0 && (product/a != b || a == -1 && b == math.MinInt64) {
+		return 0, false
+	}
+	return product, true
+}
+
+// Parse the decimal integer at the start of s exactly, returning false if s
+// doesn't start with an integer (or it's a float like "1.5" or "1e3"), or
+// it's out of range of an int64.
+func parseIntPrefix(s string) (int64, bool) {
+	i := 0
+	for i < len(s) && asciiSpace[s[i]] != 0 {
+		i++
+	}
+	start := i
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		i++
+	}
+	digitStart := i
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	if i == digitStart || i < len(s) && (s[i] == '.' || s[i] == 'e' || s[i] == 'E' || s[i] == 'x' || s[i] == 'X') {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(s[start:i], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
 var asciiSpace = [256]uint8{'\t': 1, '\n': 1, '\v': 1, '\f': 1, '\r': 1, ' ': 1}
 
 // Like strconv.ParseFloat, but parses at the start of string and
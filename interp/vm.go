@@ -143,6 +143,36 @@ func (p *interp) execute(code []compiler.Opcode) error {
 			_, ok := array[index]
 			p.replaceTop(boolean(ok))
 
+		case compiler.RefGlobal, compiler.RefLocal, compiler.RefIndex, compiler.Deref, compiler.InRef,
+			compiler.ArrayGlobalChecked, compiler.ArrayLocalChecked,
+			compiler.AssignRef, compiler.DeleteRef, compiler.IncrRef, compiler.AugAssignRef, compiler.ForInRef,
+			compiler.CallTypeofRef, compiler.CallLengthRef, compiler.CallSplitRef, compiler.CallSplitSepRef, compiler.GetlineRef:
+			// Subarray opcodes are handled in a separate function
+			// to keep this one small enough for the Go compiler to inline
+			// the stack operations.
+			var err error
+			ip, err = p.executeRef(op, code, ip)
+			if err != nil {
+				return err
+			}
+
+		case compiler.BigNum, compiler.Big:
+			// Bignum mode has its own number and arithmetic opcodes (see
+			// compiler.CompileBignum), so the ones here don't need to check
+			// for it.
+			err := p.executeBig(op, code[ip])
+			ip++
+			if err != nil {
+				return err
+			}
+
+		case compiler.AssignFieldByName, compiler.IncrFieldByName, compiler.AugAssignFieldByName:
+			var err error
+			ip, err = p.executeFieldByName(op, code, ip)
+			if err != nil {
+				return err
+			}
+
 		case compiler.AssignField:
 			right, index := p.popTwo()
 			err := p.setField(int(index.num()), p.toString(right))
@@ -200,23 +230,26 @@ func (p *interp) execute(code []compiler.Opcode) error {
 			ip += 2
 			array := p.array(resolver.Scope(arrayScope), int(arrayIndex))
 			index := p.toString(p.pop())
-			delete(array, index)
+			p.deleteItem(array, index)
 
 		case compiler.DeleteAll:
 			arrayScope := code[ip]
 			arrayIndex := code[ip+1]
 			ip += 2
 			array := p.array(resolver.Scope(arrayScope), int(arrayIndex))
-			for k := range array {
-				delete(array, k)
-			}
+			p.clearArray(array)
 
 		case compiler.IncrField:
 			amount := code[ip]
 			ip++
 			index := int(p.pop().num())
 			v := p.getField(index)
-			err := p.setField(index, p.toString(num(v.num()+float64(amount))))
+			if n := v.num(); math.Abs(n)+1 < maxExactFloat {
+				v = num(n + float64(amount))
+			} else {
+				v = increment(v, amount)
+			}
+			err := p.setField(index, p.toString(v))
 			if err != nil {
 				return err
 			}
@@ -225,20 +258,35 @@ func (p *interp) execute(code []compiler.Opcode) error {
 			amount := code[ip]
 			index := code[ip+1]
 			ip += 2
-			p.globals[index] = num(p.globals[index].num() + float64(amount))
+			v := p.globals[index]
+			if n := v.num(); math.Abs(n)+1 < maxExactFloat {
+				p.globals[index] = num(n + float64(amount))
+			} else {
+				p.globals[index] = increment(v, amount)
+			}
 
 		case compiler.IncrLocal:
 			amount := code[ip]
 			index := code[ip+1]
 			ip += 2
-			p.frame[index] = num(p.frame[index].num() + float64(amount))
+			v := p.frame[index]
+			if n := v.num(); math.Abs(n)+1 < maxExactFloat {
+				p.frame[index] = num(n + float64(amount))
+			} else {
+				p.frame[index] = increment(v, amount)
+			}
 
 		case compiler.IncrSpecial:
 			amount := code[ip]
 			index := int(code[ip+1])
 			ip += 2
 			v := p.getSpecial(index)
-			err := p.setSpecial(index, num(v.num()+float64(amount)))
+			if n := v.num(); math.Abs(n)+1 < maxExactFloat {
+				v = num(n + float64(amount))
+			} else {
+				v = increment(v, amount)
+			}
+			err := p.setSpecial(index, v)
 			if err != nil {
 				return err
 			}
@@ -249,7 +297,12 @@ func (p *interp) execute(code []compiler.Opcode) error {
 			ip += 2
 			array := p.arrays[arrayIndex]
 			index := p.toString(p.pop())
-			array[index] = num(array[index].num() + float64(amount))
+			v := array[index]
+			if n := v.num(); math.Abs(n)+1 < maxExactFloat {
+				array[index] = num(n + float64(amount))
+			} else {
+				array[index] = increment(v, amount)
+			}
 
 		case compiler.IncrArrayLocal:
 			amount := code[ip]
@@ -257,7 +310,12 @@ func (p *interp) execute(code []compiler.Opcode) error {
 			ip += 2
 			array := p.localArray(int(arrayIndex))
 			index := p.toString(p.pop())
-			array[index] = num(array[index].num() + float64(amount))
+			v := array[index]
+			if n := v.num(); math.Abs(n)+1 < maxExactFloat {
+				array[index] = num(n + float64(amount))
+			} else {
+				array[index] = increment(v, amount)
+			}
 
 		case compiler.AugAssignField:
 			operation := compiler.AugOp(code[ip])
@@ -351,42 +409,69 @@ func (p *interp) execute(code []compiler.Opcode) error {
 
 		case compiler.Add:
 			l, r := p.peekPop()
-			p.replaceTop(num(l.num() + r.num()))
+			ln, rn := l.num(), r.num()
+			if math.Abs(ln)+math.Abs(rn) < maxExactFloat {
+				p.replaceTop(num(ln + rn))
+			} else {
+				p.replaceTop(addExact(l, r, ln+rn))
+			}
 
 		case compiler.Subtract:
 			l, r := p.peekPop()
-			p.replaceTop(num(l.num() - r.num()))
+			ln, rn := l.num(), r.num()
+			if math.Abs(ln)+math.Abs(rn) < maxExactFloat {
+				p.replaceTop(num(ln - rn))
+			} else {
+				p.replaceTop(subtractExact(l, r, ln-rn))
+			}
 
 		case compiler.Multiply:
 			l, r := p.peekPop()
-			p.replaceTop(num(l.num() * r.num()))
+			ln, rn := l.num(), r.num()
+			if product := ln * rn; math.Abs(product) < maxExactFloat {
+				p.replaceTop(num(product))
+			} else {
+				p.replaceTop(multiplyExact(l, r, product))
+			}
 
 		case compiler.Divide:
 			l, r := p.peekPop()
-			rf := r.num()
-			if rf == 0.0 {
+			ln, rn := l.num(), r.num()
+			if rn == 0.0 {
 				return newError("division by zero")
 			}
-			p.replaceTop(num(l.num() / rf))
+			if math.Abs(ln) < maxExactFloat && math.Abs(rn) < maxExactFloat {
+				p.replaceTop(num(ln / rn))
+			} else {
+				p.replaceTop(divideValues(l, r))
+			}
 
 		case compiler.Power:
 			l, r := p.peekPop()
-			p.replaceTop(num(math.Pow(l.num(), r.num())))
+			if result := math.Pow(l.num(), r.num()); math.Abs(result) < maxExactFloat {
+				p.replaceTop(num(result))
+			} else {
+				p.replaceTop(powerValues(l, r))
+			}
 
 		case compiler.Modulo:
 			l, r := p.peekPop()
-			rf := r.num()
-			if rf == 0.0 {
+			ln, rn := l.num(), r.num()
+			if rn == 0.0 {
 				return newError("division by zero in mod")
 			}
-			p.replaceTop(num(math.Mod(l.num(), rf)))
+			if math.Abs(ln) < maxExactFloat && math.Abs(rn) < maxExactFloat {
+				p.replaceTop(num(math.Mod(ln, rn)))
+			} else {
+				p.replaceTop(moduloValues(l, r))
+			}
 
 		case compiler.Equals:
 			l, r := p.peekPop()
 			ln, lIsStr := l.isTrueStr()
 			rn, rIsStr := r.isTrueStr()
-			if lIsStr || rIsStr {
-				p.replaceTop(boolean(p.toString(l) == p.toString(r)))
+			if lIsStr || rIsStr || largeNums(ln, rn) {
+				p.replaceTop(boolean(p.compare(l, r) == 0))
 			} else {
 				p.replaceTop(boolean(ln == rn))
 			}
@@ -395,8 +480,8 @@ func (p *interp) execute(code []compiler.Opcode) error {
 			l, r := p.peekPop()
 			ln, lIsStr := l.isTrueStr()
 			rn, rIsStr := r.isTrueStr()
-			if lIsStr || rIsStr {
-				p.replaceTop(boolean(p.toString(l) != p.toString(r)))
+			if lIsStr || rIsStr || largeNums(ln, rn) {
+				p.replaceTop(boolean(p.compare(l, r) != 0))
 			} else {
 				p.replaceTop(boolean(ln != rn))
 			}
@@ -405,8 +490,8 @@ func (p *interp) execute(code []compiler.Opcode) error {
 			l, r := p.peekPop()
 			ln, lIsStr := l.isTrueStr()
 			rn, rIsStr := r.isTrueStr()
-			if lIsStr || rIsStr {
-				p.replaceTop(boolean(p.toString(l) < p.toString(r)))
+			if lIsStr || rIsStr || largeNums(ln, rn) {
+				p.replaceTop(boolean(p.compare(l, r) < 0))
 			} else {
 				p.replaceTop(boolean(ln < rn))
 			}
@@ -415,8 +500,8 @@ func (p *interp) execute(code []compiler.Opcode) error {
 			l, r := p.peekPop()
 			ln, lIsStr := l.isTrueStr()
 			rn, rIsStr := r.isTrueStr()
-			if lIsStr || rIsStr {
-				p.replaceTop(boolean(p.toString(l) > p.toString(r)))
+			if lIsStr || rIsStr || largeNums(ln, rn) {
+				p.replaceTop(boolean(p.compare(l, r) > 0))
 			} else {
 				p.replaceTop(boolean(ln > rn))
 			}
@@ -425,8 +510,8 @@ func (p *interp) execute(code []compiler.Opcode) error {
 			l, r := p.peekPop()
 			ln, lIsStr := l.isTrueStr()
 			rn, rIsStr := r.isTrueStr()
-			if lIsStr || rIsStr {
-				p.replaceTop(boolean(p.toString(l) <= p.toString(r)))
+			if lIsStr || rIsStr || largeNums(ln, rn) {
+				p.replaceTop(boolean(p.compare(l, r) <= 0))
 			} else {
 				p.replaceTop(boolean(ln <= rn))
 			}
@@ -435,8 +520,8 @@ func (p *interp) execute(code []compiler.Opcode) error {
 			l, r := p.peekPop()
 			ln, lIsStr := l.isTrueStr()
 			rn, rIsStr := r.isTrueStr()
-			if lIsStr || rIsStr {
-				p.replaceTop(boolean(p.toString(l) >= p.toString(r)))
+			if lIsStr || rIsStr || largeNums(ln, rn) {
+				p.replaceTop(boolean(p.compare(l, r) >= 0))
 			} else {
 				p.replaceTop(boolean(ln >= rn))
 			}
@@ -478,14 +563,23 @@ func (p *interp) execute(code []compiler.Opcode) error {
 			p.replaceTop(boolean(!p.peekTop().boolean()))
 
 		case compiler.UnaryMinus:
-			p.replaceTop(num(-p.peekTop().num()))
+			v := p.peekTop()
+			if n := v.num(); math.Abs(n) < maxExactFloat {
+				p.replaceTop(num(-n))
+			} else {
+				p.replaceTop(negateValue(v))
+			}
 
 		case compiler.UnaryPlus:
-			p.replaceTop(num(p.peekTop().num()))
+			p.replaceTop(p.peekTop().toNum())
 
 		case compiler.Boolean:
 			p.replaceTop(boolean(p.peekTop().boolean()))
 
+		case compiler.Switch:
+			table := p.compiled.Switches[code[ip]]
+			ip += 1 + p.switchOffset(table, p.pop())
+
 		case compiler.Jump:
 			offset := code[ip]
 			ip += 1 + int(offset)
@@ -513,8 +607,8 @@ func (p *interp) execute(code []compiler.Opcode) error {
 			ln, lIsStr := l.isTrueStr()
 			rn, rIsStr := r.isTrueStr()
 			var b bool
-			if lIsStr || rIsStr {
-				b = p.toString(l) == p.toString(r)
+			if lIsStr || rIsStr || largeNums(ln, rn) {
+				b = p.compare(l, r) == 0
 			} else {
 				b = ln == rn
 			}
@@ -529,8 +623,8 @@ func (p *interp) execute(code []compiler.Opcode) error {
 			ln, lIsStr := l.isTrueStr()
 			rn, rIsStr := r.isTrueStr()
 			var b bool
-			if lIsStr || rIsStr {
-				b = p.toString(l) != p.toString(r)
+			if lIsStr || rIsStr || largeNums(ln, rn) {
+				b = p.compare(l, r) != 0
 			} else {
 				b = ln != rn
 			}
@@ -545,8 +639,8 @@ func (p *interp) execute(code []compiler.Opcode) error {
 			ln, lIsStr := l.isTrueStr()
 			rn, rIsStr := r.isTrueStr()
 			var b bool
-			if lIsStr || rIsStr {
-				b = p.toString(l) < p.toString(r)
+			if lIsStr || rIsStr || largeNums(ln, rn) {
+				b = p.compare(l, r) < 0
 			} else {
 				b = ln < rn
 			}
@@ -561,8 +655,8 @@ func (p *interp) execute(code []compiler.Opcode) error {
 			ln, lIsStr := l.isTrueStr()
 			rn, rIsStr := r.isTrueStr()
 			var b bool
-			if lIsStr || rIsStr {
-				b = p.toString(l) > p.toString(r)
+			if lIsStr || rIsStr || largeNums(ln, rn) {
+				b = p.compare(l, r) > 0
 			} else {
 				b = ln > rn
 			}
@@ -577,8 +671,8 @@ func (p *interp) execute(code []compiler.Opcode) error {
 			ln, lIsStr := l.isTrueStr()
 			rn, rIsStr := r.isTrueStr()
 			var b bool
-			if lIsStr || rIsStr {
-				b = p.toString(l) <= p.toString(r)
+			if lIsStr || rIsStr || largeNums(ln, rn) {
+				b = p.compare(l, r) <= 0
 			} else {
 				b = ln <= rn
 			}
@@ -593,8 +687,8 @@ func (p *interp) execute(code []compiler.Opcode) error {
 			ln, lIsStr := l.isTrueStr()
 			rn, rIsStr := r.isTrueStr()
 			var b bool
-			if lIsStr || rIsStr {
-				b = p.toString(l) >= p.toString(r)
+			if lIsStr || rIsStr || largeNums(ln, rn) {
+				b = p.compare(l, r) >= 0
 			} else {
 				b = ln >= rn
 			}
@@ -625,25 +719,9 @@ func (p *interp) execute(code []compiler.Opcode) error {
 			ip += 5
 			array := p.array(resolver.Scope(arrayScope), int(arrayIndex))
 			loopCode := code[ip : ip+int(offset)]
-			for index := range array {
-				switch resolver.Scope(varScope) {
-				case resolver.Global:
-					p.globals[varIndex] = str(index)
-				case resolver.Local:
-					p.frame[varIndex] = str(index)
-				default: // resolver.Special
-					err := p.setSpecial(int(varIndex), str(index))
-					if err != nil {
-						return err
-					}
-				}
-				err := p.execute(loopCode)
-				if err == errBreak {
-					break
-				}
-				if err != nil {
-					return err
-				}
+			err := p.forIn(resolver.Scope(varScope), int(varIndex), array, loopCode)
+			if err != nil {
+				return err
 			}
 			ip += int(offset)
 
@@ -670,7 +748,8 @@ func (p *interp) execute(code []compiler.Opcode) error {
 			arrayIndex := code[ip+1]
 			ip += 2
 			s := p.toString(p.peekTop())
-			n, err := p.split(s, resolver.Scope(arrayScope), int(arrayIndex), p.fieldSep, false, p.inputMode)
+			array := p.array(resolver.Scope(arrayScope), int(arrayIndex))
+			n, err := p.split(s, array, p.fieldSep, false, p.inputMode)
 			if err != nil {
 				return err
 			}
@@ -682,8 +761,9 @@ func (p *interp) execute(code []compiler.Opcode) error {
 			sepIsRegex := code[ip+2] != 0
 			ip += 3
 			s, fieldSep := p.peekPop()
+			array := p.array(resolver.Scope(arrayScope), int(arrayIndex))
 			// 3-argument form of split() ignores input mode
-			n, err := p.split(p.toString(s), resolver.Scope(arrayScope), int(arrayIndex), p.toString(fieldSep), sepIsRegex, DefaultMode)
+			n, err := p.split(p.toString(s), array, p.toString(fieldSep), sepIsRegex, DefaultMode)
 			if err != nil {
 				return err
 			}
@@ -699,12 +779,30 @@ func (p *interp) execute(code []compiler.Opcode) error {
 			}
 			p.push(str(s))
 
+		case compiler.CallPatsplit, compiler.CallAsort, compiler.CallAsorti, compiler.CallPrintrow:
+			numArrays := int(code[ip])
+			arrayArgs := code[ip+1 : ip+1+2*numArrays]
+			ip += 1 + 2*numArrays
+			var err error
+			switch op {
+			case compiler.CallPatsplit:
+				err = p.callPatsplit(arrayArgs)
+			case compiler.CallPrintrow:
+				err = p.callPrintrow(arrayArgs, lexer.Token(code[ip]))
+				ip++
+			default:
+				err = p.callAsort(arrayArgs, op == compiler.CallAsorti)
+			}
+			if err != nil {
+				return err
+			}
+
 		case compiler.CallUser:
 			funcIndex := code[ip]
 			numArrayArgs := int(code[ip+1])
 			ip += 2
 
-			f := p.program.Compiled.Functions[funcIndex]
+			f := p.compiled.Functions[funcIndex]
 			if p.callDepth >= maxCallDepth {
 				return newError("calling %q exceeded maximum call depth of %d", f.Name, maxCallDepth)
 			}
@@ -714,27 +812,23 @@ func (p *interp) execute(code []compiler.Opcode) error {
 			p.frame = p.peekSlice(f.NumScalars)
 
 			// Handle array arguments
-			var arrays []int
-			for j := 0; j < numArrayArgs; j++ {
-				arrayScope := resolver.Scope(code[ip])
-				arrayIndex := int(code[ip+1])
-				ip += 2
-				arrays = append(arrays, p.arrayIndex(arrayScope, arrayIndex))
-			}
+			arrayArgs := code[ip : ip+2*numArrayArgs]
+			ip += 2 * numArrayArgs
 			oldArraysLen := len(p.arrays)
-			for j := numArrayArgs; j < f.NumArrays; j++ {
-				arrays = append(arrays, len(p.arrays))
-				p.arrays = append(p.arrays, make(map[string]value))
+			arrays, numRefs, err := p.callArrays(arrayArgs, f.NumScalars, f.NumArrays)
+			if err != nil {
+				return err
 			}
 			p.localArrays = append(p.localArrays, arrays)
 
 			// Execute the function!
 			p.callDepth++
-			err := p.execute(f.Body)
+			err = p.execute(f.Body)
 			p.callDepth--
 
-			// Pop the locals off the stack
-			p.popSlice(f.NumScalars)
+			// Pop the locals (and any subarray references) off the stack
+			p.freeLocalSubarrays(numArrayArgs)
+			p.popSlice(f.NumScalars + numRefs)
 			p.frame = oldFrame
 			p.localArrays = p.localArrays[:len(p.localArrays)-1]
 			p.arrays = p.arrays[:oldArraysLen]
@@ -759,6 +853,18 @@ func (p *interp) execute(code []compiler.Opcode) error {
 			}
 			p.push(r)
 
+		case compiler.CallIndirect:
+			numArgs := int(code[ip])
+			argOpcodes := code[ip+1 : ip+1+2*numArgs]
+			ip += 1 + 2*numArgs
+
+			name := p.toString(p.pop())
+			r, err := p.callIndirect(name, argOpcodes)
+			if err != nil {
+				return err
+			}
+			p.push(r)
+
 		case compiler.Return:
 			v := p.pop()
 			return returnValue{v}
@@ -827,129 +933,428 @@ func (p *interp) execute(code []compiler.Opcode) error {
 				return err
 			}
 
-		case compiler.Getline:
-			redirect := lexer.Token(code[ip])
-			ip++
-
-			ret, line, err := p.getline(redirect)
+		case compiler.Getline, compiler.GetlineField, compiler.GetlineGlobal,
+			compiler.GetlineLocal, compiler.GetlineSpecial, compiler.GetlineArray:
+			// Also handled separately to keep this function small.
+			var err error
+			ip, err = p.executeGetline(op, code, ip)
 			if err != nil {
 				return err
 			}
-			if ret == 1 {
-				p.setLine(line, false)
-			}
-			p.push(num(ret))
+		}
+	}
 
-		case compiler.GetlineField:
-			redirect := lexer.Token(code[ip])
-			ip++
+	return nil
+}
 
-			ret, line, err := p.getline(redirect)
-			if err != nil {
-				return err
-			}
-			if ret == 1 {
-				err := p.setField(0, line)
-				if err != nil {
-					return err
-				}
-			}
-			p.push(num(ret))
+// Execute one of the getline opcodes (see execute).
+func (p *interp) executeGetline(op compiler.Opcode, code []compiler.Opcode, ip int) (int, error) {
+	switch op {
+	case compiler.Getline:
+		redirect := lexer.Token(code[ip])
+		ip++
 
-		case compiler.GetlineGlobal:
-			redirect := lexer.Token(code[ip])
-			index := code[ip+1]
-			ip += 2
+		ret, line, err := p.getline(redirect)
+		if err != nil {
+			return ip, err
+		}
+		if ret == 1 {
+			p.setLine(line, false)
+		}
+		p.push(num(ret))
 
-			ret, line, err := p.getline(redirect)
+	case compiler.GetlineField:
+		redirect := lexer.Token(code[ip])
+		ip++
+
+		ret, line, err := p.getline(redirect)
+		if err != nil {
+			return ip, err
+		}
+		if ret == 1 {
+			err := p.setField(0, line)
 			if err != nil {
-				return err
+				return ip, err
 			}
-			if ret == 1 {
-				p.globals[index] = numStr(line)
-			}
-			p.push(num(ret))
+		}
+		p.push(num(ret))
 
-		case compiler.GetlineLocal:
-			redirect := lexer.Token(code[ip])
-			index := code[ip+1]
-			ip += 2
+	case compiler.GetlineGlobal:
+		redirect := lexer.Token(code[ip])
+		index := code[ip+1]
+		ip += 2
 
-			ret, line, err := p.getline(redirect)
-			if err != nil {
-				return err
-			}
-			if ret == 1 {
-				p.frame[index] = numStr(line)
-			}
-			p.push(num(ret))
+		ret, line, err := p.getline(redirect)
+		if err != nil {
+			return ip, err
+		}
+		if ret == 1 {
+			p.globals[index] = numStr(line)
+		}
+		p.push(num(ret))
 
-		case compiler.GetlineSpecial:
-			redirect := lexer.Token(code[ip])
-			index := code[ip+1]
-			ip += 2
+	case compiler.GetlineLocal:
+		redirect := lexer.Token(code[ip])
+		index := code[ip+1]
+		ip += 2
 
-			ret, line, err := p.getline(redirect)
-			if err != nil {
-				return err
-			}
-			if ret == 1 {
-				err := p.setSpecial(int(index), numStr(line))
-				if err != nil {
-					return err
-				}
-			}
-			p.push(num(ret))
+		ret, line, err := p.getline(redirect)
+		if err != nil {
+			return ip, err
+		}
+		if ret == 1 {
+			p.frame[index] = numStr(line)
+		}
+		p.push(num(ret))
 
-		case compiler.GetlineArray:
-			redirect := lexer.Token(code[ip])
-			arrayScope := code[ip+1]
-			arrayIndex := code[ip+2]
-			ip += 3
+	case compiler.GetlineSpecial:
+		redirect := lexer.Token(code[ip])
+		index := code[ip+1]
+		ip += 2
 
-			ret, line, err := p.getline(redirect)
+		ret, line, err := p.getline(redirect)
+		if err != nil {
+			return ip, err
+		}
+		if ret == 1 {
+			err := p.setSpecial(int(index), numStr(line))
 			if err != nil {
-				return err
-			}
-			index := p.toString(p.peekTop())
-			if ret == 1 {
-				array := p.array(resolver.Scope(arrayScope), int(arrayIndex))
-				array[index] = numStr(line)
+				return ip, err
 			}
-			p.replaceTop(num(ret))
 		}
+		p.push(num(ret))
+
+	case compiler.GetlineArray:
+		redirect := lexer.Token(code[ip])
+		arrayScope := code[ip+1]
+		arrayIndex := code[ip+2]
+		ip += 3
+
+		ret, line, err := p.getline(redirect)
+		if err != nil {
+			return ip, err
+		}
+		index := p.toString(p.peekTop())
+		if ret == 1 {
+			array := p.array(resolver.Scope(arrayScope), int(arrayIndex))
+			array[index] = numStr(line)
+		}
+		p.replaceTop(num(ret))
 	}
+	return ip, nil
+}
 
+// Execute one of the opcodes that use a subarray reference, or that read
+// items of arrays that may contain subarrays (see execute).
+func (p *interp) executeRef(op compiler.Opcode, code []compiler.Opcode, ip int) (int, error) {
+	switch op {
+	case compiler.ArrayGlobalChecked, compiler.ArrayLocalChecked:
+		arrayIndex := code[ip]
+		ip++
+		var array map[string]value
+		if op == compiler.ArrayGlobalChecked {
+			array = p.arrays[arrayIndex]
+		} else {
+			array = p.localArray(int(arrayIndex))
+		}
+		index := p.toString(p.peekTop())
+		v := arrayGet(array, index)
+		if v.typ == typeArray {
+			return ip, newError("can't use subarray %q as scalar", index)
+		}
+		p.replaceTop(v)
+
+	case compiler.RefGlobal:
+		arrayIndex := code[ip]
+		ip++
+		index := p.toString(p.peekTop())
+		p.replaceTop(arrayRef(int(arrayIndex), index))
+
+	case compiler.RefLocal:
+		arrayIndex := code[ip]
+		ip++
+		index := p.toString(p.peekTop())
+		p.replaceTop(arrayRef(p.arrayIndex(resolver.Local, int(arrayIndex)), index))
+
+	case compiler.RefIndex:
+		r, index := p.peekPop()
+		sub, err := p.subArrayItem(r)
+		if err != nil {
+			return ip, err
+		}
+		p.replaceTop(subarrayRef(sub, p.toString(index)))
+
+	case compiler.Deref:
+		r := p.peekTop()
+		v := arrayGet(p.refArray(r), r.s)
+		if v.typ == typeArray {
+			return ip, newError("can't use subarray %q as scalar", r.s)
+		}
+		p.replaceTop(v)
+
+	case compiler.InRef:
+		r := p.peekTop()
+		_, ok := p.refArray(r)[r.s]
+		p.replaceTop(boolean(ok))
+
+	case compiler.AssignRef:
+		v, r := p.popTwo()
+		p.refArray(r)[r.s] = v
+
+	case compiler.DeleteRef:
+		r := p.pop()
+		p.deleteItem(p.refArray(r), r.s)
+
+	case compiler.IncrRef:
+		amount := code[ip]
+		ip++
+		r := p.pop()
+		array := p.refArray(r)
+		v := array[r.s]
+		if v.typ == typeArray {
+			return ip, newError("can't use subarray %q as scalar", r.s)
+		}
+		array[r.s] = increment(v, amount)
+
+	case compiler.AugAssignRef:
+		operation := compiler.AugOp(code[ip])
+		ip++
+		right, r := p.popTwo()
+		array := p.refArray(r)
+		left := array[r.s]
+		if left.typ == typeArray {
+			return ip, newError("can't use subarray %q as scalar", r.s)
+		}
+		v, err := p.augAssignOp(operation, left, right)
+		if err != nil {
+			return ip, err
+		}
+		array[r.s] = v
+
+	case compiler.ForInRef:
+		varScope := code[ip]
+		varIndex := code[ip+1]
+		offset := code[ip+2]
+		ip += 3
+		array, err := p.subArray(p.pop())
+		if err != nil {
+			return ip, err
+		}
+		loopCode := code[ip : ip+int(offset)]
+		err = p.forIn(resolver.Scope(varScope), int(varIndex), array, loopCode)
+		if err != nil {
+			return ip, err
+		}
+		ip += int(offset)
+
+	case compiler.CallTypeofRef:
+		r := p.peekTop()
+		v, ok := p.refArray(r)[r.s]
+		if !ok {
+			p.replaceTop(str("untyped"))
+			break
+		}
+		p.replaceTop(str(v.typeName()))
+
+	case compiler.CallLengthRef:
+		r := p.peekTop()
+		v := arrayGet(p.refArray(r), r.s)
+		if v.typ == typeArray {
+			p.replaceTop(num(float64(len(p.subarray(v)))))
+			break
+		}
+		s := p.toString(v)
+		var length int
+		if p.chars {
+			length = utf8.RuneCountInString(s)
+		} else {
+			length = len(s)
+		}
+		p.replaceTop(num(float64(length)))
+
+	case compiler.CallSplitRef:
+		s, r := p.peekPop()
+		array, err := p.subArray(r)
+		if err != nil {
+			return ip, err
+		}
+		n, err := p.split(p.toString(s), array, p.fieldSep, false, p.inputMode)
+		if err != nil {
+			return ip, err
+		}
+		p.replaceTop(num(float64(n)))
+
+	case compiler.CallSplitSepRef:
+		sepIsRegex := code[ip] != 0
+		ip++
+		r, fieldSep := p.popTwo()
+		s := p.peekTop()
+		array, err := p.subArray(r)
+		if err != nil {
+			return ip, err
+		}
+		n, err := p.split(p.toString(s), array, p.toString(fieldSep), sepIsRegex, DefaultMode)
+		if err != nil {
+			return ip, err
+		}
+		p.replaceTop(num(float64(n)))
+
+	case compiler.GetlineRef:
+		redirect := lexer.Token(code[ip])
+		ip++
+
+		ret, line, err := p.getline(redirect)
+		if err != nil {
+			return ip, err
+		}
+		if ret == 1 {
+			r := p.peekTop()
+			p.refArray(r)[r.s] = numStr(line)
+		}
+		p.replaceTop(num(ret))
+	}
+	return ip, nil
+}
+
+// Execute a bignum mode opcode (see execute) with argument arg.
+func (p *interp) executeBig(op, arg compiler.Opcode) error {
+	if op == compiler.BigNum {
+		p.push(p.bigNums[arg])
+		return nil
+	}
+	switch arg {
+	case compiler.UnaryMinus:
+		p.replaceTop(p.bigNegate(p.peekTop()))
+		return nil
+	case compiler.UnaryPlus:
+		p.replaceTop(p.bigToNum(p.peekTop()))
+		return nil
+	}
+
+	l, r := p.peekPop()
+	var augOp compiler.AugOp
+	switch arg {
+	case compiler.Add:
+		augOp = compiler.AugOpAdd
+	case compiler.Subtract:
+		augOp = compiler.AugOpSub
+	case compiler.Multiply:
+		augOp = compiler.AugOpMul
+	case compiler.Divide:
+		if r.num() == 0.0 {
+			return newError("division by zero")
+		}
+		augOp = compiler.AugOpDiv
+	case compiler.Power:
+		augOp = compiler.AugOpPow
+	case compiler.Modulo:
+		if r.num() == 0.0 {
+			return newError("division by zero in mod")
+		}
+		augOp = compiler.AugOpMod
+	default:
+		// Comparison operators
+		c := p.compare(l, r)
+		var b bool
+		switch arg {
+		case compiler.Equals:
+			b = c == 0
+		case compiler.NotEquals:
+			b = c != 0
+		case compiler.Less:
+			b = c < 0
+		case compiler.Greater:
+			b = c > 0
+		case compiler.LessOrEqual:
+			b = c <= 0
+		default: // GreaterOrEqual
+			b = c >= 0
+		}
+		p.replaceTop(boolean(b))
+		return nil
+	}
+	p.replaceTop(p.bigArith(augOp, l, r))
 	return nil
 }
 
+// Execute one of the opcodes that assign to a named field (see execute).
+func (p *interp) executeFieldByName(op compiler.Opcode, code []compiler.Opcode, ip int) (int, error) {
+	switch op {
+	case compiler.AssignFieldByName:
+		right, name := p.popTwo()
+		err := p.setFieldByName(p.toString(name), p.toString(right))
+		if err != nil {
+			return ip, err
+		}
+
+	case compiler.IncrFieldByName:
+		amount := code[ip]
+		ip++
+		index, err := p.fieldIndexByName(p.toString(p.pop()), true)
+		if err != nil {
+			return ip, err
+		}
+		v := p.getField(index)
+		err = p.setField(index, p.toString(increment(v, amount)))
+		if err != nil {
+			return ip, err
+		}
+
+	case compiler.AugAssignFieldByName:
+		operation := compiler.AugOp(code[ip])
+		ip++
+		right, name := p.popTwo()
+		index, err := p.fieldIndexByName(p.toString(name), true)
+		if err != nil {
+			return ip, err
+		}
+		field := p.getField(index)
+		v, err := p.augAssignOp(operation, field, right)
+		if err != nil {
+			return ip, err
+		}
+		err = p.setField(index, p.toString(v))
+		if err != nil {
+			return ip, err
+		}
+	}
+	return ip, nil
+}
+
 func (p *interp) callBuiltin(builtinOp compiler.BuiltinOp) error {
 	switch builtinOp {
+	case compiler.BuiltinAnd:
+		l, r := p.peekPop()
+		args, err := bitwiseArgs("and", l, r)
+		if err != nil {
+			return err
+		}
+		p.replaceTop(bitwiseResult(args[0] & args[1]))
+
 	case compiler.BuiltinAtan2:
 		y, x := p.peekPop()
 		p.replaceTop(num(math.Atan2(y.num(), x.num())))
 
 	case compiler.BuiltinClose:
-		var err error
-		code := -1
 		name := p.toString(p.peekTop())
-		if stream := p.inputStreams[name]; stream != nil {
-			// Close input stream
-			delete(p.inputStreams, name)
-			delete(p.scanners, name)
-			err = stream.Close()
-			code = stream.ExitCode()
-		} else if stream := p.outputStreams[name]; stream != nil {
-			// Close output stream
-			delete(p.outputStreams, name)
-			err = stream.Close()
-			code = stream.ExitCode()
-		}
+		p.replaceTop(num(float64(p.closeStream(name))))
+
+	case compiler.BuiltinCloseHow:
+		how := p.toString(p.pop())
+		name := p.toString(p.peekTop())
+		code, err := p.closeCoprocess(name, how)
 		if err != nil {
-			p.printErrorf("error closing %q: %v\n", name, err)
+			return err
 		}
 		p.replaceTop(num(float64(code)))
 
+	case compiler.BuiltinCompl:
+		args, err := bitwiseArgs("compl", p.peekTop())
+		if err != nil {
+			return err
+		}
+		p.replaceTop(bitwiseResult(compl(args[0])))
+
 	case compiler.BuiltinCos:
 		p.replaceTop(num(math.Cos(p.peekTop().num())))
 
@@ -980,6 +1385,14 @@ func (p *interp) callBuiltin(builtinOp compiler.BuiltinOp) error {
 			p.push(num(0))
 		}
 
+	case compiler.BuiltinGensub:
+		args := p.popSlice(4)
+		out, err := p.gensub(p.toString(args[0]), p.toString(args[1]), args[2], p.toString(args[3]))
+		if err != nil {
+			return err
+		}
+		p.push(str(out))
+
 	case compiler.BuiltinGsub:
 		regex, repl, in := p.peekPeekPop()
 		out, n, err := p.sub(p.toString(regex), p.toString(repl), p.toString(in), true)
@@ -1003,7 +1416,15 @@ func (p *interp) callBuiltin(builtinOp compiler.BuiltinOp) error {
 		p.replaceTop(num(float64(awkIndex)))
 
 	case compiler.BuiltinInt:
-		p.replaceTop(num(float64(int64(p.peekTop().num()))))
+		if p.bignum {
+			p.replaceTop(p.bigInt(p.peekTop()))
+		} else {
+			n := p.peekTop().toNum()
+			if _, ok := n.exactInt(); !ok {
+				n = num(float64(int64(n.n)))
+			}
+			p.replaceTop(n)
+		}
 
 	case compiler.BuiltinLength:
 		var length int
@@ -1027,6 +1448,14 @@ func (p *interp) callBuiltin(builtinOp compiler.BuiltinOp) error {
 	case compiler.BuiltinLog:
 		p.replaceTop(num(math.Log(p.peekTop().num())))
 
+	case compiler.BuiltinLshift:
+		l, r := p.peekPop()
+		args, err := bitwiseArgs("lshift", l, r)
+		if err != nil {
+			return err
+		}
+		p.replaceTop(lshift(args[0], args[1]))
+
 	case compiler.BuiltinMatch:
 		sValue, regex := p.peekPop()
 		s := p.toString(sValue)
@@ -1047,18 +1476,46 @@ func (p *interp) callBuiltin(builtinOp compiler.BuiltinOp) error {
 		}
 		p.replaceTop(p.matchStart)
 
+	case compiler.BuiltinMktime:
+		spec, utc := p.peekPop()
+		loc := time.Local
+		if utc.boolean() {
+			loc = time.UTC
+		}
+		p.replaceTop(num(float64(mktime(p.toString(spec), loc))))
+
+	case compiler.BuiltinOr:
+		l, r := p.peekPop()
+		args, err := bitwiseArgs("or", l, r)
+		if err != nil {
+			return err
+		}
+		p.replaceTop(bitwiseResult(args[0] | args[1]))
+
 	case compiler.BuiltinRand:
 		p.push(num(p.random.Float64()))
 
+	case compiler.BuiltinRshift:
+		l, r := p.peekPop()
+		args, err := bitwiseArgs("rshift", l, r)
+		if err != nil {
+			return err
+		}
+		p.replaceTop(rshift(args[0], args[1]))
+
 	case compiler.BuiltinSin:
 		p.replaceTop(num(math.Sin(p.peekTop().num())))
 
 	case compiler.BuiltinSqrt:
-		p.replaceTop(num(math.Sqrt(p.peekTop().num())))
+		if p.bignum {
+			p.replaceTop(p.bigSqrt(p.peekTop()))
+		} else {
+			p.replaceTop(num(math.Sqrt(p.peekTop().num())))
+		}
 
 	case compiler.BuiltinSrand:
 		prevSeed := p.randSeed
-		p.randSeed = float64(time.Now().Unix())
+		p.randSeed = float64(p.now().Unix())
 		p.random.Seed(int64(math.Float64bits(p.randSeed)))
 		p.push(num(prevSeed))
 
@@ -1068,6 +1525,31 @@ func (p *interp) callBuiltin(builtinOp compiler.BuiltinOp) error {
 		p.random.Seed(int64(math.Float64bits(p.randSeed)))
 		p.replaceTop(num(prevSeed))
 
+	case compiler.BuiltinStrftime:
+		format := p.toString(p.procInfo["strftime"])
+		p.push(str(strftime(format, p.now().Local())))
+
+	case compiler.BuiltinStrftimeFormat:
+		format := p.toString(p.peekTop())
+		p.replaceTop(str(strftime(format, p.now().Local())))
+
+	case compiler.BuiltinStrftimeTime:
+		timestamp, utc := p.popTwo()
+		format := p.toString(p.peekTop())
+		t := time.Unix(int64(timestamp.num()), 0)
+		if utc.boolean() {
+			t = t.UTC()
+		}
+		p.replaceTop(str(strftime(format, t)))
+
+	case compiler.BuiltinStrptime:
+		s, format := p.peekPop()
+		timestamp, err := strptime(p.toString(s), p.toString(format), time.Local)
+		if err != nil {
+			timestamp = -1
+		}
+		p.replaceTop(num(float64(timestamp)))
+
 	case compiler.BuiltinSub:
 		regex, repl, in := p.peekPeekPop()
 		out, n, err := p.sub(p.toString(regex), p.toString(repl), p.toString(in), false)
@@ -1147,11 +1629,33 @@ func (p *interp) callBuiltin(builtinOp compiler.BuiltinOp) error {
 		}
 		p.replaceTop(num(float64(exitCode)))
 
+	case compiler.BuiltinSystime:
+		p.push(num(float64(p.now().Unix())))
+
 	case compiler.BuiltinTolower:
 		p.replaceTop(str(strings.ToLower(p.toString(p.peekTop()))))
 
 	case compiler.BuiltinToupper:
 		p.replaceTop(str(strings.ToUpper(p.toString(p.peekTop()))))
+
+	case compiler.BuiltinTypeof:
+		p.replaceTop(str(p.peekTop().typeName()))
+
+	case compiler.BuiltinTypeofUntyped:
+		v := p.peekTop()
+		if v.typ == typeNull {
+			p.replaceTop(str("untyped"))
+			break
+		}
+		p.replaceTop(str(v.typeName()))
+
+	case compiler.BuiltinXor:
+		l, r := p.peekPop()
+		args, err := bitwiseArgs("xor", l, r)
+		if err != nil {
+			return err
+		}
+		p.replaceTop(bitwiseResult(args[0] ^ args[1]))
 	}
 
 	return nil
@@ -1169,6 +1673,344 @@ func arrayGet(array map[string]value, index string) value {
 	return v
 }
 
+// Execute loopCode for each index in array, setting the given variable to
+// the index (the guts of a for-in loop).
+func (p *interp) forIn(varScope resolver.Scope, varIndex int, array map[string]value, loopCode []compiler.Opcode) error {
+	// If PROCINFO["sorted_in"] is set, loop over a sorted copy of the indexes
+	if sortedIn, ok := p.procInfo["sorted_in"]; ok {
+		how := p.toString(sortedIn)
+		if how != "" && how != "@unsorted" {
+			indexes, err := p.sortedIndexes(array, how)
+			if err != nil {
+				return err
+			}
+			for _, index := range indexes {
+				err := p.forInBody(varScope, varIndex, index, loopCode)
+				if err == errBreak {
+					break
+				}
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+
+	for index := range array {
+		err := p.forInBody(varScope, varIndex, index, loopCode)
+		if err == errBreak {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Set the for-in loop variable to index and execute one iteration of the loop.
+func (p *interp) forInBody(varScope resolver.Scope, varIndex int, index string, loopCode []compiler.Opcode) error {
+	switch varScope {
+	case resolver.Global:
+		p.globals[varIndex] = str(index)
+	case resolver.Local:
+		p.frame[varIndex] = str(index)
+	default: // resolver.Special
+		err := p.setSpecial(varIndex, str(index))
+		if err != nil {
+			return err
+		}
+	}
+	return p.execute(loopCode)
+}
+
+// Call the user-defined function with the given index, passing args as its
+// first scalar arguments and the arrays with the given indexes as its first
+// array arguments. This is used for calls from Go code, like sort comparison
+// functions, and indirect calls; compiled calls use the CallUser opcode.
+func (p *interp) callUser(funcIndex int, args []value, arrayArgs []int) (value, error) {
+	f := p.compiled.Functions[funcIndex]
+	if p.callDepth >= maxCallDepth {
+		return null(), newError("calling %q exceeded maximum call depth of %d", f.Name, maxCallDepth)
+	}
+
+	// Set up frame for scalar arguments and any local arrays
+	for _, arg := range args {
+		p.push(arg)
+	}
+	p.pushNulls(f.NumScalars - len(args))
+	oldFrame := p.frame
+	p.frame = p.peekSlice(f.NumScalars)
+	oldArraysLen := len(p.arrays)
+	arrays := append([]int(nil), arrayArgs...)
+	for j := len(arrayArgs); j < f.NumArrays; j++ {
+		arrays = append(arrays, len(p.arrays))
+		p.arrays = append(p.arrays, make(map[string]value))
+	}
+	p.localArrays = append(p.localArrays, arrays)
+
+	p.callDepth++
+	err := p.execute(f.Body)
+	p.callDepth--
+
+	p.freeLocalSubarrays(len(arrayArgs))
+	p.popSlice(f.NumScalars)
+	p.frame = oldFrame
+	p.localArrays = p.localArrays[:len(p.localArrays)-1]
+	p.arrays = p.arrays[:oldArraysLen]
+
+	if r, ok := err.(returnValue); ok {
+		return r.Value, nil
+	} else if err != nil {
+		return null(), err
+	}
+	return null(), nil
+}
+
+// Call the user-defined or native function with the given name, as in an
+// indirect call @f(args). The arguments are given by the (scope, index) pairs
+// in argOpcodes: arrays have a non-zero scope, and other arguments (scope 0)
+// are on the stack, with a[x] arguments as references. AWK-defined functions
+// take precedence over native ones, the same as for direct calls.
+func (p *interp) callIndirect(name string, argOpcodes []compiler.Opcode) (value, error) {
+	numStackArgs := 0
+	for j := 0; j < len(argOpcodes); j += 2 {
+		if argOpcodes[j] == 0 {
+			numStackArgs++
+		}
+	}
+	stackArgs := append([]value(nil), p.popSlice(numStackArgs)...)
+	numArgs := len(argOpcodes) / 2
+
+	for i, f := range p.compiled.Functions {
+		if f.Name != name {
+			continue
+		}
+		if numArgs > len(f.Params) {
+			return null(), newError("%q called with more arguments than declared", name)
+		}
+		oldArraysLen := len(p.arrays)
+		var args []value
+		var arrays []int
+		for j := 0; j < numArgs; j++ {
+			argScope := resolver.Scope(argOpcodes[2*j])
+			var arg value
+			if argScope == 0 {
+				arg, stackArgs = stackArgs[0], stackArgs[1:]
+			}
+			switch {
+			case argScope != 0 && f.Arrays[j]:
+				arrays = append(arrays, p.arrayIndex(argScope, int(argOpcodes[2*j+1])))
+			case argScope != 0:
+				return null(), newError("can't pass array as scalar param %q in indirect call to %q", f.Params[j], name)
+			case arg.typ == typeRef && f.Arrays[j]:
+				array, err := p.subArray(arg)
+				if err != nil {
+					return null(), err
+				}
+				arrays = append(arrays, len(p.arrays))
+				p.arrays = append(p.arrays, array)
+			case f.Arrays[j]:
+				return null(), newError("can't pass scalar as array param %q in indirect call to %q", f.Params[j], name)
+			default:
+				arg, err := p.derefArg(arg)
+				if err != nil {
+					return null(), err
+				}
+				args = append(args, arg)
+			}
+		}
+		r, err := p.callUser(i, args, arrays)
+		p.arrays = p.arrays[:oldArraysLen]
+		return r, err
+	}
+
+	for i, f := range p.nativeFuncs {
+		if f.name != name {
+			continue
+		}
+		if !f.isVariadic && numArgs > len(f.in) {
+			return null(), newError("%q called with more arguments than declared", name)
+		}
+		args := make([]value, numArgs)
+		for j := range args {
+			if argOpcodes[2*j] != 0 {
+				return null(), newError("can't pass array to native function %q in indirect call", name)
+			}
+			arg, err := p.derefArg(stackArgs[0])
+			if err != nil {
+				return null(), err
+			}
+			args[j], stackArgs = arg, stackArgs[1:]
+		}
+		return p.callNative(i, args)
+	}
+	return null(), newError("indirect call to undefined function %q", name)
+}
+
+// Return the value of the a[x] reference r if it's passed as a scalar
+// argument, otherwise return v itself.
+func (p *interp) derefArg(v value) (value, error) {
+	if v.typ != typeRef {
+		return v, nil
+	}
+	item := arrayGet(p.refArray(v), v.s)
+	if item.typ == typeArray {
+		return null(), newError("can't use subarray %q as scalar", v.s)
+	}
+	return item, nil
+}
+
+// Return the array indexes for a user function call's array arguments and
+// local arrays, given the (scope, index) pairs in arrayArgs. Subarray
+// arguments (scope 0) are references on the stack below the scalar
+// arguments, and are added to p.arrays for the duration of the call. Also
+// return the number of subarray references.
+func (p *interp) callArrays(arrayArgs []compiler.Opcode, numScalars, numArrays int) ([]int, int, error) {
+	numRefs := 0
+	for j := 0; j < len(arrayArgs); j += 2 {
+		if arrayArgs[j] == 0 {
+			numRefs++
+		}
+	}
+	refs := p.stack[p.sp-numScalars-numRefs : p.sp-numScalars]
+	var arrays []int
+	for j := 0; j < len(arrayArgs); j += 2 {
+		arrayScope := resolver.Scope(arrayArgs[j])
+		arrayIndex := int(arrayArgs[j+1])
+		if arrayScope == 0 {
+			array, err := p.subArray(refs[arrayIndex])
+			if err != nil {
+				return nil, 0, err
+			}
+			arrays = append(arrays, len(p.arrays))
+			p.arrays = append(p.arrays, array)
+			continue
+		}
+		arrays = append(arrays, p.arrayIndex(arrayScope, arrayIndex))
+	}
+	for j := len(arrayArgs) / 2; j < numArrays; j++ {
+		arrays = append(arrays, len(p.arrays))
+		p.arrays = append(p.arrays, make(map[string]value))
+	}
+	return arrays, numRefs, nil
+}
+
+// Return the array for the j'th array argument of a builtin function, given
+// the (scope, index) pairs in arrayArgs. A subarray argument (scope 0) is
+// popped off the stack as a reference.
+func (p *interp) arrayArg(arrayArgs []compiler.Opcode, j int) (map[string]value, error) {
+	arrayScope := resolver.Scope(arrayArgs[2*j])
+	if arrayScope == 0 {
+		return p.subArray(p.pop())
+	}
+	return p.array(arrayScope, int(arrayArgs[2*j+1])), nil
+}
+
+// Call patsplit(s, array, fieldpat[, seps]).
+func (p *interp) callPatsplit(arrayArgs []compiler.Opcode) error {
+	var seps map[string]value
+	if len(arrayArgs) > 2 {
+		var err error
+		seps, err = p.arrayArg(arrayArgs, 1)
+		if err != nil {
+			return err
+		}
+	}
+	fieldPat := p.toString(p.pop())
+	array, err := p.arrayArg(arrayArgs, 0)
+	if err != nil {
+		return err
+	}
+	n, err := p.patsplit(p.toString(p.peekTop()), array, fieldPat, seps)
+	if err != nil {
+		return err
+	}
+	p.replaceTop(num(float64(n)))
+	return nil
+}
+
+// Call asort(src[, dest[, how]]), or asorti if indexes is true.
+func (p *interp) callAsort(arrayArgs []compiler.Opcode, indexes bool) error {
+	how := p.toString(p.pop())
+	var arrays [2]map[string]value
+	for j := len(arrayArgs)/2 - 1; j >= 0; j-- {
+		array, err := p.arrayArg(arrayArgs, j)
+		if err != nil {
+			return err
+		}
+		arrays[j] = array
+	}
+	src, dest := arrays[0], arrays[0]
+	if arrays[1] != nil {
+		dest = arrays[1]
+	}
+	n, err := p.asort(src, dest, how, indexes)
+	if err != nil {
+		return err
+	}
+	p.push(num(float64(n)))
+	return nil
+}
+
+// Call printrow(array[, fields]), with its output redirected to the
+// destination below the arguments on the stack if redirect isn't ILLEGAL.
+func (p *interp) callPrintrow(arrayArgs []compiler.Opcode, redirect lexer.Token) error {
+	var arrays [2]map[string]value
+	for j := len(arrayArgs)/2 - 1; j >= 0; j-- {
+		array, err := p.arrayArg(arrayArgs, j)
+		if err != nil {
+			return err
+		}
+		arrays[j] = array
+	}
+	fields := arrays[1]
+	if fields == nil {
+		fields = p.array(resolver.Global, p.arrayIndexes["OFIELDS"])
+	}
+	output := p.output
+	if redirect != lexer.ILLEGAL {
+		var err error
+		output, err = p.getOutputStream(redirect, p.pop())
+		if err != nil {
+			return err
+		}
+	}
+	n, err := p.printRow(output, arrays[0], fields)
+	if err != nil {
+		return err
+	}
+	p.push(num(float64(n)))
+	return nil
+}
+
+// Return the subarray referred to by r, creating it if the item doesn't
+// exist yet (or is uninitialized).
+func (p *interp) subArray(r value) (map[string]value, error) {
+	v, err := p.subArrayItem(r)
+	if err != nil {
+		return nil, err
+	}
+	return p.subarray(v), nil
+}
+
+// Like subArray, but return the typeArray value of the item referred to by r.
+func (p *interp) subArrayItem(r value) (value, error) {
+	array := p.refArray(r)
+	v := array[r.s]
+	switch v.typ {
+	case typeArray:
+		return v, nil
+	case typeNull:
+		v = p.newSubarray(make(map[string]value))
+		array[r.s] = v
+		return v, nil
+	default:
+		return null(), newError("can't use scalar %q as subarray", r.s)
+	}
+}
+
 // Stack operations follow. These should be inlined. Instead of just push and
 // pop, for efficiency we have custom operations for when we're replacing the
 // top of stack without changing the stack pointer. Primarily this avoids the
@@ -1254,6 +2096,26 @@ func (p *interp) getline(redirect lexer.Token) (float64, string, error) {
 		}
 		if !scanner.Scan() {
 			if err := scanner.Err(); err != nil {
+				p.setErrno(err)
+				return -1, "", nil
+			}
+			return 0, "", nil
+		}
+		return 1, scanner.Text(), nil
+
+	case lexer.PIPE_AND: // redirect from coprocess
+		name := p.toString(p.pop())
+		s, err := p.getCoprocess(name)
+		if s == nil {
+			if err != nil {
+				return 0, "", err
+			}
+			return -1, "", nil // non-fatal error, ERRNO has been set
+		}
+		scanner := p.scanners[name]
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				p.setErrno(err)
 				return -1, "", nil
 			}
 			return 0, "", nil
@@ -1264,15 +2126,21 @@ func (p *interp) getline(redirect lexer.Token) (float64, string, error) {
 		name := p.toString(p.pop())
 		scanner, err := p.getInputScannerFile(name)
 		if err != nil {
-			if errors.Is(err, fs.ErrNotExist) {
+			var interpErr *Error
+			if errors.Is(err, fs.ErrNotExist) || (p.nonFatal(name) && !errors.As(err, &interpErr)) {
 				// File not found is not a hard error, getline just returns -1.
 				// See: https://github.com/benhoyt/goawk/issues/41
+				p.setErrno(err)
 				return -1, "", nil
 			}
 			return 0, "", err
 		}
+		if scanner == nil {
+			return -1, "", nil // non-fatal network error, ERRNO has been set
+		}
 		if !scanner.Scan() {
 			if err := scanner.Err(); err != nil {
+				p.setErrno(err)
 				return -1, "", nil
 			}
 			return 0, "", nil
@@ -1293,28 +2161,106 @@ func (p *interp) getline(redirect lexer.Token) (float64, string, error) {
 	}
 }
 
-// Perform augmented assignment operation.
+// Perform augmented assignment operation. Like the arithmetic opcodes, this
+// uses float64 arithmetic when the operands and result are exact, and falls
+// back to exact integer arithmetic otherwise.
 func (p *interp) augAssignOp(op compiler.AugOp, l, r value) (value, error) {
+	ln, rn := l.num(), r.num()
 	switch op {
 	case compiler.AugOpAdd:
-		return num(l.num() + r.num()), nil
+		if math.Abs(ln)+math.Abs(rn) < maxExactFloat {
+			return num(ln + rn), nil
+		}
+		return addExact(l, r, ln+rn), nil
 	case compiler.AugOpSub:
-		return num(l.num() - r.num()), nil
+		if math.Abs(ln)+math.Abs(rn) < maxExactFloat {
+			return num(ln - rn), nil
+		}
+		return subtractExact(l, r, ln-rn), nil
 	case compiler.AugOpMul:
-		return num(l.num() * r.num()), nil
+		product := ln * rn
+		if math.Abs(product) < maxExactFloat {
+			return num(product), nil
+		}
+		return multiplyExact(l, r, product), nil
 	case compiler.AugOpDiv:
-		rf := r.num()
-		if rf == 0.0 {
+		if rn == 0.0 {
 			return null(), newError("division by zero")
 		}
-		return num(l.num() / rf), nil
+		if math.Abs(ln) < maxExactFloat && math.Abs(rn) < maxExactFloat {
+			return num(ln / rn), nil
+		}
+		return divideValues(l, r), nil
 	case compiler.AugOpPow:
-		return num(math.Pow(l.num(), r.num())), nil
+		result := math.Pow(ln, rn)
+		if math.Abs(result) < maxExactFloat {
+			return num(result), nil
+		}
+		return powerValues(l, r), nil
 	default: // AugOpMod
-		rf := r.num()
-		if rf == 0.0 {
+		if rn == 0.0 {
 			return null(), newError("division by zero in mod")
 		}
-		return num(math.Mod(l.num(), rf)), nil
+		if math.Abs(ln) < maxExactFloat && math.Abs(rn) < maxExactFloat {
+			return num(math.Mod(ln, rn)), nil
+		}
+		return moduloValues(l, r), nil
+	}
+}
+
+// Add amount to v, for the increment and decrement operators.
+func increment(v value, amount compiler.Opcode) value {
+	return addValues(v, num(float64(amount)))
+}
+
+// Compare l and r for the comparison operators when either is a string or
+// a number too large to compare exactly as a float64 (or in bignum mode),
+// returning -1, 0, or 1.
+func (p *interp) compare(l, r value) int {
+	_, lIsStr := l.isTrueStr()
+	_, rIsStr := r.isTrueStr()
+	if lIsStr || rIsStr {
+		return strings.Compare(p.toString(l), p.toString(r))
+	}
+	if p.bignum {
+		return p.bigCompare(l, r)
+	}
+	return compareNumValues(l, r)
+}
+
+// Report whether ln or rn is too large for the comparison operators to
+// compare them as float64s, because an integer may not be exact (and neither
+// is NaN, which must compare unequal to everything).
+func largeNums(ln, rn float64) bool {
+	return (math.Abs(ln) >= maxExactFloat || math.Abs(rn) >= maxExactFloat) &&
+		!math.IsNaN(ln) && !math.IsNaN(rn)
+}
+
+// Return the jump offset of the first case in a switch statement that
+// matches v, or of the default case (or end of the switch) if none do. Cases
+// match as if compared using the "==" operator, or "~" for regex cases.
+func (p *interp) switchOffset(table *compiler.SwitchTable, v value) int {
+	match := len(table.Cases)
+	n, isStr := v.isTrueStr()
+	s := p.toString(v)
+	if i, ok := table.Strs[s]; ok {
+		match = i
+	}
+	if i, ok := table.Nums[n]; ok && !isStr && i < match {
+		match = i
+	}
+	// Number cases compare as strings with a string value, and regex cases
+	// need to be tried in order.
+	for i := 0; i < match; i++ {
+		c := table.Cases[i]
+		if c.Kind == compiler.SwitchRegex && p.regexes[c.Regex].MatchString(s) ||
+			c.Kind == compiler.SwitchNum && isStr && num(c.Num).str(p.convertFormat) == s {
+			match = i
+			break
+		}
+	}
+	if match < len(table.Cases) {
+		return table.Cases[match].Offset
 	}
+	return table.Default
 }
@@ -112,6 +112,19 @@ func (l *Lexer) scan() (Position, Token, string) {
 			l.next()
 		}
 		name := string(l.src[start : l.offset-1])
+		if l.ch == ':' && l.offset+1 < len(l.src) && l.src[l.offset] == ':' && isNameStart(l.src[l.offset+1]) {
+			// Qualified name like ns::name (neither part can be a keyword)
+			l.next()
+			l.next()
+			for isNameStart(l.ch) || isDigit(l.ch) {
+				l.next()
+			}
+			qualified := string(l.src[start : l.offset-1])
+			if KeywordToken(name) != ILLEGAL || KeywordToken(qualified[len(name)+2:]) != ILLEGAL {
+				return pos, ILLEGAL, fmt.Sprintf("can't use keyword in qualified name %q", qualified)
+			}
+			return pos, NAME, qualified
+		}
 		tok := KeywordToken(name)
 		if tok == ILLEGAL {
 			tok = NAME
@@ -128,6 +141,11 @@ func (l *Lexer) scan() (Position, Token, string) {
 		tok = DOLLAR
 	case '@':
 		tok = AT
+		if l.scanDirective("namespace") {
+			tok = NAMESPACE
+		} else if l.scanDirective("include") {
+			tok = INCLUDE
+		}
 	case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9', '.':
 		// Avoid make/append and use l.offset directly for performance
 		start := l.offset - 2
@@ -280,7 +298,12 @@ func (l *Lexer) scan() (Position, Token, string) {
 			return l.pos, ILLEGAL, "unexpected char after '&'"
 		}
 	case '|':
-		tok = l.choice('|', PIPE, OR)
+		if l.ch == '&' {
+			l.next()
+			tok = PIPE_AND
+		} else {
+			tok = l.choice('|', PIPE, OR)
+		}
 	default:
 		tok = ILLEGAL
 		val = "unexpected char"
@@ -369,6 +392,20 @@ func (l *Lexer) unread() {
 	l.ch = l.src[l.offset-1]
 }
 
+// If the name just after an "@" is the given directive (and not just the
+// start of a longer name), skip over it and return true.
+func (l *Lexer) scanDirective(directive string) bool {
+	end := l.offset - 1 + len(directive)
+	if end > len(l.src) || string(l.src[l.offset-1:end]) != directive ||
+		end < len(l.src) && (isNameStart(l.src[end]) || isDigit(l.src[end])) {
+		return false
+	}
+	for range directive {
+		l.next()
+	}
+	return true
+}
+
 func isNameStart(ch byte) bool {
 	return ch == '_' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z'
 }
@@ -27,6 +27,12 @@ func TestLexer(t *testing.T) {
 		{"x y0", `1:1 name "x", 1:3 name "y0"`},
 		{"x 0y", `1:1 name "x", 1:3 number "0", 1:4 name "y"`},
 		{"sub SUB", `1:1 sub "", 1:5 name "SUB"`},
+		{"ns::x y::z0", `1:1 name "ns::x", 1:7 name "y::z0"`},
+		{"a ? b:c : d", `1:1 name "a", 1:3 ? "", 1:5 name "b", 1:6 : "", 1:7 name "c", 1:9 : "", 1:11 name "d"`},
+		{"x::1", `1:1 name "x", 1:2 : "", 1:3 : "", 1:4 number "1"`},
+		{"ns::if", `1:1 <illegal> "can't use keyword in qualified name \"ns::if\""`},
+		{"@namespace @namespaces @x", `1:1 @namespace "", 1:12 @ "", 1:13 name "namespaces", 1:24 @ "", 1:25 name "x"`},
+		{`@include "a" @included`, `1:1 @include "", 1:10 string "a", 1:14 @ "", 1:15 name "included"`},
 
 		// String tokens
 		{`"foo"`, `1:1 string "foo"`},
@@ -228,11 +234,12 @@ func TestKeywordToken(t *testing.T) {
 func TestAllTokens(t *testing.T) {
 	input := "# comment line\n" +
 		"+ += && = : , -- /\n/= $ @ == >= > >> ++ { [ < ( #\n" +
-		"<= ~ % %= * *= !~ ! != | || ^ ^= ** **= ? } ] ) ; - -= " +
-		"BEGIN break continue delete do else END exit " +
-		"for function getline if in next nextfile print printf return while " +
-		"atan2 close cos exp fflush gsub index int length log match rand " +
-		"sin split sprintf sqrt srand sub substr system tolower toupper " +
+		"<= ~ % %= * *= !~ ! != | |& || ^ ^= ** **= ? } ] ) ; - -= " +
+		"BEGIN BEGINFILE break case continue default delete do else END ENDFILE exit " +
+		"for function getline if in @include @namespace next nextfile print printf return switch while " +
+		"and asort asorti atan2 close compl cos exp fflush gensub gsub index int isarray length log lshift match " +
+		"mktime or patsplit printrow rand rshift sin split sprintf sqrt srand strftime strptime sub substr system " +
+		"systime tolower toupper typeof xor " +
 		"x \"str\\n\" 1234\n" +
 		"` ."
 
@@ -251,11 +258,12 @@ func TestAllTokens(t *testing.T) {
 
 	expected := "<newline> " +
 		"+ += && = : , -- / <newline> /= $ @ == >= > >> ++ { [ < ( <newline> " +
-		"<= ~ % %= * *= !~ ! != | || ^ ^= ^ ^= ? } ] ) ; - -= " +
-		"BEGIN break continue delete do else END exit " +
-		"for function getline if in next nextfile print printf return while " +
-		"atan2 close cos exp fflush gsub index int length log match rand " +
-		"sin split sprintf sqrt srand sub substr system tolower toupper " +
+		"<= ~ % %= * *= !~ ! != | |& || ^ ^= ^ ^= ? } ] ) ; - -= " +
+		"BEGIN BEGINFILE break case continue default delete do else END ENDFILE exit " +
+		"for function getline if in @include @namespace next nextfile print printf return switch while " +
+		"and asort asorti atan2 close compl cos exp fflush gensub gsub index int isarray length log lshift match " +
+		"mktime or patsplit printrow rand rshift sin split sprintf sqrt srand strftime strptime sub substr system " +
+		"systime tolower toupper typeof xor " +
 		"name string number <newline> " +
 		"<illegal> <illegal> EOF"
 	if output != expected {
@@ -44,6 +44,7 @@ const (
 	NOT_EQUALS
 	OR
 	PIPE
+	PIPE_AND
 	POW
 	POW_ASSIGN
 	QUESTION
@@ -57,49 +58,73 @@ const (
 	// Keywords
 
 	BEGIN
+	BEGINFILE
 	BREAK
+	CASE
 	CONTINUE
+	DEFAULT
 	DELETE
 	DO
 	ELSE
 	END
+	ENDFILE
 	EXIT
 	FOR
 	FUNCTION
 	GETLINE
 	IF
 	IN
+	INCLUDE
+	NAMESPACE
 	NEXT
 	NEXTFILE
 	PRINT
 	PRINTF
 	RETURN
+	SWITCH
 	WHILE
 
 	// Built-in functions
 
+	F_AND
+	F_ASORT
+	F_ASORTI
 	F_ATAN2
 	F_CLOSE
+	F_COMPL
 	F_COS
 	F_EXP
 	F_FFLUSH
+	F_GENSUB
 	F_GSUB
 	F_INDEX
 	F_INT
+	F_ISARRAY
 	F_LENGTH
 	F_LOG
+	F_LSHIFT
 	F_MATCH
+	F_MKTIME
+	F_OR
+	F_PATSPLIT
+	F_PRINTROW
 	F_RAND
+	F_RSHIFT
 	F_SIN
 	F_SPLIT
 	F_SPRINTF
 	F_SQRT
 	F_SRAND
+	F_STRFTIME
+	F_STRPTIME
 	F_SUB
 	F_SUBSTR
 	F_SYSTEM
+	F_SYSTIME
 	F_TOLOWER
 	F_TOUPPER
+	F_TYPEOF
+	F_XOR
 
 	// Literals and names (variables and arrays)
 
@@ -109,53 +134,75 @@ const (
 	REGEX
 
 	LAST       = REGEX
-	FIRST_FUNC = F_ATAN2
-	LAST_FUNC  = F_TOUPPER
+	FIRST_FUNC = F_AND
+	LAST_FUNC  = F_XOR
 )
 
 var keywordTokens = map[string]Token{
-	"BEGIN":    BEGIN,
-	"break":    BREAK,
-	"continue": CONTINUE,
-	"delete":   DELETE,
-	"do":       DO,
-	"else":     ELSE,
-	"END":      END,
-	"exit":     EXIT,
-	"for":      FOR,
-	"function": FUNCTION,
-	"getline":  GETLINE,
-	"if":       IF,
-	"in":       IN,
-	"next":     NEXT,
-	"nextfile": NEXTFILE,
-	"print":    PRINT,
-	"printf":   PRINTF,
-	"return":   RETURN,
-	"while":    WHILE,
+	"BEGIN":     BEGIN,
+	"BEGINFILE": BEGINFILE,
+	"break":     BREAK,
+	"case":      CASE,
+	"continue":  CONTINUE,
+	"default":   DEFAULT,
+	"delete":    DELETE,
+	"do":        DO,
+	"else":      ELSE,
+	"END":       END,
+	"ENDFILE":   ENDFILE,
+	"exit":      EXIT,
+	"for":       FOR,
+	"function":  FUNCTION,
+	"getline":   GETLINE,
+	"if":        IF,
+	"in":        IN,
+	"next":      NEXT,
+	"nextfile":  NEXTFILE,
+	"print":     PRINT,
+	"printf":    PRINTF,
+	"return":    RETURN,
+	"switch":    SWITCH,
+	"while":     WHILE,
 
-	"atan2":   F_ATAN2,
-	"close":   F_CLOSE,
-	"cos":     F_COS,
-	"exp":     F_EXP,
-	"fflush":  F_FFLUSH,
-	"gsub":    F_GSUB,
-	"index":   F_INDEX,
-	"int":     F_INT,
-	"length":  F_LENGTH,
-	"log":     F_LOG,
-	"match":   F_MATCH,
-	"rand":    F_RAND,
-	"sin":     F_SIN,
-	"split":   F_SPLIT,
-	"sprintf": F_SPRINTF,
-	"sqrt":    F_SQRT,
-	"srand":   F_SRAND,
-	"sub":     F_SUB,
-	"substr":  F_SUBSTR,
-	"system":  F_SYSTEM,
-	"tolower": F_TOLOWER,
-	"toupper": F_TOUPPER,
+	"and":      F_AND,
+	"asort":    F_ASORT,
+	"asorti":   F_ASORTI,
+	"atan2":    F_ATAN2,
+	"close":    F_CLOSE,
+	"compl":    F_COMPL,
+	"cos":      F_COS,
+	"exp":      F_EXP,
+	"fflush":   F_FFLUSH,
+	"gensub":   F_GENSUB,
+	"gsub":     F_GSUB,
+	"index":    F_INDEX,
+	"int":      F_INT,
+	"isarray":  F_ISARRAY,
+	"length":   F_LENGTH,
+	"log":      F_LOG,
+	"lshift":   F_LSHIFT,
+	"match":    F_MATCH,
+	"mktime":   F_MKTIME,
+	"or":       F_OR,
+	"patsplit": F_PATSPLIT,
+	"printrow": F_PRINTROW,
+	"rand":     F_RAND,
+	"rshift":   F_RSHIFT,
+	"sin":      F_SIN,
+	"split":    F_SPLIT,
+	"sprintf":  F_SPRINTF,
+	"sqrt":     F_SQRT,
+	"srand":    F_SRAND,
+	"strftime": F_STRFTIME,
+	"strptime": F_STRPTIME,
+	"sub":      F_SUB,
+	"substr":   F_SUBSTR,
+	"system":   F_SYSTEM,
+	"systime":  F_SYSTIME,
+	"tolower":  F_TOLOWER,
+	"toupper":  F_TOUPPER,
+	"typeof":   F_TYPEOF,
+	"xor":      F_XOR,
 }
 
 // KeywordToken returns the token associated with the given keyword
@@ -201,6 +248,7 @@ var tokenNames = map[Token]string{
 	NOT_EQUALS: "!=",
 	OR:         "||",
 	PIPE:       "|",
+	PIPE_AND:   "|&",
 	POW:        "^",
 	POW_ASSIGN: "^=",
 	QUESTION:   "?",
@@ -211,48 +259,72 @@ var tokenNames = map[Token]string{
 	SUB:        "-",
 	SUB_ASSIGN: "-=",
 
-	BEGIN:    "BEGIN",
-	BREAK:    "break",
-	CONTINUE: "continue",
-	DELETE:   "delete",
-	DO:       "do",
-	ELSE:     "else",
-	END:      "END",
-	EXIT:     "exit",
-	FOR:      "for",
-	FUNCTION: "function",
-	GETLINE:  "getline",
-	IF:       "if",
-	IN:       "in",
-	NEXT:     "next",
-	NEXTFILE: "nextfile",
-	PRINT:    "print",
-	PRINTF:   "printf",
-	RETURN:   "return",
-	WHILE:    "while",
+	BEGIN:     "BEGIN",
+	BEGINFILE: "BEGINFILE",
+	BREAK:     "break",
+	CASE:      "case",
+	CONTINUE:  "continue",
+	DEFAULT:   "default",
+	DELETE:    "delete",
+	DO:        "do",
+	ELSE:      "else",
+	END:       "END",
+	ENDFILE:   "ENDFILE",
+	EXIT:      "exit",
+	FOR:       "for",
+	FUNCTION:  "function",
+	GETLINE:   "getline",
+	IF:        "if",
+	IN:        "in",
+	INCLUDE:   "@include",
+	NAMESPACE: "@namespace",
+	NEXT:      "next",
+	NEXTFILE:  "nextfile",
+	PRINT:     "print",
+	PRINTF:    "printf",
+	RETURN:    "return",
+	SWITCH:    "switch",
+	WHILE:     "while",
 
-	F_ATAN2:   "atan2",
-	F_CLOSE:   "close",
-	F_COS:     "cos",
-	F_EXP:     "exp",
-	F_FFLUSH:  "fflush",
-	F_GSUB:    "gsub",
-	F_INDEX:   "index",
-	F_INT:     "int",
-	F_LENGTH:  "length",
-	F_LOG:     "log",
-	F_MATCH:   "match",
-	F_RAND:    "rand",
-	F_SIN:     "sin",
-	F_SPLIT:   "split",
-	F_SPRINTF: "sprintf",
-	F_SQRT:    "sqrt",
-	F_SRAND:   "srand",
-	F_SUB:     "sub",
-	F_SUBSTR:  "substr",
-	F_SYSTEM:  "system",
-	F_TOLOWER: "tolower",
-	F_TOUPPER: "toupper",
+	F_AND:      "and",
+	F_ASORT:    "asort",
+	F_ASORTI:   "asorti",
+	F_ATAN2:    "atan2",
+	F_CLOSE:    "close",
+	F_COMPL:    "compl",
+	F_COS:      "cos",
+	F_EXP:      "exp",
+	F_FFLUSH:   "fflush",
+	F_GENSUB:   "gensub",
+	F_GSUB:     "gsub",
+	F_INDEX:    "index",
+	F_INT:      "int",
+	F_ISARRAY:  "isarray",
+	F_LENGTH:   "length",
+	F_LOG:      "log",
+	F_LSHIFT:   "lshift",
+	F_MATCH:    "match",
+	F_MKTIME:   "mktime",
+	F_OR:       "or",
+	F_PATSPLIT: "patsplit",
+	F_PRINTROW: "printrow",
+	F_RAND:     "rand",
+	F_RSHIFT:   "rshift",
+	F_SIN:      "sin",
+	F_SPLIT:    "split",
+	F_SPRINTF:  "sprintf",
+	F_SQRT:     "sqrt",
+	F_SRAND:    "srand",
+	F_STRFTIME: "strftime",
+	F_STRPTIME: "strptime",
+	F_SUB:      "sub",
+	F_SUBSTR:   "substr",
+	F_SYSTEM:   "system",
+	F_SYSTIME:  "systime",
+	F_TOLOWER:  "tolower",
+	F_TOUPPER:  "toupper",
+	F_TYPEOF:   "typeof",
+	F_XOR:      "xor",
 
 	NAME:   "name",
 	NUMBER: "number",
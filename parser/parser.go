@@ -7,6 +7,7 @@ package parser
 import (
 	"fmt"
 	"io"
+	"math"
 	"regexp"
 	"strconv"
 	"strings"
@@ -44,6 +45,15 @@ type ParserConfig struct {
 	// Map of named Go functions to allow calling from AWK. See docs
 	// on interp.Config.Funcs for details.
 	Funcs map[string]any
+
+	// Include is called to find and read the file named in an @include
+	// directive, and returns its source code. The included source is
+	// parsed in place of the directive, starting in the default "awk"
+	// namespace, and positions in it are numbered from the returned
+	// line (so the caller can map them back to the file). Return nil
+	// source to skip a file, for example one that has already been
+	// included. If Include is nil, @include directives are an error.
+	Include func(name string) (src []byte, line int, err error)
 }
 
 func (c *ParserConfig) toResolverConfig() *resolver.Config {
@@ -76,6 +86,9 @@ func ParseProgram(src []byte, config *ParserConfig) (prog *Program, err error) {
 	}()
 	lex := lexer.NewLexer(src)
 	p := parser{lexer: lex}
+	if config != nil {
+		p.include = config.Include
+	}
 	p.multiExprs = make(map[*ast.MultiExpr]lexer.Position, 3)
 
 	p.next() // initialize p.tok
@@ -117,26 +130,42 @@ func (p *Program) Disassemble(writer io.Writer) error {
 // Parser state
 type parser struct {
 	// Lexer instance and current token values
-	lexer   *lexer.Lexer
-	pos     lexer.Position // position of last token (tok)
-	tok     lexer.Token    // last lexed token
-	prevTok lexer.Token    // previously lexed token
-	val     string         // string value of last token (or "")
+	lexer      *lexer.Lexer
+	lineOffset int            // added to line numbers from lexer (for @include)
+	pos        lexer.Position // position of last token (tok)
+	tok        lexer.Token    // last lexed token
+	prevTok    lexer.Token    // previously lexed token
+	val        string         // string value of last token (or "")
 
 	// Parsing state
-	inAction           bool     // true if parsing an action (false in BEGIN or END)
-	funcName           string   // function name if parsing a func, else ""
-	loopDepth          int      // current loop depth (0 if not in any loops)
-	pendingGetlineLeft ast.Expr // saved expression to the left of |
+	inAction           bool            // true if parsing an action (false in BEGIN or END)
+	fileBlock          lexer.Token     // BEGINFILE or ENDFILE if parsing one, else ILLEGAL
+	funcName           string          // function name if parsing a func, else ""
+	loopDepth          int             // current loop depth (0 if not in any loops)
+	switchDepth        int             // current switch depth (0 if not in any switches)
+	pendingGetlineLeft ast.Expr        // saved expression to the left of | or |&
+	pendingGetlineOp   lexer.Token     // PIPE or PIPE_AND after pendingGetlineLeft
+	namespace          string          // current @namespace name ("" for default "awk" namespace)
+	locals             map[string]bool // parameters of function being parsed, if any
 
 	// Variable tracking and resolving
 	multiExprs map[*ast.MultiExpr]lexer.Position // tracks comma-separated expressions
+
+	// Reads included files (ParserConfig.Include), or nil
+	include func(name string) ([]byte, int, error)
 }
 
 // Parse an entire AWK program.
 func (p *parser) program() *ast.Program {
 	prog := &ast.Program{}
+	p.items(prog)
+	p.checkMultiExprs()
+	return prog
+}
 
+// Parse top-level items (pattern-actions, functions, and directives) till
+// EOF, adding them to prog.
+func (p *parser) items(prog *ast.Program) {
 	// Terminator "(SEMICOLON|NEWLINE) NEWLINE*" is required after each item
 	// with two exceptions where it is optional:
 	//
@@ -166,9 +195,24 @@ func (p *parser) program() *ast.Program {
 		case lexer.END:
 			p.next()
 			prog.End = append(prog.End, p.stmtsBrace())
+		case lexer.BEGINFILE, lexer.ENDFILE:
+			p.fileBlock = p.tok
+			p.next()
+			if p.fileBlock == lexer.BEGINFILE {
+				prog.BeginFile = append(prog.BeginFile, p.stmtsBrace())
+			} else {
+				prog.EndFile = append(prog.EndFile, p.stmtsBrace())
+			}
+			p.fileBlock = lexer.ILLEGAL
 		case lexer.FUNCTION:
 			function := p.function()
 			prog.Functions = append(prog.Functions, function)
+		case lexer.NAMESPACE:
+			p.namespaceDirective()
+			needsTerminator = true
+		case lexer.INCLUDE:
+			p.includeDirective(prog)
+			needsTerminator = true
 		default:
 			p.inAction = true
 			// Allow empty pattern, normal pattern, or range pattern
@@ -191,10 +235,6 @@ func (p *parser) program() *ast.Program {
 			p.inAction = false
 		}
 	}
-
-	p.checkMultiExprs()
-
-	return prog
 }
 
 // Parse a list of statements.
@@ -248,7 +288,7 @@ func (p *parser) simpleStmt() ast.Stmt {
 		}
 		redirect := lexer.ILLEGAL
 		var dest ast.Expr
-		if p.matches(lexer.GREATER, lexer.APPEND, lexer.PIPE) {
+		if p.matches(lexer.GREATER, lexer.APPEND, lexer.PIPE, lexer.PIPE_AND) {
 			redirect = p.tok
 			p.next()
 			dest = p.expr()
@@ -264,16 +304,28 @@ func (p *parser) simpleStmt() ast.Stmt {
 	case lexer.DELETE:
 		p.next()
 		name, namePos := p.expectName()
+		var path [][]ast.Expr
 		var index []ast.Expr
 		if p.tok == lexer.LBRACKET {
-			p.next()
-			index = p.exprList(p.expr)
-			if len(index) == 0 {
-				panic(p.errorf("expected expression instead of ]"))
+			path = p.subscripts()
+			path, index = path[:len(path)-1], path[len(path)-1]
+			if len(path) == 0 {
+				path = nil
 			}
-			p.expect(lexer.RBRACKET)
 		}
-		return &ast.DeleteStmt{Array: name, ArrayPos: namePos, Index: index, Start: startPos, End: p.pos}
+		return &ast.DeleteStmt{Array: name, ArrayPos: namePos, Path: path, Index: index, Start: startPos, End: p.pos}
+	case lexer.F_PRINTROW:
+		// Like print, a printrow() statement can be redirected, so ">" after
+		// it is a redirect rather than a comparison.
+		expr := p.printExpr()
+		call, ok := expr.(*ast.CallExpr)
+		if ok && call.Func == lexer.F_PRINTROW && p.matches(lexer.GREATER, lexer.APPEND, lexer.PIPE, lexer.PIPE_AND) {
+			redirect := p.tok
+			p.next()
+			dest := p.expr()
+			return &ast.PrintrowStmt{Call: call, Redirect: redirect, Dest: dest, Start: startPos, End: p.pos}
+		}
+		return &ast.ExprStmt{Expr: expr, Start: startPos, End: p.pos}
 	case lexer.IF, lexer.FOR, lexer.WHILE, lexer.DO, lexer.BREAK, lexer.CONTINUE, lexer.NEXT, lexer.NEXTFILE, lexer.EXIT, lexer.RETURN:
 		panic(p.errorf("expected print/printf, delete, or expression"))
 	default:
@@ -305,7 +357,7 @@ func (p *parser) stmt() ast.Stmt {
 	case lexer.FOR:
 		// Parse for statement, either "for in" or C-like for loop.
 		//
-		//     FOR LPAREN NAME IN NAME RPAREN NEWLINE* stmts |
+		//     FOR LPAREN NAME IN NAME [subscripts] RPAREN NEWLINE* stmts |
 		//     FOR LPAREN [simpleStmt] SEMICOLON NEWLINE*
 		//                [expr] SEMICOLON NEWLINE*
 		//                [simpleStmt] RPAREN NEWLINE* stmts
@@ -342,6 +394,7 @@ func (p *parser) stmt() ast.Stmt {
 				VarPos:    varExpr.Pos,
 				Array:     inExpr.Array,
 				ArrayPos:  inExpr.ArrayPos,
+				Path:      inExpr.Path,
 				BodyStart: bodyStart,
 				Body:      body,
 				Start:     startPos,
@@ -386,8 +439,17 @@ func (p *parser) stmt() ast.Stmt {
 		cond := p.expr()
 		p.expect(lexer.RPAREN)
 		s = &ast.DoWhileStmt{Body: body, Cond: cond, Start: startPos, End: p.pos}
+	case lexer.SWITCH:
+		p.next()
+		p.expect(lexer.LPAREN)
+		expr := p.expr()
+		p.expect(lexer.RPAREN)
+		p.optionalNewlines()
+		bodyStart := p.pos
+		cases := p.switchBody()
+		s = &ast.SwitchStmt{Expr: expr, BodyStart: bodyStart, Cases: cases, Start: startPos, End: p.pos}
 	case lexer.BREAK:
-		if p.loopDepth == 0 {
+		if p.loopDepth == 0 && p.switchDepth == 0 {
 			panic(p.errorf("break must be inside a loop body"))
 		}
 		p.next()
@@ -399,13 +461,19 @@ func (p *parser) stmt() ast.Stmt {
 		p.next()
 		s = &ast.ContinueStmt{Start: startPos, End: p.pos}
 	case lexer.NEXT:
+		if p.fileBlock != lexer.ILLEGAL {
+			panic(p.errorf("next can't be inside %s", p.fileBlock))
+		}
 		if !p.inAction && p.funcName == "" {
 			panic(p.errorf("next can't be inside BEGIN or END"))
 		}
 		p.next()
 		s = &ast.NextStmt{Start: startPos, End: p.pos}
 	case lexer.NEXTFILE:
-		if !p.inAction && p.funcName == "" {
+		if p.fileBlock == lexer.ENDFILE {
+			panic(p.errorf("nextfile can't be inside ENDFILE"))
+		}
+		if !p.inAction && p.funcName == "" && p.fileBlock == lexer.ILLEGAL {
 			panic(p.errorf("nextfile can't be inside BEGIN or END"))
 		}
 		p.next()
@@ -453,6 +521,73 @@ func (p *parser) loopStmts() ast.Stmts {
 	return ss
 }
 
+// Parse the cases of a switch statement:
+//
+//	LBRACE NEWLINE* ((CASE caseValue | DEFAULT) COLON NEWLINE* stmt*)* RBRACE
+func (p *parser) switchBody() []*ast.SwitchCase {
+	p.expect(lexer.LBRACE)
+	p.optionalNewlines()
+	var cases []*ast.SwitchCase
+	seen := make(map[string]bool)
+	hasDefault := false
+	for p.tok != lexer.RBRACE {
+		c := &ast.SwitchCase{}
+		switch p.tok {
+		case lexer.CASE:
+			p.next()
+			c.Value = p.caseValue()
+			key := fmt.Sprintf("%#v", c.Value) // type and exact value
+			if seen[key] {
+				panic(p.errorf("duplicate case value %s", c.Value))
+			}
+			seen[key] = true
+		case lexer.DEFAULT:
+			if hasDefault {
+				panic(p.errorf("more than one default case in switch"))
+			}
+			hasDefault = true
+			p.next()
+		default:
+			panic(p.errorf("expected case or default instead of %s", p.tok))
+		}
+		p.expect(lexer.COLON)
+		p.optionalNewlines()
+		p.switchDepth++
+		for !p.matches(lexer.CASE, lexer.DEFAULT, lexer.RBRACE, lexer.EOF) {
+			if p.matches(lexer.SEMICOLON, lexer.NEWLINE) {
+				p.next()
+				continue
+			}
+			c.Body = append(c.Body, p.stmt())
+		}
+		p.switchDepth--
+		cases = append(cases, c)
+	}
+	p.expect(lexer.RBRACE)
+	return cases
+}
+
+// Parse the value of a switch case, which must be a constant: a number
+// (optionally negative), a string, or a regex.
+func (p *parser) caseValue() ast.Expr {
+	switch p.tok {
+	case lexer.NUMBER, lexer.STRING, lexer.DIV, lexer.DIV_ASSIGN:
+		return p.primary()
+	case lexer.SUB:
+		p.next()
+		if p.tok != lexer.NUMBER {
+			break
+		}
+		n := p.primary().(*ast.NumExpr)
+		n.Value = -n.Value
+		if n.Text != "" {
+			n.Text = "-" + n.Text
+		}
+		return n
+	}
+	panic(p.errorf("expected number, string, or regex after case instead of %s", p.tok))
+}
+
 // Parse a function definition and body. As it goes, this resolves
 // the local variable indexes and tracks which parameters are array
 // parameters.
@@ -477,6 +612,9 @@ func (p *parser) function() *ast.Function {
 		if param == name {
 			panic(p.errorf("can't use function name as parameter name"))
 		}
+		if strings.Contains(param, "::") {
+			panic(p.errorf("can't use qualified name %q as parameter name", param))
+		}
 		if locals[param] {
 			panic(p.errorf("duplicate parameter name %q", param))
 		}
@@ -489,10 +627,12 @@ func (p *parser) function() *ast.Function {
 
 	// Parse the body
 	p.funcName = name
+	p.locals = locals
 
 	body := p.stmtsBrace()
 
 	p.funcName = ""
+	p.locals = nil
 
 	return &ast.Function{Name: name, Params: params, Body: body, Pos: funcNamePos}
 }
@@ -502,7 +642,7 @@ func (p *parser) function() *ast.Function {
 func (p *parser) exprList(parse func() ast.Expr) []ast.Expr {
 	exprs := []ast.Expr{}
 	first := true
-	for !p.matches(lexer.NEWLINE, lexer.SEMICOLON, lexer.RBRACE, lexer.RBRACKET, lexer.RPAREN, lexer.GREATER, lexer.PIPE, lexer.APPEND) {
+	for !p.matches(lexer.NEWLINE, lexer.SEMICOLON, lexer.RBRACE, lexer.RBRACKET, lexer.RPAREN, lexer.GREATER, lexer.PIPE, lexer.PIPE_AND, lexer.APPEND) {
 		if !first {
 			p.commaNewlines()
 		}
@@ -523,15 +663,17 @@ func (p *parser) exprList(parse func() ast.Expr) []ast.Expr {
 func (p *parser) expr() ast.Expr      { return p._assign(p.getline) }
 func (p *parser) printExpr() ast.Expr { return p._assign(p.printCond) }
 
-// Parse an "expr | getline [lvalue]" expression:
+// Parse an "expr | getline [lvalue]" or "expr |& getline [lvalue]"
+// expression:
 //
-//	assign [PIPE GETLINE [lvalue]]
+//	assign [(PIPE | PIPE_AND) GETLINE [lvalue]]
 func (p *parser) getline() ast.Expr {
 	// NOTE: getline is special, see https://github.com/benhoyt/goawk/pull/216
 	p.pendingGetlineLeft = nil
 	left := p.cond()
-	if p.tok == lexer.PIPE {
+	if p.tok == lexer.PIPE || p.tok == lexer.PIPE_AND {
 		p.pendingGetlineLeft = left
+		p.pendingGetlineOp = p.tok
 		return p.cond()
 	}
 	return left
@@ -541,27 +683,23 @@ func (p *parser) getline() ast.Expr {
 //
 //	lvalue [assign_op assign]
 //
-// An lvalue is a variable name, an array[expr] index expression, or
-// an $expr field expression.
+// An lvalue is a variable name, an array[expr] index expression, an
+// $expr field expression, or an @expr named field expression.
 func (p *parser) _assign(higher func() ast.Expr) ast.Expr {
 	leftPos := p.pos
 	expr := higher()
 	if p.matches(lexer.ASSIGN, lexer.ADD_ASSIGN, lexer.DIV_ASSIGN, lexer.MOD_ASSIGN, lexer.MUL_ASSIGN, lexer.POW_ASSIGN, lexer.SUB_ASSIGN) {
-		_, isNamedField := expr.(*ast.NamedFieldExpr)
-		if isNamedField {
-			panic(p.errorf("assigning @ expression not supported"))
-		}
 		op := p.tok
 		p.next()
 		right := p._assign(higher)
-		if !ast.IsLValue(expr) {
+		if !isLValue(expr) {
 			// Partial backtracking to allow expressions like "1 && x=1",
 			// which isn't really valid, as assignments are lower-precedence
 			// than binary operators, but onetrueawk, Gawk, and mawk all
 			// support this for logical, match and comparison operators. See
 			// issue #166.
 			binary, isBinary := expr.(*ast.BinaryExpr)
-			if isBinary && ast.IsLValue(binary.Right) {
+			if isBinary && isLValue(binary.Right) {
 				switch binary.Op {
 				case lexer.AND, lexer.OR, lexer.MATCH, lexer.NOT_MATCH, lexer.EQUALS, lexer.NOT_EQUALS, lexer.LESS, lexer.LTE, lexer.GTE, lexer.GREATER:
 					assign := makeAssign(binary.Right, op, right)
@@ -575,6 +713,14 @@ func (p *parser) _assign(higher func() ast.Expr) ast.Expr {
 	return expr
 }
 
+// Report whether expr can be assigned to or incremented. This is
+// ast.IsLValue plus named fields like @"name", which aren't valid as getline
+// or sub/gsub targets.
+func isLValue(expr ast.Expr) bool {
+	_, isNamedField := expr.(*ast.NamedFieldExpr)
+	return isNamedField || ast.IsLValue(expr)
+}
+
 func makeAssign(left ast.Expr, op lexer.Token, right ast.Expr) ast.Expr {
 	switch op {
 	case lexer.ASSIGN:
@@ -629,7 +775,7 @@ func (p *parser) printAnd() ast.Expr { return p.binaryLeft(p.printIn, true, lexe
 
 // Parse an "in" expression:
 //
-//	match [IN NAME] [IN NAME] ...
+//	match [IN NAME [subscripts]] [IN NAME [subscripts]] ...
 func (p *parser) in() ast.Expr      { return p._in(p.match) }
 func (p *parser) printIn() ast.Expr { return p._in(p.printMatch) }
 
@@ -638,7 +784,11 @@ func (p *parser) _in(higher func() ast.Expr) ast.Expr {
 	for p.tok == lexer.IN {
 		p.next()
 		name, namePos := p.expectName()
-		expr = &ast.InExpr{Index: []ast.Expr{expr}, Array: name, ArrayPos: namePos}
+		var path [][]ast.Expr
+		if p.tok == lexer.LBRACKET {
+			path = p.subscripts()
+		}
+		expr = &ast.InExpr{Index: []ast.Expr{expr}, Array: name, ArrayPos: namePos, Path: path}
 	}
 	return expr
 }
@@ -712,7 +862,7 @@ func (p *parser) pow() ast.Expr {
 
 func (p *parser) postIncr() ast.Expr {
 	expr := p.primary()
-	if (p.tok == lexer.INCR || p.tok == lexer.DECR) && ast.IsLValue(expr) {
+	if (p.tok == lexer.INCR || p.tok == lexer.DECR) && isLValue(expr) {
 		op := p.tok
 		p.next()
 		return &ast.IncrExpr{Expr: expr, Op: op}
@@ -720,22 +870,51 @@ func (p *parser) postIncr() ast.Expr {
 	return expr
 }
 
+// Report whether n holds the value of number literal s closely enough:
+// either exactly, or to the 15 significant digits a float64 is good for,
+// without overflowing or underflowing.
+func exactLiteral(s string, n float64) bool {
+	if strings.Trim(s, "0123456789") == "" {
+		return n < 1<<53
+	}
+	mantissa := s
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		mantissa = s[:i]
+	}
+	digits := strings.Trim(strings.Replace(mantissa, ".", "", 1), "0")
+	switch {
+	case digits == "":
+		return true // zero
+	case len(digits) > 15, math.IsInf(n, 0):
+		return false
+	default:
+		return math.Abs(n) >= 0x1p-1022 // smallest normal float64
+	}
+}
+
 func (p *parser) primary() ast.Expr {
 	if p.pendingGetlineLeft != nil {
-		p.expect(lexer.PIPE)
+		op := p.pendingGetlineOp
+		p.expect(op)
 		p.expect(lexer.GETLINE)
 		left := p.pendingGetlineLeft
 		p.pendingGetlineLeft = nil
 		target := p.optionalLValue()
-		return &ast.GetlineExpr{Command: left, Target: target}
+		return &ast.GetlineExpr{Command: left, Coprocess: op == lexer.PIPE_AND, Target: target}
 	}
 	switch p.tok {
 	case lexer.NUMBER:
 		// AWK allows forms like "1.5e", but ParseFloat doesn't
 		s := strings.TrimRight(p.val, "eE")
 		n, _ := strconv.ParseFloat(s, 64)
+		expr := &ast.NumExpr{Value: n}
+		if !exactLiteral(s, n) {
+			// Keep the source of literals a float64 can't hold exactly
+			// (large integers, and numbers that need bignum mode)
+			expr.Text = s
+		}
 		p.next()
-		return &ast.NumExpr{Value: n}
+		return expr
 	case lexer.STRING:
 		s := p.val
 		p.next()
@@ -759,7 +938,13 @@ func (p *parser) primary() ast.Expr {
 		return expr
 	case lexer.AT:
 		p.next()
-		return &ast.NamedFieldExpr{Field: p.primary()}
+		field := p.primary()
+		if call, ok := field.(*ast.UserCallExpr); ok {
+			// @f(args) is an indirect call to the function named by f.
+			fn := &ast.VarExpr{Name: call.Name, Pos: call.Pos}
+			return &ast.IndirectCallExpr{Func: fn, Args: call.Args, Pos: call.Pos}
+		}
+		return &ast.NamedFieldExpr{Field: field}
 	case lexer.NOT, lexer.ADD, lexer.SUB:
 		op := p.tok
 		p.next()
@@ -768,7 +953,13 @@ func (p *parser) primary() ast.Expr {
 		op := p.tok
 		p.next()
 		exprPos := p.pos
-		expr := p.optionalLValue()
+		var expr ast.Expr
+		if p.tok == lexer.AT {
+			p.next()
+			expr = &ast.NamedFieldExpr{Field: p.primary()}
+		} else {
+			expr = p.optionalLValue()
+		}
 		if expr == nil {
 			panic(ast.PosErrorf(exprPos, "expected lvalue after %s", op))
 		}
@@ -776,14 +967,9 @@ func (p *parser) primary() ast.Expr {
 	case lexer.NAME:
 		name, namePos := p.expectName()
 		if p.tok == lexer.LBRACKET {
-			// a[x] or a[x, y] array index expression
-			p.next()
-			index := p.exprList(p.expr)
-			if len(index) == 0 {
-				panic(p.errorf("expected expression instead of ]"))
-			}
-			p.expect(lexer.RBRACKET)
-			return &ast.IndexExpr{Array: name, ArrayPos: namePos, Index: index}
+			// a[x] or a[x, y] array index expression, or a[x][y] subarray
+			// element
+			return p.indexExpr(name, namePos)
 		} else if p.tok == lexer.LPAREN && !p.lexer.HadSpace() {
 			// Grammar requires no space between function name and
 			// left paren for user function calls, hence the funky
@@ -807,7 +993,11 @@ func (p *parser) primary() ast.Expr {
 			if p.tok == lexer.IN {
 				p.next()
 				name, namePos := p.expectName()
-				return &ast.InExpr{Index: exprs, Array: name, ArrayPos: namePos}
+				var path [][]ast.Expr
+				if p.tok == lexer.LBRACKET {
+					path = p.subscripts()
+				}
+				return &ast.InExpr{Index: exprs, Array: name, ArrayPos: namePos, Path: path}
 			}
 			// MultiExpr is used as a pseudo-expression for print[f] parsing.
 			return p.multiExpr(exprs, parenPos)
@@ -819,6 +1009,8 @@ func (p *parser) primary() ast.Expr {
 		if p.tok == lexer.LESS {
 			p.next()
 			file = p.primary()
+		} else if p.fileBlock != lexer.ILLEGAL {
+			panic(p.errorf("non-redirected getline can't be inside %s", p.fileBlock))
 		}
 		return &ast.GetlineExpr{Target: target, File: file}
 	// Below is the parsing of all the builtin function calls. We
@@ -844,19 +1036,74 @@ func (p *parser) primary() ast.Expr {
 		}
 		p.expect(lexer.RPAREN)
 		return &ast.CallExpr{Func: op, Args: args}
+	case lexer.F_GENSUB:
+		p.next()
+		p.expect(lexer.LPAREN)
+		regex := p.regexStr(p.expr)
+		p.commaNewlines()
+		repl := p.expr()
+		p.commaNewlines()
+		how := p.expr()
+		args := []ast.Expr{regex, repl, how}
+		if p.tok == lexer.COMMA {
+			p.commaNewlines()
+			args = append(args, p.expr())
+		}
+		p.expect(lexer.RPAREN)
+		return &ast.CallExpr{Func: lexer.F_GENSUB, Args: args}
 	case lexer.F_SPLIT:
 		p.next()
 		p.expect(lexer.LPAREN)
 		str := p.expr()
 		p.commaNewlines()
-		name, namePos := p.expectName()
-		args := []ast.Expr{str, &ast.VarExpr{Name: name, Pos: namePos}}
+		args := []ast.Expr{str, p.arrayArg()}
 		if p.tok == lexer.COMMA {
 			p.commaNewlines()
 			args = append(args, p.regexStr(p.expr))
 		}
 		p.expect(lexer.RPAREN)
 		return &ast.CallExpr{Func: lexer.F_SPLIT, Args: args}
+	case lexer.F_PATSPLIT:
+		p.next()
+		p.expect(lexer.LPAREN)
+		str := p.expr()
+		p.commaNewlines()
+		args := []ast.Expr{str, p.arrayArg()}
+		if p.tok == lexer.COMMA {
+			p.commaNewlines()
+			args = append(args, p.regexStr(p.expr))
+			if p.tok == lexer.COMMA {
+				p.commaNewlines()
+				args = append(args, p.arrayArg())
+			}
+		}
+		p.expect(lexer.RPAREN)
+		return &ast.CallExpr{Func: lexer.F_PATSPLIT, Args: args}
+	case lexer.F_ASORT, lexer.F_ASORTI:
+		op := p.tok
+		p.next()
+		p.expect(lexer.LPAREN)
+		args := []ast.Expr{p.arrayArg()}
+		if p.tok == lexer.COMMA {
+			p.commaNewlines()
+			args = append(args, p.arrayArg())
+			if p.tok == lexer.COMMA {
+				p.commaNewlines()
+				args = append(args, p.expr())
+			}
+		}
+		p.expect(lexer.RPAREN)
+		return &ast.CallExpr{Func: op, Args: args}
+	case lexer.F_PRINTROW:
+		p.next()
+		p.expect(lexer.LPAREN)
+		args := []ast.Expr{p.arrayArg()}
+		if p.tok == lexer.COMMA {
+			p.commaNewlines()
+			args = append(args, p.arrayArg())
+		}
+		p.expect(lexer.RPAREN)
+		return &ast.CallExpr{Func: lexer.F_PRINTROW, Args: args}
 	case lexer.F_MATCH:
 		p.next()
 		p.expect(lexer.LPAREN)
@@ -865,11 +1112,13 @@ func (p *parser) primary() ast.Expr {
 		regex := p.regexStr(p.expr)
 		p.expect(lexer.RPAREN)
 		return &ast.CallExpr{Func: lexer.F_MATCH, Args: []ast.Expr{str, regex}}
-	case lexer.F_RAND:
+	case lexer.F_RAND, lexer.F_SYSTIME:
+		// Simple 0-argument functions
+		op := p.tok
 		p.next()
 		p.expect(lexer.LPAREN)
 		p.expect(lexer.RPAREN)
-		return &ast.CallExpr{Func: lexer.F_RAND}
+		return &ast.CallExpr{Func: op}
 	case lexer.F_SRAND:
 		p.next()
 		p.expect(lexer.LPAREN)
@@ -904,6 +1153,31 @@ func (p *parser) primary() ast.Expr {
 		}
 		p.expect(lexer.RPAREN)
 		return &ast.CallExpr{Func: lexer.F_SUBSTR, Args: args}
+	case lexer.F_CLOSE, lexer.F_MKTIME, lexer.F_STRPTIME:
+		// 1-argument functions with an optional 2nd argument
+		op := p.tok
+		p.next()
+		p.expect(lexer.LPAREN)
+		args := []ast.Expr{p.expr()}
+		if p.tok == lexer.COMMA {
+			p.commaNewlines()
+			args = append(args, p.expr())
+		}
+		p.expect(lexer.RPAREN)
+		return &ast.CallExpr{Func: op, Args: args}
+	case lexer.F_STRFTIME:
+		p.next()
+		p.expect(lexer.LPAREN)
+		var args []ast.Expr
+		if p.tok != lexer.RPAREN {
+			args = append(args, p.expr())
+			for len(args) < 3 && p.tok == lexer.COMMA {
+				p.commaNewlines()
+				args = append(args, p.expr())
+			}
+		}
+		p.expect(lexer.RPAREN)
+		return &ast.CallExpr{Func: lexer.F_STRFTIME, Args: args}
 	case lexer.F_SPRINTF:
 		p.next()
 		p.expect(lexer.LPAREN)
@@ -923,7 +1197,8 @@ func (p *parser) primary() ast.Expr {
 		}
 		p.expect(lexer.RPAREN)
 		return &ast.CallExpr{Func: lexer.F_FFLUSH, Args: args}
-	case lexer.F_COS, lexer.F_SIN, lexer.F_EXP, lexer.F_LOG, lexer.F_SQRT, lexer.F_INT, lexer.F_TOLOWER, lexer.F_TOUPPER, lexer.F_SYSTEM, lexer.F_CLOSE:
+	case lexer.F_COS, lexer.F_SIN, lexer.F_EXP, lexer.F_LOG, lexer.F_SQRT, lexer.F_INT, lexer.F_TOLOWER, lexer.F_TOUPPER, lexer.F_SYSTEM, lexer.F_COMPL,
+		lexer.F_TYPEOF, lexer.F_ISARRAY:
 		// Simple 1-argument functions
 		op := p.tok
 		p.next()
@@ -931,7 +1206,21 @@ func (p *parser) primary() ast.Expr {
 		arg := p.expr()
 		p.expect(lexer.RPAREN)
 		return &ast.CallExpr{Func: op, Args: []ast.Expr{arg}}
-	case lexer.F_ATAN2, lexer.F_INDEX:
+	case lexer.F_AND, lexer.F_OR, lexer.F_XOR:
+		// Bitwise functions with 2 or more arguments
+		op := p.tok
+		p.next()
+		p.expect(lexer.LPAREN)
+		args := []ast.Expr{p.expr()}
+		p.commaNewlines()
+		args = append(args, p.expr())
+		for p.tok == lexer.COMMA {
+			p.commaNewlines()
+			args = append(args, p.expr())
+		}
+		p.expect(lexer.RPAREN)
+		return &ast.CallExpr{Func: op, Args: args}
+	case lexer.F_ATAN2, lexer.F_INDEX, lexer.F_LSHIFT, lexer.F_RSHIFT:
 		// Simple 2-argument functions
 		op := p.tok
 		p.next()
@@ -956,14 +1245,9 @@ func (p *parser) optionalLValue() ast.Expr {
 		}
 		name, namePos := p.expectName()
 		if p.tok == lexer.LBRACKET {
-			// a[x] or a[x, y] array index expression
-			p.next()
-			index := p.exprList(p.expr)
-			if len(index) == 0 {
-				panic(p.errorf("expected expression instead of ]"))
-			}
-			p.expect(lexer.RBRACKET)
-			return &ast.IndexExpr{Array: name, ArrayPos: namePos, Index: index}
+			// a[x] or a[x, y] array index expression, or a[x][y] subarray
+			// element
+			return p.indexExpr(name, namePos)
 		}
 		return &ast.VarExpr{Name: name, Pos: namePos}
 	case lexer.DOLLAR:
@@ -974,6 +1258,49 @@ func (p *parser) optionalLValue() ast.Expr {
 	}
 }
 
+// Parse an array index expression, such as a[x], a[x, y], or a[x][y] for an
+// element of a subarray:
+//
+//	NAME subscripts
+func (p *parser) indexExpr(name string, namePos lexer.Position) *ast.IndexExpr {
+	path := p.subscripts()
+	index := path[len(path)-1]
+	path = path[:len(path)-1]
+	if len(path) == 0 {
+		path = nil
+	}
+	return &ast.IndexExpr{Array: name, ArrayPos: namePos, Path: path, Index: index}
+}
+
+// Parse an array argument to a builtin function like split or asort, either
+// an array name or a subarray such as a[x]:
+//
+//	NAME [subscripts]
+func (p *parser) arrayArg() ast.Expr {
+	name, namePos := p.expectName()
+	if p.tok == lexer.LBRACKET {
+		return p.indexExpr(name, namePos)
+	}
+	return &ast.VarExpr{Name: name, Pos: namePos}
+}
+
+// Parse one or more array subscripts:
+//
+//	(LBRACKET exprList RBRACKET)+
+func (p *parser) subscripts() [][]ast.Expr {
+	var path [][]ast.Expr
+	for p.tok == lexer.LBRACKET {
+		p.next()
+		index := p.exprList(p.expr)
+		if len(index) == 0 {
+			panic(p.errorf("expected expression instead of ]"))
+		}
+		p.expect(lexer.RBRACKET)
+		path = append(path, index)
+	}
+	return path
+}
+
 // Parse /.../ regex or generic expression:
 //
 //	REGEX | expr
@@ -1024,6 +1351,7 @@ func (p *parser) optionalNewlines() {
 func (p *parser) next() {
 	p.prevTok = p.tok
 	p.pos, p.tok, p.val = p.lexer.Scan()
+	p.pos.Line += p.lineOffset
 	if p.tok == lexer.ILLEGAL {
 		panic(p.errorf("%s", p.val))
 	}
@@ -1033,6 +1361,7 @@ func (p *parser) next() {
 // DIV_ASSIGN token).
 func (p *parser) nextRegex() string {
 	p.pos, p.tok, p.val = p.lexer.ScanRegex()
+	p.pos.Line += p.lineOffset
 	if p.tok == lexer.ILLEGAL {
 		panic(p.errorf("%s", p.val))
 	}
@@ -1053,11 +1382,89 @@ func (p *parser) expect(tok lexer.Token) {
 	p.next()
 }
 
-// Ensure current token is a name, parse it, and return name and position.
+// Ensure current token is a name, parse it, and return name (qualified with
+// the current namespace) and position.
 func (p *parser) expectName() (string, lexer.Position) {
 	name, pos := p.val, p.pos
 	p.expect(lexer.NAME)
-	return name, pos
+	return p.qualify(name), pos
+}
+
+// Parse a @namespace "name" directive, which qualifies global names that
+// follow it (till the next directive) with the given namespace.
+func (p *parser) namespaceDirective() {
+	p.expect(lexer.NAMESPACE)
+	if p.tok != lexer.STRING {
+		panic(p.errorf("expected namespace name string instead of %s", p.tok))
+	}
+	name := p.val
+	if !isIdentifier(name) || lexer.KeywordToken(name) != lexer.ILLEGAL {
+		panic(p.errorf("invalid namespace name %q", name))
+	}
+	if name == "awk" {
+		name = ""
+	}
+	p.namespace = name
+	p.next()
+}
+
+// Parse an @include "file" directive, which parses the items in the named
+// file (see ParserConfig.Include) as if they were in place of the directive.
+func (p *parser) includeDirective(prog *ast.Program) {
+	if p.include == nil {
+		panic(p.errorf("@include not supported"))
+	}
+	p.expect(lexer.INCLUDE)
+	if p.tok != lexer.STRING {
+		panic(p.errorf("expected file name string instead of %s", p.tok))
+	}
+	src, line, err := p.include(p.val)
+	if err != nil {
+		panic(p.errorf("%v", err))
+	}
+	p.next()
+	if src == nil {
+		return
+	}
+
+	// Save the lexer state of the including file and parse the included
+	// one with its own lexer, in the default namespace.
+	lex, lineOffset, pos, tok, prevTok, val := p.lexer, p.lineOffset, p.pos, p.tok, p.prevTok, p.val
+	namespace := p.namespace
+	p.lexer = lexer.NewLexer(src)
+	p.lineOffset = line - 1
+	p.namespace = ""
+	p.next()
+	p.items(prog)
+	p.lexer, p.lineOffset, p.pos, p.tok, p.prevTok, p.val = lex, lineOffset, pos, tok, prevTok, val
+	p.namespace = namespace
+}
+
+// Return name qualified with the current namespace: for example, "x" in
+// namespace "ns" is "ns::x". Function parameters and all-uppercase names
+// (like NR) are never qualified, and "awk::x" is the global name "x".
+func (p *parser) qualify(name string) string {
+	if strings.HasPrefix(name, "awk::") {
+		return name[len("awk::"):]
+	}
+	if p.namespace == "" || strings.Contains(name, "::") || p.locals[name] || strings.ToUpper(name) == name {
+		return name
+	}
+	return p.namespace + "::" + name
+}
+
+// Report whether s is a valid (unqualified) AWK identifier.
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '_' && !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && !(i > 0 && c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
 }
 
 // Return true iff current token matches one of the given operators,
@@ -39,8 +39,10 @@ $0 {
     print "x" >"file"
     print "x" >>"append"
     print "y" |"prog"
+    print "z" |&"coproc"
     delete a
     delete a[k]
+    delete a[x][k]
     if (c) {
         get(a, k)
     }
@@ -56,6 +58,9 @@ $0 {
     for (k in a) {
         break
     }
+    for (k in a[x]) {
+        break
+    }
     while (0) {
         print "x"
     }
@@ -63,12 +68,23 @@ $0 {
         print "y"
         exit status
     } while (x)
+    switch (x) {
+    case 1:
+        print "one"
+    case "two":
+        break
+    case /three/:
+    default:
+        print "other"
+    }
     next
     nextfile
     "cmd" |getline
     "cmd" |getline x
     "cmd" |getline a[1]
     "cmd" |getline $1
+    "cmd" |&getline
+    "cmd" |&getline x
     getline
     getline x
     (getline x + 1)
@@ -91,6 +107,7 @@ $0 {
     ((b && c) || d)
     (k in a)
     ((x, y, z) in a)
+    (k in a[x][y])
     (s ~ "foo")
     (b < 1)
     (c <= 2)
@@ -116,12 +133,17 @@ $0 {
     }
     $1
     $(1 + 2)
+    @"id" = 42
+    @name += 1
+    @"n"++
+    --@"n"
     !x
     +x
     -x
     var
     a[key]
     a[x, y, z]
+    a[x][y, z]
     f()
     set(a, k, v)
     sub(/regex/, repl, s)
@@ -130,10 +152,31 @@ $0 {
     sub(regex, repl, s)
     gsub(regex, repl)
     gsub(regex, repl, s)
+    gensub(/(a)(b)/, "\\2\\1", "g")
+    gensub(regex, repl, 2, s)
     split(s, a)
     split(s, a, regex)
+    split(s, a[x])
+    patsplit(s, a)
+    patsplit(s, a, regex, seps[x])
+    asort(src)
+    asort(src, dest)
+    asorti(src, dest[x], "@ind_num_desc")
+    printrow(row)
+    printrow(row, fields[x])
+    printrow(row) >"out.csv"
+    printrow(row[x], fields) |"sort"
     match(s, regex)
     rand()
+    systime()
+    strftime()
+    strftime("%Y")
+    strftime("%Y", t)
+    strftime("%Y", t, 1)
+    mktime("2024 01 02 03 04 05")
+    mktime(spec, 1)
+    strptime(s)
+    strptime(s, "%Y-%m-%d")
     srand()
     srand(1)
     length()
@@ -153,6 +196,17 @@ $0 {
     close("file")
     atan2(x, y)
     index(haystack, needle)
+    and(x, y)
+    or(x, y, z)
+    xor(x, 255)
+    lshift(x, 2)
+    rshift(x, 2)
+    compl(x)
+    typeof(x)
+    isarray(a)
+    9007199254740993
+    @fn()
+    @fn(x, "y")
     {
         print "block statement"
         f()
@@ -302,6 +356,70 @@ function AddNums(n,   sum,i) {
 	}
 }
 
+func TestInclude(t *testing.T) {
+	files := map[string]string{
+		"lib":  "@namespace \"lib\"\nfunction f() { return 1 }\n",
+		"util": "@include \"lib\"\nfunction g() { return lib::f() }\n",
+		"bad":  "function h() {\n  return 1 +\n}\n",
+	}
+	included := make(map[string]bool)
+	config := &parser.ParserConfig{
+		Include: func(name string) ([]byte, int, error) {
+			src, ok := files[name]
+			if !ok {
+				return nil, 0, fmt.Errorf("@include file %q not found", name)
+			}
+			if included[name] {
+				return nil, 0, nil
+			}
+			included[name] = true
+			return []byte(src), 100, nil
+		},
+	}
+
+	prog, err := parser.ParseProgram([]byte(`@namespace "app"
+@include "util"; @include "lib"
+BEGIN { x = awk::g() }`), config)
+	if err != nil {
+		t.Fatalf("error parsing program: %v", err)
+	}
+	expected := `BEGIN {
+    app::x = g()
+}
+
+function lib::f() {
+    return 1
+}
+
+function g() {
+    return lib::f()
+}`
+	if prog.String() != expected {
+		t.Fatalf("expected first, got second:\n%s\n----------\n%s", expected, prog)
+	}
+
+	tests := []struct {
+		src string
+		err string
+	}{
+		{`@include "bad"`, "parse error at 101:13: expected expression instead of <newline>"},
+		{"BEGIN {}\n@include \"nope\"", `parse error at 2:10: @include file "nope" not found`},
+		{`@include x`, "parse error at 1:10: expected file name string instead of name"},
+		{`BEGIN { @include "lib" }`, "parse error at 1:9: expected expression instead of @include"},
+	}
+	for _, test := range tests {
+		_, err := parser.ParseProgram([]byte(test.src), config)
+		if err == nil || err.Error() != test.err {
+			t.Errorf("%q: expected error %q, got %v", test.src, test.err, err)
+		}
+	}
+
+	_, err = parser.ParseProgram([]byte(`@include "lib"`), nil)
+	if err == nil || err.Error() != "parse error at 1:1: @include not supported" {
+		t.Errorf("expected @include not supported error, got %v", err)
+	}
+}
+
 type code struct {
 	indent int
 	buf    strings.Builder
@@ -0,0 +1,2 @@
+@include "testdata/include/lib.awk"
+BEGIN { print double(21) + }
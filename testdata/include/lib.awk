@@ -0,0 +1,3 @@
+function double(x) {
+    return x * 2
+}
@@ -0,0 +1,5 @@
+# Included files are only included once
+@include "testdata/include/lib.awk"
+@include "testdata/include/lib"
+
+BEGIN { print double(21) }
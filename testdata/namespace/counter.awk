@@ -0,0 +1,11 @@
+# Globals and functions in this file are in the "counter" namespace
+@namespace "counter"
+
+function incr(name) {
+    counts[name]++
+    total++
+}
+
+function get(name) {
+    return counts[name] + 0
+}
@@ -0,0 +1,10 @@
+@namespace "app"
+@include "testdata/namespace/counter.awk"
+
+function incr(name) {
+    counter::incr(name)
+}
+
+{ incr($1) }
+
+END { print counter::get("a"), counter::get("b"), counter::total, total, NR }
@@ -0,0 +1 @@
+END { total = "main"; print total }